@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+)
+
+// oneShotMirror logs in and returns a mirror that is not connected to the
+// event socket, for commands that look at the account once.
+func oneShotMirror() (*mirror.FilenMirror, error) {
+	client, _, err := login()
+	if err != nil {
+		return nil, err
+	}
+	store := remote.NewFilenStore(client)
+	return mirror.NewFilenMirror(store, nil, mirrorConfig(currentConfig.Load())), nil
+}
+
+func interruptContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// runSync runs one full sync. It exits with exitFailed if the sync failed,
+// also if only some files failed to download.
+func runSync(args []string) int {
+	flags := newFlagSet("sync", "Run one full sync and exit")
+	syncDir := flags.String("sync-dir", "", "directory to mirror to (overrides sync.dir)")
+	timeout := flags.Duration("timeout", 0, "give up after this long, e.g. 30m (default no limit)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if !loadConfig(func(c *config.Config) { overrideSyncDir(c, *syncDir) }) {
+		return exitError
+	}
+	m, err := oneShotMirror()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to log in")
+		return exitError
+	}
+	ctx, cancel := interruptContext(*timeout)
+	defer cancel()
+
+	err = m.SyncOnce(ctx)
+	if errors.Is(err, mirror.ErrFilesFailed) {
+		log.Error().Err(err).Msg("Sync incomplete")
+		return exitFailed
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Sync failed")
+		return exitFailed
+	}
+	log.Info().Msg("Sync complete")
+	return exitOK
+}
+
+// runDiff prints the changes a sync would make. It exits with exitFailed if
+// there are any.
+func runDiff(args []string) int {
+	flags := newFlagSet("diff", "Show the changes a sync would make, without making them")
+	syncDir := flags.String("sync-dir", "", "directory to compare (overrides sync.dir)")
+	asJSON := flags.Bool("json", false, "print the changes as a JSON array")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if !loadConfig(func(c *config.Config) { overrideSyncDir(c, *syncDir) }) {
+		return exitError
+	}
+	m, err := oneShotMirror()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to log in")
+		return exitError
+	}
+	ctx, cancel := interruptContext(0)
+	defer cancel()
+
+	changes, err := m.Plan(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to compare with the remote tree")
+		return exitError
+	}
+
+	if *asJSON {
+		err = printJSON(changes)
+	} else {
+		for _, change := range changes {
+			p := change.Path
+			if change.IsDir {
+				p += "/"
+			}
+			_, err = fmt.Printf("%-7s %s\n", change.Kind, p)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print changes")
+		return exitError
+	}
+
+	if len(changes) > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// runVerify hashes the local copies of all remote files. It exits with
+// exitFailed if any of them is missing or differs.
+func runVerify(args []string) int {
+	flags := newFlagSet("verify", "Hash the local files and compare them with the remote hashes")
+	syncDir := flags.String("sync-dir", "", "directory to verify (overrides sync.dir)")
+	asJSON := flags.Bool("json", false, "print the mismatches as a JSON array")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if !loadConfig(func(c *config.Config) { overrideSyncDir(c, *syncDir) }) {
+		return exitError
+	}
+	m, err := oneShotMirror()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to log in")
+		return exitError
+	}
+	ctx, cancel := interruptContext(0)
+	defer cancel()
+
+	mismatches, err := m.Verify(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to verify")
+		return exitError
+	}
+
+	if *asJSON {
+		err = printJSON(mismatches)
+	} else {
+		for _, mismatch := range mismatches {
+			line := fmt.Sprintf("%-10s %s", mismatch.Kind, mismatch.Path)
+			if mismatch.Error != "" {
+				line += " (" + mismatch.Error + ")"
+			}
+			_, err = fmt.Println(line)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print mismatches")
+		return exitError
+	}
+
+	if len(mismatches) > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// printJSON prints v to stdout, an empty array for a nil slice.
+func printJSON[T any](v []T) error {
+	if v == nil {
+		v = []T{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes of all commands, so that cron jobs and CI can tell a failed
+// check from a broken invocation.
+const (
+	exitOK = 0
+	// exitFailed means the command ran, but found a problem: a sync failed,
+	// changes are pending, files do not match or the daemon is unhealthy.
+	exitFailed = 1
+	// exitError means the command could not run, e.g. because of invalid
+	// flags or a failed login.
+	exitError = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"daemon", "mirror the account continuously (default)", runDaemon},
+	{"sync", "run one full sync and exit", runSync},
+	{"diff", "show the changes a sync would make", runDiff},
+	{"verify", "hash the local files and compare them with the remote hashes", runVerify},
+	{"status", "query a running daemon", runStatus},
+	{"reload", "make a running daemon reload its config", runReload},
+	{"watch", "print the events of the account", runWatch},
+	{"replay", "run a mirror against recorded events", runReplay},
+	{"keyring", "manage the encrypted keyring of secrets", runKeyring},
+}
+
+// runCommand runs the command named by args[0], the daemon if args is
+// empty.
+func runCommand(args []string) int {
+	if len(args) == 0 {
+		return runDaemon(nil)
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage(os.Stderr)
+	return exitError
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: filen-mirror [command] [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun filen-mirror <command> -h for the flags of a command.\n")
+	fmt.Fprintf(w, "\nSettings are read from the config file given by -config, the environment\n")
+	fmt.Fprintf(w, "and the flags, each overriding the former.\n")
+	fmt.Fprintf(w, "\nExit codes: %d success, %d check failed, %d error.\n", exitOK, exitFailed, exitError)
+}
+
+func newFlagSet(name, summary string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&configPath, "config", os.Getenv("FILEN_CONFIG"), "YAML config file ($FILEN_CONFIG)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: filen-mirror %s [flags]\n\n%s.\n\nFlags:\n", name, summary)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// isFlagSet reports whether the flag name was given on the command line.
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseFlags returns false and the exit code if the command must not run.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	}
+	if err != nil {
+		return exitError, false
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments: %v\n", flags.Args())
+		flags.Usage()
+		return exitError, false
+	}
+	return exitOK, true
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+)
+
+// statusResponse is served by the daemon on /status.
+type statusResponse struct {
+	mirror.Status
+	Healthy bool `json:"healthy"`
+}
+
+// runDaemon mirrors the account until interrupted.
+func runDaemon(args []string) int {
+	flags := newFlagSet("daemon", "Mirror the account continuously")
+	syncDir := flags.String("sync-dir", "", "directory to mirror to (overrides sync.dir)")
+	addr := flags.String("status-addr", "", "address to serve the status on, empty to disable (overrides status.addr)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	override := func(c *config.Config) {
+		overrideSyncDir(c, *syncDir)
+		if isFlagSet(flags, "status-addr") {
+			c.Status.Addr = *addr
+		}
+	}
+	if !loadConfig(override) {
+		return exitError
+	}
+
+	client, session, err := login()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to log in")
+		return exitError
+	}
+	events, err := setupEvents(client, session)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create Filen events")
+		return exitError
+	}
+
+	store := remote.NewFilenStore(client)
+	session.OnClientChange(store.SetClient)
+
+	m := mirror.NewFilenMirror(store, events, mirrorConfig(currentConfig.Load()))
+
+	reloads := &reloader{m: m, override: override}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	cfg := currentConfig.Load()
+	if cfg.Status.Addr != "" {
+		listener, err := net.Listen("tcp", cfg.Status.Addr)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to serve status")
+			return exitError
+		}
+		server := &http.Server{Handler: statusHandler(m, reloads), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			err := server.Serve(listener)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("Status server failed")
+			}
+		}()
+		defer func() { _ = server.Close() }()
+		log.Info().Msgf("Serving status on http://%s/status", listener.Addr())
+	}
+
+	m.Start()
+
+	for ctx.Err() == nil {
+		select {
+		case <-hangups:
+			_ = reloads.reloadLogged("SIGHUP")
+		case <-ctx.Done():
+		}
+	}
+	log.Info().Msg("Shutting down")
+	_ = events.Close()
+	return exitOK
+}
+
+// statusHandler serves the status and, if reloads is set, the reload
+// endpoint of the daemon.
+func statusHandler(m *mirror.FilenMirror, reloads *reloader) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		status := m.Status()
+		resp := statusResponse{Status: status, Healthy: status.Healthy()}
+		w.Header().Set("Content-Type", "application/json")
+		if !resp.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	if reloads != nil {
+		mux.HandleFunc("POST /reload", reloads.handler)
+	}
+	return mux
+}
+
+func overrideSyncDir(c *config.Config, syncDir string) {
+	if syncDir != "" {
+		c.Sync.Dir = syncDir
+	}
+}
+
+func mirrorConfig(cfg *config.Config) mirror.FilenMirrorConfig {
+	return mirror.FilenMirrorConfig{
+		SyncDir:          cfg.Sync.Dir,
+		Exclude:          cfg.Sync.Exclude,
+		Workers:          cfg.Sync.Workers,
+		FullSyncInterval: cfg.Sync.FullSyncInterval,
+		DownloadLimit:    cfg.Sync.DownloadLimit,
+	}
+}
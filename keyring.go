@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/secret"
+	"golang.org/x/term"
+)
+
+// runKeyring manages the entries of the encrypted keyring file.
+func runKeyring(args []string) int {
+	flags := newFlagSet("keyring", "Manage the encrypted keyring.\n\n"+
+		"  keyring set NAME     store a secret read from stdin, creating the keyring if needed\n"+
+		"  keyring list         list the names of the stored secrets\n"+
+		"  keyring remove NAME  remove a secret\n\n"+
+		"Secrets are referred to as {keyring: NAME} in the config, e.g. filen.password.\n"+
+		"The keyring is unlocked with secrets.keyring.passphrase or, on a terminal,\n"+
+		"a passphrase prompt")
+	path := flags.String("path", "", "keyring file (overrides secrets.keyring.path)")
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitError
+	}
+
+	action, names := flags.Arg(0), flags.Args()
+	if len(names) > 0 {
+		names = names[1:]
+	}
+	wantNames := map[string]int{"set": 1, "list": 0, "remove": 1}
+	n, ok := wantNames[action]
+	if !ok || len(names) != n {
+		flags.Usage()
+		return exitError
+	}
+
+	// credentials are not needed, so the config is not validated
+	c, err := config.Load(configPath, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if *path != "" {
+		c.Secrets.Keyring.Path = *path
+	}
+	if c.Secrets.Keyring.Path == "" {
+		fmt.Fprintln(os.Stderr, "no keyring configured, set secrets.keyring.path, FILEN_KEYRING or -path")
+		return exitError
+	}
+
+	err = editKeyring(c, action, names)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+func editKeyring(c *config.Config, action string, names []string) error {
+	keyringPath := c.Secrets.Keyring.Path
+	_, err := os.Stat(keyringPath)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !exists && action != "set" {
+		return fmt.Errorf("keyring %s does not exist", keyringPath)
+	}
+
+	passphrase, err := keyringPassphrase(c, !exists)
+	if err != nil {
+		return err
+	}
+	defer secret.Wipe(passphrase)
+
+	entries := make(map[string][]byte)
+	if exists {
+		entries, err = secret.LoadKeyring(keyringPath, passphrase)
+		if err != nil {
+			return err
+		}
+	}
+	defer secret.WipeAll(entries)
+
+	switch action {
+	case "list":
+		keys := make([]string, 0, len(entries))
+		for name := range entries {
+			keys = append(keys, name)
+		}
+		slices.Sort(keys)
+		for _, name := range keys {
+			fmt.Println(name)
+		}
+		return nil
+	case "remove":
+		if _, ok := entries[names[0]]; !ok {
+			return fmt.Errorf("%s: %w", names[0], secret.ErrNotInKeyring)
+		}
+		secret.Wipe(entries[names[0]])
+		delete(entries, names[0])
+	case "set":
+		value, err := readSecretInput("Secret for " + names[0] + ": ")
+		if err != nil {
+			return err
+		}
+		secret.Wipe(entries[names[0]])
+		entries[names[0]] = value
+	}
+	return secret.SaveKeyring(keyringPath, passphrase, entries)
+}
+
+// keyringPassphrase reads the configured passphrase or prompts for it.
+// A new passphrase is prompted for twice.
+func keyringPassphrase(c *config.Config, create bool) ([]byte, error) {
+	passphrase, err := c.Resolver().Get(c.Secrets.Keyring.Passphrase, "filen_keyring_passphrase")
+	if !errors.Is(err, secret.ErrNotConfigured) {
+		return passphrase, err
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("no keyring passphrase configured, set secrets.keyring.passphrase, FILEN_KEYRING_PASSPHRASE_FILE or FILEN_KEYRING_KEY_FILE")
+	}
+
+	passphrase, err = promptSecret("Keyring passphrase: ")
+	if err != nil || !create {
+		return passphrase, err
+	}
+	repeated, err := promptSecret("Repeat passphrase: ")
+	defer secret.Wipe(repeated)
+	if err != nil {
+		secret.Wipe(passphrase)
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, repeated) {
+		secret.Wipe(passphrase)
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// readSecretInput prompts for a secret on a terminal and otherwise reads
+// the first line of stdin.
+func readSecretInput(prompt string) ([]byte, error) {
+	var value []byte
+	var err error
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		value, err = promptSecret(prompt)
+	} else {
+		value, err = bufio.NewReader(os.Stdin).ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		value = bytes.TrimRight(value, "\r\n")
+	}
+	if err == nil && len(value) == 0 {
+		err = fmt.Errorf("empty secret")
+	}
+	return value, err
+}
+
+func promptSecret(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	value, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return value, err
+}
@@ -2,109 +2,191 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
 	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
-	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/secret"
 	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/totp"
 	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
 )
 
 var version = "dev"
 var userAgent = fmt.Sprintf("filen-mirror/%s", version)
 
-var log = zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+// logOutput lets a reload change the log format while goroutines are
+// logging.
+var logOutput = &switchWriter{}
+
+var log = zerolog.New(logOutput).With().Timestamp().Logger()
 
 const timeout = time.Second * 30
 
-var config *configStruct
+// currentConfig is set by loadConfig and replaced by reloads.
+var currentConfig atomic.Pointer[config.Config]
 
-type configStruct struct {
-	filenEmail    string
-	filenPassword string
-	totpSecret    string
-	totpDigits    int
-	totpPeriod    int64
-	syncDir       string
-	socketURL     string
-}
+// configPath is set by the -config flag of every command.
+var configPath string
 
-func getConfig() *configStruct {
-	if config != nil {
-		return config
+// loadConfig loads the config file and the environment, lets override apply
+// the flags of the command and validates the result. Errors are printed.
+func loadConfig(override func(c *config.Config)) bool {
+	c, err := readConfig(override)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
 	}
+	setupLogging(c.Log)
+	currentConfig.Store(c)
+	return true
+}
 
-	totpDigits, err := strconv.Atoi(os.Getenv("TOTP_DIGITS"))
+func readConfig(override func(c *config.Config)) (*config.Config, error) {
+	c, err := config.Load(configPath, os.LookupEnv)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Invalid TOTP_DIGITS")
+		return nil, err
 	}
-	totpPeriod, err := strconv.ParseInt(os.Getenv("TOTP_PERIOD"), 10, 64)
+	if override != nil {
+		override(c)
+	}
+	return c, c.Validate()
+}
+
+func setupLogging(c config.LogConfig) {
+	level, err := zerolog.ParseLevel(c.Level)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Invalid TOTP_PERIOD")
+		level = zerolog.DebugLevel
 	}
+	zerolog.SetGlobalLevel(level)
 
-	config = &configStruct{
-		filenEmail:    os.Getenv("FILEN_EMAIL"),
-		filenPassword: os.Getenv("FILEN_PASSWORD"),
-		totpSecret:    os.Getenv("TOTP_SECRET"),
-		totpDigits:    totpDigits,
-		totpPeriod:    totpPeriod,
-		syncDir:       getenvDefault("FILEN_SYNC_DIR", "./data"),
-		socketURL:     getenvDefault("FILEN_SOCKET_URL", "wss://socket.filen.io:443"),
+	var w io.Writer = os.Stderr
+	if c.Format == "console" {
+		w = zerolog.ConsoleWriter{Out: os.Stderr}
 	}
-	return config
+	logOutput.set(w)
+}
+
+type switchWriter struct {
+	w atomic.Pointer[io.Writer]
+}
+
+func (s *switchWriter) set(w io.Writer) {
+	s.w.Store(&w)
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	w := s.w.Load()
+	if w == nil {
+		return os.Stderr.Write(p)
+	}
+	return (*w).Write(p)
 }
 
 func main() {
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	zerolog.DefaultContextLogger = &log
-
-	dotenv, err := os.ReadFile(".env")
-	if err == nil {
-		lines := strings.SplitSeq(string(dotenv), "\n")
-		for line := range lines {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				os.Setenv(parts[0], parts[1])
-			}
-		}
+	zlog.Logger = log
+	err := config.LoadDotenv(".env")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
 	}
 
-	totp := setupTotp()
-	client, err := setupFilenClient(totp)
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// login logs in and returns a session that logs in again when the API key
+// is refused.
+func login() (*filen.Filen, *filenextra.Session, error) {
+	client, err := setupFilenClient(context.Background())
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to set up Filen client")
+		return nil, nil, err
 	}
 
-	events, err := filenextra.NewFilenEvents(getConfig().socketURL, client, http.Header{
+	session := filenextra.NewSession(client, func(ctx context.Context) (*filen.Filen, error) {
+		return setupFilenClient(ctx)
+	}, filenextra.SessionConfig{})
+	return client, session, nil
+}
+
+func setupEvents(client *filen.Filen, session *filenextra.Session) (*filenextra.FilenEventListener, error) {
+	cfg := currentConfig.Load()
+	events, err := filenextra.NewFilenEvents(cfg.Filen.SocketURL, client, http.Header{
 		"User-Agent": []string{userAgent},
 	})
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create Filen events")
+		return nil, err
+	}
+	events.SetSession(session)
+	if cfg.RecordEvents != "" {
+		recorder, err := filenextra.NewRecorder(filenextra.RecorderConfig{Path: cfg.RecordEvents})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open event recording: %w", err)
+		}
+		events.SetRecorder(recorder)
 	}
 
-	mirror := mirror.NewFilenMirror(client, events, mirror.FilenMirrorConfig{
-		SyncDir: getConfig().syncDir,
-	})
-	mirror.Start()
-
-	select {}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err = events.RegisterShares(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to look up shared folders, events about them can not be decrypted")
+	}
+	return events, nil
 }
 
-func setupFilenClient(totp *totp.TOTPGenerator) (*filen.Filen, error) {
-	otp, err := totp.Generate()
+// setupFilenClient logs in. The secrets are read for every login and wiped
+// afterwards, so that they are not kept in memory while the mirror runs.
+func setupFilenClient(ctx context.Context) (*filen.Filen, error) {
+	cfg := currentConfig.Load()
+	secrets := cfg.Resolver()
+
+	ctx, err := filenextra.WithEndpoints(ctx, cfg.Endpoints())
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate TOTP: %w", err)
+		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	password, err := secrets.Get(cfg.Filen.Password, "filen_password")
+	if errors.Is(err, secret.ErrNotConfigured) {
+		return nil, fmt.Errorf("no password configured, set filen.password, FILEN_PASSWORD or FILEN_PASSWORD_FILE: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+	defer secret.Wipe(password)
+
+	generator, err := totpGenerator(cfg, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	client, err := filen.New(ctx, getConfig().filenEmail, getConfig().filenPassword, otp)
+	var code func(window int) (string, error)
+	if generator != nil {
+		checkClock(ctx, cfg, generator.PeriodDuration())
+		now := time.Now()
+		code = func(window int) (string, error) {
+			otp, err := generator.GenerateAt(now.Add(time.Duration(window) * generator.PeriodDuration()))
+			if err != nil {
+				return "", fmt.Errorf("failed to generate TOTP: %w", err)
+			}
+			return otp, nil
+		}
+	}
+
+	client, err := filenextra.Login(ctx, cfg.Filen.Email, string(password), code)
+	if errors.Is(err, filenextra.ErrTwoFactorRejected) && generator == nil {
+		return nil, fmt.Errorf("failed to create Filen client, set filen.totp.secret or filen.totp.uri: %w", err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Filen client: %w", err)
 	}
@@ -112,19 +194,57 @@ func setupFilenClient(totp *totp.TOTPGenerator) (*filen.Filen, error) {
 	return client, nil
 }
 
-func setupTotp() *totp.TOTPGenerator {
-	return &totp.TOTPGenerator{
-		Secret: getConfig().totpSecret,
-		Digits: getConfig().totpDigits,
-		Period: getConfig().totpPeriod,
+// totpGenerator returns the generator of the configured 2FA, or nil if the
+// account has none.
+func totpGenerator(cfg *config.Config, secrets *secret.Resolver) (*totp.TOTPGenerator, error) {
+	uri, err := secrets.Get(cfg.Filen.TOTP.URI, "filen_totp_uri")
+	if err != nil && !errors.Is(err, secret.ErrNotConfigured) {
+		return nil, fmt.Errorf("failed to read TOTP URI: %w", err)
+	}
+	defer secret.Wipe(uri)
+	if len(uri) > 0 && cfg.Filen.TOTP.Secret.IsZero() {
+		return totp.ParseURI(string(uri))
+	}
+
+	totpSecret, err := secrets.Get(cfg.Filen.TOTP.Secret, "filen_totp_secret")
+	if errors.Is(err, secret.ErrNotConfigured) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TOTP secret: %w", err)
+	}
+	defer secret.Wipe(totpSecret)
+
+	algorithm, err := totp.ParseAlgorithm(cfg.Filen.TOTP.Algorithm)
+	if err != nil {
+		return nil, err
 	}
+	return &totp.TOTPGenerator{
+		Secret:    string(totpSecret),
+		Digits:    cfg.Filen.TOTP.Digits,
+		Period:    cfg.Filen.TOTP.Period,
+		Algorithm: algorithm,
+	}, nil
 }
 
-func getenvDefault(name, def string) string {
-	val := os.Getenv(name)
-	if val == "" {
-		return def
+// checkClock warns if the local clock is too far off the gateway's for the
+// TOTP codes to match. Codes of the adjacent windows are tried as well, but
+// a code may be rejected from half a period on.
+func checkClock(ctx context.Context, cfg *config.Config, period time.Duration) {
+	gateway := cfg.Filen.Gateway
+	if len(gateway) == 0 {
+		gateway = filenextra.DefaultEndpoints().Gateway
+	}
+	if len(gateway) == 0 {
+		return
 	}
 
-	return val
+	skew, err := filenextra.ClockSkew(ctx, gateway[0])
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to check the clock against the server")
+		return
+	}
+	if skew.Abs() >= period/2 {
+		log.Warn().Msgf("The local clock is %s off the server's, 2FA codes may be rejected; sync the clock, e.g. with NTP", skew.Round(time.Second))
+	}
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/executer"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		code int
+		run  bool
+	}{
+		{nil, exitOK, true},
+		{[]string{"-n", "3"}, exitOK, true},
+		{[]string{"-h"}, exitOK, false},
+		{[]string{"-unknown"}, exitError, false},
+		{[]string{"extra"}, exitError, false},
+	} {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		flags.Int("n", 0, "")
+		code, run := parseFlags(flags, tc.args)
+		assert.Equal(t, tc.code, code, tc.args)
+		assert.Equal(t, tc.run, run, tc.args)
+	}
+}
+
+func TestRunCommandExitCodes(t *testing.T) {
+	t.Setenv("FILEN_CONFIG", "")
+	assert.Equal(t, exitOK, runCommand([]string{"help"}))
+	assert.Equal(t, exitError, runCommand([]string{"unknown"}))
+	assert.Equal(t, exitError, runCommand([]string{"sync", "-unknown"}))
+	assert.Equal(t, exitError, runCommand([]string{"status", "-addr", ""}))
+	assert.Equal(t, exitError, runCommand([]string{"replay"}))
+	assert.Equal(t, exitError, runCommand([]string{"replay", t.TempDir() + "/missing.jsonl"}))
+}
+
+// testMirror returns a mirror of an empty store. It is healthy once synced.
+func testMirror(synced bool) *mirror.FilenMirror {
+	store := remote.NewMemoryStore()
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{
+		SyncDir:  "/data",
+		Executer: executer.NewMemoryExecuter(),
+	})
+	if synced {
+		_ = store.Close()
+		m.Run()
+	}
+	return m
+}
+
+func TestStatusHandler(t *testing.T) {
+	unsynced := testMirror(false)
+	for _, tc := range []struct {
+		m      *mirror.FilenMirror
+		status int
+		body   string
+	}{
+		{testMirror(true), http.StatusOK, `"healthy":true`},
+		{unsynced, http.StatusServiceUnavailable, `"healthy":false`},
+	} {
+		rec := httptest.NewRecorder()
+		statusHandler(tc.m, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		assert.Equal(t, tc.status, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), tc.body)
+	}
+
+	// the reload endpoint only exists with a reloader
+	rec := httptest.NewRecorder()
+	statusHandler(unsynced, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRunStatus(t *testing.T) {
+	t.Setenv("FILEN_CONFIG", "")
+	healthy := httptest.NewServer(statusHandler(testMirror(true), nil))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(statusHandler(testMirror(false), nil))
+	defer unhealthy.Close()
+
+	addr := func(s *httptest.Server) string { return strings.TrimPrefix(s.URL, "http://") }
+	assert.Equal(t, exitOK, runCommand([]string{"status", "-json", "-addr", addr(healthy)}))
+	assert.Equal(t, exitFailed, runCommand([]string{"status", "-json", "-addr", addr(unhealthy)}))
+	unhealthy.Close()
+	assert.Equal(t, exitError, runCommand([]string{"status", "-addr", addr(unhealthy)}))
+}
+
+func TestReloadNeedsToken(t *testing.T) {
+	t.Setenv("FILEN_CONFIG", "")
+	t.Setenv("FILEN_EMAIL", "user@example.com")
+	t.Setenv("FILEN_PASSWORD", "secret")
+	t.Setenv("FILEN_SYNC_DIR", "/data")
+	post := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		r := &reloader{m: testMirror(true)}
+		statusHandler(r.m, r).ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.True(t, loadConfig(nil))
+	assert.Equal(t, http.StatusForbidden, post("Bearer token").Code)
+
+	t.Setenv("FILEN_STATUS_RELOAD_TOKEN", "token")
+	assert.True(t, loadConfig(nil))
+	assert.Equal(t, http.StatusUnauthorized, post("").Code)
+	assert.Equal(t, http.StatusUnauthorized, post("Bearer other").Code)
+	rec := post("Bearer token")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "config reloaded")
+}
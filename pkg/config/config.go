@@ -0,0 +1,282 @@
+// Package config loads the configuration of filen-mirror.
+//
+// Settings are taken, from lowest to highest precedence, from the defaults,
+// the YAML config file, the environment (including a .env file, which does
+// not override variables that are already set) and the command line flags.
+// An empty environment variable counts as unset.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/secret"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/totp"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is wrapped by all errors about the content of the config.
+var ErrInvalidConfig = errors.New("invalid config")
+
+type Config struct {
+	Filen  FilenConfig  `yaml:"filen"`
+	Sync   SyncConfig   `yaml:"sync"`
+	Status StatusConfig `yaml:"status"`
+	Log    LogConfig    `yaml:"log"`
+	// Secrets configures where secrets without a source of their own are
+	// looked up and the keyring secrets can refer to.
+	Secrets SecretsConfig `yaml:"secrets"`
+	// RecordEvents is the path socket messages are recorded to, if set.
+	RecordEvents string `yaml:"recordEvents"`
+}
+
+type FilenConfig struct {
+	Email    string     `yaml:"email"`
+	Password secret.Ref `yaml:"password"`
+	TOTP     TOTPConfig `yaml:"totp"`
+	// SocketURL is the event socket.
+	SocketURL string `yaml:"socketURL"`
+	// Gateway, Egest and Ingest replace the endpoints built into the SDK
+	// when set.
+	Gateway []string `yaml:"gateway"`
+	Egest   []string `yaml:"egest"`
+	Ingest  []string `yaml:"ingest"`
+}
+
+// TOTPConfig configures the 2FA of the account. 2FA is off unless Secret
+// or URI is set.
+type TOTPConfig struct {
+	// Secret is the base32 secret of the account's 2FA.
+	Secret secret.Ref `yaml:"secret"`
+	// URI is an otpauth://totp/ key URI, as shown by the QR code, in place
+	// of Secret. Its parameters replace Digits, Period and Algorithm.
+	URI    secret.Ref `yaml:"uri"`
+	Digits int        `yaml:"digits"`
+	Period int64      `yaml:"period"`
+	// Algorithm is SHA1, SHA256 or SHA512.
+	Algorithm string `yaml:"algorithm"`
+}
+
+type SyncConfig struct {
+	// Dir is the directory the account is mirrored to.
+	Dir string `yaml:"dir"`
+	// Exclude lists paths left out of the mirror, see mirror.ValidateExclude.
+	Exclude []string `yaml:"exclude"`
+	// Workers is the number of concurrent downloads.
+	Workers int `yaml:"workers"`
+	// FullSyncInterval is the time between the full syncs of the daemon,
+	// e.g. "1h".
+	FullSyncInterval time.Duration `yaml:"fullSyncInterval"`
+	// DownloadLimit is the number of bytes per second all downloads together
+	// may use. 0 means unlimited.
+	DownloadLimit int64 `yaml:"downloadLimit"`
+}
+
+type SecretsConfig struct {
+	// Dir holds Docker or Kubernetes secret mounts. Secrets without a
+	// source are read from the files filen_password, filen_totp_secret,
+	// filen_totp_uri, filen_status_reload_token and filen_keyring_passphrase
+	// in it. Empty disables it.
+	Dir     string               `yaml:"dir"`
+	Keyring secret.KeyringConfig `yaml:"keyring"`
+}
+
+type StatusConfig struct {
+	// Addr is where the daemon serves its status. Empty disables it.
+	Addr string `yaml:"addr"`
+	// ReloadToken must be sent as a bearer token to reload the config
+	// through the status address. Unset, the config is only reloaded on
+	// SIGHUP.
+	ReloadToken secret.Ref `yaml:"reloadToken"`
+}
+
+type LogConfig struct {
+	// Level is a zerolog level, e.g. "info" or "debug".
+	Level string `yaml:"level"`
+	// Format is "json" or "console".
+	Format string `yaml:"format"`
+}
+
+func Default() *Config {
+	return &Config{
+		Filen: FilenConfig{
+			TOTP:      TOTPConfig{Digits: 6, Period: 30},
+			SocketURL: "wss://socket.filen.io:443",
+		},
+		Sync: SyncConfig{
+			Dir:              "./data",
+			Workers:          4,
+			FullSyncInterval: time.Hour,
+		},
+		Status:  StatusConfig{Addr: "127.0.0.1:8787"},
+		Secrets: SecretsConfig{Dir: secret.DefaultDir},
+		Log:     LogConfig{Level: "debug", Format: "json"},
+	}
+}
+
+// Load returns the defaults overridden by the config file at path, if path
+// is not empty, and by the environment. The result still has to be
+// validated once flags were applied.
+func Load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		err := cfg.loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := cfg.applyEnv(lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("%w: %s: unsupported format, expected a .yaml or .yml file", ErrInvalidConfig, path)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err = dec.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+	return nil
+}
+
+// Validate returns an error listing every invalid setting by its path in
+// the config file.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Filen.Email == "" {
+		invalid("filen.email", "required")
+	}
+	for _, ref := range []struct {
+		field string
+		ref   secret.Ref
+	}{
+		{"filen.password", c.Filen.Password},
+		{"filen.totp.secret", c.Filen.TOTP.Secret},
+		{"filen.totp.uri", c.Filen.TOTP.URI},
+		{"status.reloadToken", c.Status.ReloadToken},
+	} {
+		err := ref.ref.Validate()
+		if err != nil {
+			invalid(ref.field, "%v", err)
+		}
+		if ref.ref.Keyring != "" && c.Secrets.Keyring.Path == "" {
+			invalid(ref.field, "refers to the keyring, but secrets.keyring.path is not set")
+		}
+	}
+	err := c.Secrets.Keyring.Passphrase.Validate()
+	if err != nil {
+		invalid("secrets.keyring.passphrase", "%v", err)
+	}
+	if c.Secrets.Keyring.Passphrase.Keyring != "" {
+		invalid("secrets.keyring.passphrase", "must not be stored in the keyring")
+	}
+	if c.Filen.TOTP.Digits < 6 || c.Filen.TOTP.Digits > 10 {
+		invalid("filen.totp.digits", "must be between 6 and 10, got %d", c.Filen.TOTP.Digits)
+	}
+	if c.Filen.TOTP.Period <= 0 {
+		invalid("filen.totp.period", "must be positive, got %d", c.Filen.TOTP.Period)
+	}
+	_, err = totp.ParseAlgorithm(c.Filen.TOTP.Algorithm)
+	if err != nil {
+		invalid("filen.totp.algorithm", "%v", err)
+	}
+	if !c.Filen.TOTP.Secret.IsZero() && !c.Filen.TOTP.URI.IsZero() {
+		invalid("filen.totp", "set either secret or uri")
+	}
+	err = filenextra.ValidateSocketURL(c.Filen.SocketURL)
+	if err != nil {
+		invalid("filen.socketURL", "%v", err)
+	}
+	for _, endpoints := range []struct {
+		field string
+		urls  []string
+	}{
+		{"filen.gateway", c.Filen.Gateway},
+		{"filen.egest", c.Filen.Egest},
+		{"filen.ingest", c.Filen.Ingest},
+	} {
+		for i, u := range endpoints.urls {
+			_, err := filenextra.ParseEndpointList(u)
+			if err != nil || strings.TrimSpace(u) == "" || strings.Contains(u, ",") {
+				invalid(fmt.Sprintf("%s[%d]", endpoints.field, i), "invalid endpoint %q, expected an http or https base URL", u)
+			}
+		}
+	}
+
+	if c.Sync.Dir == "" {
+		invalid("sync.dir", "required")
+	}
+	for i, pattern := range c.Sync.Exclude {
+		err := mirror.ValidateExclude([]string{pattern})
+		if err != nil {
+			invalid(fmt.Sprintf("sync.exclude[%d]", i), "%v", err)
+		}
+	}
+	if c.Sync.Workers <= 0 {
+		invalid("sync.workers", "must be positive, got %d", c.Sync.Workers)
+	}
+	if c.Sync.FullSyncInterval < time.Minute {
+		invalid("sync.fullSyncInterval", "must be at least 1m, got %s", c.Sync.FullSyncInterval)
+	}
+	if c.Sync.DownloadLimit < 0 {
+		invalid("sync.downloadLimit", "must not be negative, got %d", c.Sync.DownloadLimit)
+	}
+
+	_, err = zerolog.ParseLevel(c.Log.Level)
+	if err != nil || c.Log.Level == "" {
+		invalid("log.level", "unknown level %q", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "console" {
+		invalid("log.format", "must be json or console, got %q", c.Log.Format)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Resolver returns the resolver for the secrets of c.
+func (c *Config) Resolver() *secret.Resolver {
+	return &secret.Resolver{
+		Dir:     c.Secrets.Dir,
+		Keyring: c.Secrets.Keyring,
+	}
+}
+
+// Endpoints returns the configured endpoints. Unset lists keep the SDK
+// defaults.
+func (c *Config) Endpoints() filenextra.Endpoints {
+	return filenextra.Endpoints{
+		Gateway: c.Filen.Gateway,
+		Egest:   c.Filen.Egest,
+		Ingest:  c.Filen.Ingest,
+	}
+}
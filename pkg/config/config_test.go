@@ -0,0 +1,194 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/secret"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "filen-mirror.yaml")
+	assert.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	return p
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	p := writeConfig(t, `
+filen:
+  email: file@example.com
+  password: secret
+sync:
+  dir: /from-file
+  exclude: ["*.tmp"]
+  workers: 8
+  fullSyncInterval: 30m
+`)
+
+	cfg, err := config.Load(p, env(map[string]string{
+		"FILEN_SYNC_DIR":            "/from-env",
+		"FILEN_EMAIL":               "",
+		"FILEN_SYNC_WORKERS":        "2",
+		"FILEN_SYNC_DOWNLOAD_LIMIT": "1048576",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+
+	assert.Equal(t, "file@example.com", cfg.Filen.Email, "empty variables are unset")
+	assert.Equal(t, "/from-env", cfg.Sync.Dir)
+	assert.Equal(t, 2, cfg.Sync.Workers)
+	assert.Equal(t, int64(1048576), cfg.Sync.DownloadLimit)
+	assert.Equal(t, []string{"*.tmp"}, cfg.Sync.Exclude)
+	assert.Equal(t, 30*time.Minute, cfg.Sync.FullSyncInterval)
+	// defaults
+	assert.Equal(t, 6, cfg.Filen.TOTP.Digits)
+	assert.Equal(t, int64(30), cfg.Filen.TOTP.Period)
+	assert.Equal(t, "wss://socket.filen.io:443", cfg.Filen.SocketURL)
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	cfg, err := config.Load("", env(map[string]string{
+		"FILEN_EMAIL":    "user@example.com",
+		"FILEN_PASSWORD": "secret",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "./data", cfg.Sync.Dir)
+}
+
+func TestLoadSecretSources(t *testing.T) {
+	p := writeConfig(t, `
+filen:
+  email: user@example.com
+  password: {keyring: filen_password}
+  totp:
+    secret: inline
+secrets:
+  keyring:
+    path: /etc/filen-mirror/keyring.json
+`)
+	cfg, err := config.Load(p, env(map[string]string{
+		"FILEN_TOTP_SECRET_FILE":         "/run/secrets/totp",
+		"FILEN_KEYRING_KEY_FILE":         "/etc/filen-mirror/keyring.key",
+		"FILEN_KEYRING_PASSPHRASE":       "ignored, the key file wins",
+		"FILEN_STATUS_RELOAD_TOKEN_FILE": "/run/secrets/reload",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, secret.Ref{Keyring: "filen_password"}, cfg.Filen.Password)
+	assert.Equal(t, secret.Ref{File: "/run/secrets/totp"}, cfg.Filen.TOTP.Secret)
+	assert.Equal(t, secret.Ref{File: "/etc/filen-mirror/keyring.key"}, cfg.Secrets.Keyring.Passphrase)
+	assert.Equal(t, secret.Ref{File: "/run/secrets/reload"}, cfg.Status.ReloadToken)
+	assert.Equal(t, secret.DefaultDir, cfg.Secrets.Dir)
+}
+
+func TestLoadTOTPURI(t *testing.T) {
+	cfg, err := config.Load("", env(map[string]string{
+		"FILEN_EMAIL":         "user@example.com",
+		"FILEN_TOTP_URI_FILE": "/run/secrets/totp_uri",
+		"TOTP_ALGORITHM":      "sha512",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, secret.Ref{File: "/run/secrets/totp_uri"}, cfg.Filen.TOTP.URI)
+	assert.True(t, cfg.Filen.TOTP.Secret.IsZero())
+	assert.Equal(t, "sha512", cfg.Filen.TOTP.Algorithm)
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	p := writeConfig(t, `
+sync:
+  dir: /data
+  worker: 3
+`)
+	_, err := config.Load(p, env(nil))
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.ErrorContains(t, err, "line 4: field worker not found")
+}
+
+func TestLoadRejectsInvalidEnv(t *testing.T) {
+	_, err := config.Load("", env(map[string]string{"TOTP_DIGITS": "six"}))
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.ErrorContains(t, err, `TOTP_DIGITS: invalid integer "six"`)
+}
+
+func TestLoadRejectsOtherFormats(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "filen-mirror.toml")
+	assert.NoError(t, os.WriteFile(p, nil, 0o600))
+	_, err := config.Load(p, env(nil))
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+}
+
+func TestValidateListsAllErrors(t *testing.T) {
+	p := writeConfig(t, `
+filen:
+  password: {file: /run/secrets/filen_password, env: FILEN_PASSWORD}
+  totp:
+    digits: 4
+    algorithm: MD5
+    secret: {keyring: filen_totp_secret}
+    uri: otpauth://totp/user?secret=GEZDGNBVGY3TQOJQ
+  gateway: ["ftp://gateway.example.com"]
+sync:
+  exclude: ["[a-"]
+  workers: 0
+  fullSyncInterval: 1s
+log:
+  format: xml
+`)
+	cfg, err := config.Load(p, env(nil))
+	assert.NoError(t, err)
+
+	err = cfg.Validate()
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+	for _, field := range []string{
+		"filen.email: required",
+		"filen.password: only one of value, file, env and keyring may be set",
+		"filen.totp.secret: refers to the keyring, but secrets.keyring.path is not set",
+		"filen.totp.digits: must be between 6 and 10, got 4",
+		`filen.totp.algorithm: unknown algorithm "MD5"`,
+		"filen.totp: set either secret or uri",
+		"filen.gateway[0]: invalid endpoint",
+		"sync.exclude[0]: exclude pattern",
+		"sync.workers: must be positive",
+		"sync.fullSyncInterval: must be at least 1m",
+		`log.format: must be json or console, got "xml"`,
+	} {
+		assert.ErrorContains(t, err, field)
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	cfg, err := config.Load("../../filen-mirror.example.yaml", env(nil))
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestCheckReload(t *testing.T) {
+	running := config.Default()
+	next := config.Default()
+	next.Sync.Workers = 16
+	next.Sync.DownloadLimit = 1 << 20
+	next.Sync.Exclude = []string{"*.tmp"}
+	next.Log.Level = "info"
+	assert.NoError(t, config.CheckReload(running, next))
+
+	next.Sync.Dir = "/elsewhere"
+	next.Filen.Gateway = []string{"https://gateway.example.com"}
+	err := config.CheckReload(running, next)
+	assert.ErrorIs(t, err, config.ErrNotReloadable)
+	assert.ErrorContains(t, err, "sync.dir: changed from ./data to /elsewhere")
+	assert.ErrorContains(t, err, "filen.gateway: changed from [] to [https://gateway.example.com]")
+}
@@ -0,0 +1,40 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotenv(t *testing.T) {
+	vars, err := config.ParseDotenv(strings.NewReader(`
+# credentials
+FILEN_EMAIL=user@example.com # trailing comment
+export FILEN_PASSWORD="pa ss#word\"\n"
+TOTP_SECRET='raw \n value'
+EMPTY=
+HASH=a#b
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"FILEN_EMAIL":    "user@example.com",
+		"FILEN_PASSWORD": "pa ss#word\"\n",
+		"TOTP_SECRET":    `raw \n value`,
+		"EMPTY":          "",
+		"HASH":           "a#b",
+	}, vars)
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	for input, msg := range map[string]string{
+		"A=1\nnot a variable": "line 2: expected KEY=VALUE",
+		"1A=1":                "line 1: expected KEY=VALUE",
+		`A="unterminated`:     "line 1: A: unterminated quote",
+		`A='quoted' trailing`: `line 1: A: unexpected "trailing" after quoted value`,
+	} {
+		_, err := config.ParseDotenv(strings.NewReader(input))
+		assert.EqualError(t, err, msg, input)
+	}
+}
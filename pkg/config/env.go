@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/secret"
+)
+
+// envVar overrides a setting with the value of an environment variable.
+type envVar struct {
+	name  string
+	apply func(c *Config, value string) error
+}
+
+// envVars are applied in order, so of FILEN_TOTP_SECRET and TOTP_SECRET the
+// former wins, and a *_FILE variable wins over the variable holding the
+// value.
+var envVars = []envVar{
+	{"FILEN_EMAIL", setString(func(c *Config) *string { return &c.Filen.Email })},
+	{"FILEN_PASSWORD", setSecretValue(func(c *Config) *secret.Ref { return &c.Filen.Password })},
+	{"FILEN_PASSWORD_FILE", setSecretFile(func(c *Config) *secret.Ref { return &c.Filen.Password })},
+	{"TOTP_SECRET", setSecretValue(func(c *Config) *secret.Ref { return &c.Filen.TOTP.Secret })},
+	{"FILEN_TOTP_SECRET", setSecretValue(func(c *Config) *secret.Ref { return &c.Filen.TOTP.Secret })},
+	{"TOTP_SECRET_FILE", setSecretFile(func(c *Config) *secret.Ref { return &c.Filen.TOTP.Secret })},
+	{"FILEN_TOTP_SECRET_FILE", setSecretFile(func(c *Config) *secret.Ref { return &c.Filen.TOTP.Secret })},
+	{"FILEN_TOTP_URI", setSecretValue(func(c *Config) *secret.Ref { return &c.Filen.TOTP.URI })},
+	{"FILEN_TOTP_URI_FILE", setSecretFile(func(c *Config) *secret.Ref { return &c.Filen.TOTP.URI })},
+	{"FILEN_SECRETS_DIR", setString(func(c *Config) *string { return &c.Secrets.Dir })},
+	{"FILEN_KEYRING", setString(func(c *Config) *string { return &c.Secrets.Keyring.Path })},
+	{"FILEN_KEYRING_PASSPHRASE", setSecretValue(func(c *Config) *secret.Ref { return &c.Secrets.Keyring.Passphrase })},
+	{"FILEN_KEYRING_PASSPHRASE_FILE", setSecretFile(func(c *Config) *secret.Ref { return &c.Secrets.Keyring.Passphrase })},
+	{"FILEN_KEYRING_KEY_FILE", setSecretFile(func(c *Config) *secret.Ref { return &c.Secrets.Keyring.Passphrase })},
+	{"TOTP_DIGITS", func(c *Config, value string) error {
+		digits, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		c.Filen.TOTP.Digits = digits
+		return nil
+	}},
+	{"TOTP_PERIOD", func(c *Config, value string) error {
+		period, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		c.Filen.TOTP.Period = period
+		return nil
+	}},
+	{"TOTP_ALGORITHM", setString(func(c *Config) *string { return &c.Filen.TOTP.Algorithm })},
+	{"FILEN_SOCKET_URL", setString(func(c *Config) *string { return &c.Filen.SocketURL })},
+	{"FILEN_GATEWAY_URL", setEndpoints(func(c *Config) *[]string { return &c.Filen.Gateway })},
+	{"FILEN_EGEST_URL", setEndpoints(func(c *Config) *[]string { return &c.Filen.Egest })},
+	{"FILEN_INGEST_URL", setEndpoints(func(c *Config) *[]string { return &c.Filen.Ingest })},
+	{"FILEN_SYNC_DIR", setString(func(c *Config) *string { return &c.Sync.Dir })},
+	{"FILEN_SYNC_EXCLUDE", func(c *Config, value string) error {
+		c.Sync.Exclude = splitList(value)
+		return nil
+	}},
+	{"FILEN_SYNC_WORKERS", func(c *Config, value string) error {
+		workers, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		c.Sync.Workers = workers
+		return nil
+	}},
+	{"FILEN_SYNC_DOWNLOAD_LIMIT", func(c *Config, value string) error {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		c.Sync.DownloadLimit = limit
+		return nil
+	}},
+	{"FILEN_FULL_SYNC_INTERVAL", func(c *Config, value string) error {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, e.g. 30m or 2h", value)
+		}
+		c.Sync.FullSyncInterval = interval
+		return nil
+	}},
+	{"FILEN_STATUS_ADDR", setString(func(c *Config) *string { return &c.Status.Addr })},
+	{"FILEN_STATUS_RELOAD_TOKEN", setSecretValue(func(c *Config) *secret.Ref { return &c.Status.ReloadToken })},
+	{"FILEN_STATUS_RELOAD_TOKEN_FILE", setSecretFile(func(c *Config) *secret.Ref { return &c.Status.ReloadToken })},
+	{"FILEN_LOG_LEVEL", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"FILEN_LOG_FORMAT", setString(func(c *Config) *string { return &c.Log.Format })},
+	{"FILEN_RECORD_EVENTS", setString(func(c *Config) *string { return &c.RecordEvents })},
+}
+
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	for _, v := range envVars {
+		value, ok := lookupEnv(v.name)
+		if !ok || value == "" {
+			continue
+		}
+		err := v.apply(c, value)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, v.name, err)
+		}
+	}
+	return nil
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+// setSecretValue replaces the secret's source by the value of the variable.
+func setSecretValue(field func(c *Config) *secret.Ref) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = secret.Ref{Value: value}
+		return nil
+	}
+}
+
+// setSecretFile replaces the secret's source by the file the variable
+// names. The file is read when the secret is needed.
+func setSecretFile(field func(c *Config) *secret.Ref) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = secret.Ref{File: value}
+		return nil
+	}
+}
+
+func setEndpoints(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		endpoints, err := filenextra.ParseEndpointList(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = endpoints
+		return nil
+	}
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
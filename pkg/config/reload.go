@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotReloadable is wrapped by CheckReload.
+var ErrNotReloadable = errors.New("settings cannot change without a restart")
+
+// fixedSettings cannot change while the daemon runs. The others are applied
+// live: sync.workers, sync.exclude, sync.fullSyncInterval and
+// sync.downloadLimit by the mirror, log by the logger, and the secrets are
+// read again at the next login or, for status.reloadToken, the next reload
+// request.
+var fixedSettings = []struct {
+	field string
+	value func(c *Config) any
+}{
+	{"filen.email", func(c *Config) any { return c.Filen.Email }},
+	{"filen.socketURL", func(c *Config) any { return c.Filen.SocketURL }},
+	{"filen.gateway", func(c *Config) any { return c.Filen.Gateway }},
+	{"filen.egest", func(c *Config) any { return c.Filen.Egest }},
+	{"filen.ingest", func(c *Config) any { return c.Filen.Ingest }},
+	{"sync.dir", func(c *Config) any { return c.Sync.Dir }},
+	{"status.addr", func(c *Config) any { return c.Status.Addr }},
+	{"recordEvents", func(c *Config) any { return c.RecordEvents }},
+}
+
+// CheckReload returns an error naming every setting that differs between
+// the running config and next, but cannot change without a restart.
+func CheckReload(running, next *Config) error {
+	var changed []error
+	for _, setting := range fixedSettings {
+		// compared as printed, so that an empty list equals an unset one
+		from, to := fmt.Sprint(setting.value(running)), fmt.Sprint(setting.value(next))
+		if from != to {
+			changed = append(changed, fmt.Errorf("%s: changed from %s to %s", setting.field, from, to))
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrNotReloadable, errors.Join(changed...))
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package executer
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ChaosConfig sets the probability of each injected fault, from 0 to 1.
+type ChaosConfig struct {
+	Seed int64
+	// NoSpaceRate fails writes with ENOSPC.
+	NoSpaceRate float64
+	// IORate fails any operation with EIO.
+	IORate float64
+	// SlowWriteRate delays writes by SlowWriteDelay.
+	SlowWriteRate  float64
+	SlowWriteDelay time.Duration
+	// RenameFailRate fails renames with EIO.
+	RenameFailRate float64
+}
+
+// ChaosExecuter wraps another Executer and injects faults into its
+// operations. EnsureFile and EnsureDir are composed from the faulty
+// primitives, so callers see errors at the same points a real disk would
+// produce them.
+type ChaosExecuter struct {
+	inner Executer
+
+	mu  sync.Mutex
+	rng *rand.Rand
+	cfg ChaosConfig
+}
+
+func NewChaosExecuter(inner Executer, cfg ChaosConfig) *ChaosExecuter {
+	return &ChaosExecuter{
+		inner: inner,
+		rng:   rand.New(rand.NewSource(cfg.Seed)),
+		cfg:   cfg,
+	}
+}
+
+// SetConfig replaces the fault rates. The random source is kept.
+func (ce *ChaosExecuter) SetConfig(cfg ChaosConfig) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.cfg = cfg
+}
+
+func (ce *ChaosExecuter) EnsureFile(path string, modTime time.Time, hash string, downloadFunc func() (io.ReadCloser, error)) error {
+	return ensureFile(ce, path, modTime, hash, downloadFunc)
+}
+
+func (ce *ChaosExecuter) EnsureDir(path string) error {
+	return ensureDir(ce, path)
+}
+
+func (ce *ChaosExecuter) WriteFile(ctx context.Context, path string, modTime time.Time, r io.Reader) error {
+	if err := ce.fault("write", path, func(cfg ChaosConfig) float64 { return cfg.IORate }, syscall.EIO); err != nil {
+		return err
+	}
+	if err := ce.fault("write", path, func(cfg ChaosConfig) float64 { return cfg.NoSpaceRate }, syscall.ENOSPC); err != nil {
+		return err
+	}
+	if delay := ce.slowWrite(); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+	return ce.inner.WriteFile(ctx, path, modTime, r)
+}
+
+func (ce *ChaosExecuter) CalculateHash(path string) (string, error) {
+	if err := ce.ioFault("read", path); err != nil {
+		return "", err
+	}
+	return ce.inner.CalculateHash(path)
+}
+
+func (ce *ChaosExecuter) Stat(path string) (os.FileInfo, error) {
+	if err := ce.ioFault("stat", path); err != nil {
+		return nil, err
+	}
+	return ce.inner.Stat(path)
+}
+
+func (ce *ChaosExecuter) Chtimes(path string, mtime time.Time) error {
+	if err := ce.ioFault("chtimes", path); err != nil {
+		return err
+	}
+	return ce.inner.Chtimes(path, mtime)
+}
+
+func (ce *ChaosExecuter) Rename(oldPath, newPath string) error {
+	if err := ce.ioFault("rename", oldPath); err != nil {
+		return err
+	}
+	if err := ce.fault("rename", oldPath, func(cfg ChaosConfig) float64 { return cfg.RenameFailRate }, syscall.EIO); err != nil {
+		return err
+	}
+	return ce.inner.Rename(oldPath, newPath)
+}
+
+func (ce *ChaosExecuter) MkdirAll(path string) error {
+	if err := ce.ioFault("mkdir", path); err != nil {
+		return err
+	}
+	if err := ce.fault("mkdir", path, func(cfg ChaosConfig) float64 { return cfg.NoSpaceRate }, syscall.ENOSPC); err != nil {
+		return err
+	}
+	return ce.inner.MkdirAll(path)
+}
+
+func (ce *ChaosExecuter) RemovePath(path string) error {
+	if err := ce.ioFault("remove", path); err != nil {
+		return err
+	}
+	return ce.inner.RemovePath(path)
+}
+
+func (ce *ChaosExecuter) WalkDir(path string, cb func(p string, isDir bool, continueDescending *bool)) error {
+	if err := ce.ioFault("open", path); err != nil {
+		return err
+	}
+	return ce.inner.WalkDir(path, cb)
+}
+
+func (ce *ChaosExecuter) ioFault(op, path string) error {
+	return ce.fault(op, path, func(cfg ChaosConfig) float64 { return cfg.IORate }, syscall.EIO)
+}
+
+func (ce *ChaosExecuter) fault(op, path string, rate func(ChaosConfig) float64, errno syscall.Errno) error {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if ce.rng.Float64() < rate(ce.cfg) {
+		return &fs.PathError{Op: op, Path: path, Err: errno}
+	}
+	return nil
+}
+
+func (ce *ChaosExecuter) slowWrite() time.Duration {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if ce.rng.Float64() < ce.cfg.SlowWriteRate {
+		return ce.cfg.SlowWriteDelay
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Executer performs all local filesystem operations of the mirror.
+type Executer interface {
+	EnsureFile(path string, modTime time.Time, hash string, downloadFunc func() (io.ReadCloser, error)) error
+	EnsureDir(path string) error
+	// WriteFile atomically replaces path with the content of r and sets its
+	// modification time.
+	WriteFile(ctx context.Context, path string, modTime time.Time, r io.Reader) error
+	// CalculateHash returns the hex encoded SHA-512 hash of the file at path,
+	// the hash Filen keeps for files.
+	CalculateHash(path string) (string, error)
+	Stat(path string) (os.FileInfo, error)
+	Chtimes(path string, mtime time.Time) error
+	Rename(oldPath, newPath string) error
+	MkdirAll(path string) error
+	RemovePath(path string) error
+	// WalkDir calls cb for every entry below path. Directories are descended
+	// into unless cb clears continueDescending.
+	WalkDir(path string, cb func(p string, isDir bool, continueDescending *bool)) error
+}
+
+// ensureFile implements EnsureFile on top of the primitives of e.
+func ensureFile(e Executer, path string, modTime time.Time, hash string, downloadFunc func() (io.ReadCloser, error)) error {
+	needDownload := false
+	info, err := e.Stat(path)
+	if os.IsNotExist(err) {
+		needDownload = true
+	} else if err != nil {
+		return err
+	} else {
+		if info.IsDir() {
+			err := e.RemovePath(path)
+			if err != nil {
+				return err
+			}
+			needDownload = true
+		} else if !info.ModTime().Equal(modTime) {
+			isHash, err := e.CalculateHash(path)
+			if err != nil {
+				return err
+			}
+			if isHash != hash {
+				needDownload = true
+			} else {
+				return e.Chtimes(path, modTime)
+			}
+		}
+	}
+
+	if needDownload {
+		log.Info().Msgf("Downloading file to %s", path)
+		r, err := downloadFunc()
+		if err != nil {
+			return fmt.Errorf("download func: %w", err)
+		}
+		defer func() { _ = r.Close() }()
+		return e.WriteFile(context.Background(), path, modTime, r)
+	}
+
+	return nil
+}
+
+// ensureDir implements EnsureDir on top of the primitives of e.
+func ensureDir(e Executer, path string) error {
+	info, err := e.Stat(path)
+	if os.IsNotExist(err) {
+		log.Info().Msgf("Creating directory %s", path)
+		return e.MkdirAll(path)
+	} else if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		log.Info().Msgf("Removing file %s to create directory", path)
+		err := e.RemovePath(path)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Msgf("Creating directory %s", path)
+		return e.MkdirAll(path)
+	}
+
+	return nil
+}
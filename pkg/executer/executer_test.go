@@ -0,0 +1,117 @@
+package executer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/executer"
+	"github.com/stretchr/testify/assert"
+)
+
+func downloadOf(content string, calls *int) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		*calls++
+		return io.NopCloser(bytes.NewReader([]byte(content))), nil
+	}
+}
+
+func TestMemoryEnsureFile(t *testing.T) {
+	e := executer.NewMemoryExecuter()
+	var calls int
+
+	assert.NoError(t, e.EnsureFile("/data/dir/file.txt", time.Unix(10, 0), "", downloadOf("hello", &calls)))
+	assert.Equal(t, 1, calls)
+	content, err := e.ReadFile("/data/dir/file.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	// same modtime: nothing to do
+	assert.NoError(t, e.EnsureFile("/data/dir/file.txt", time.Unix(10, 0), "", downloadOf("other", &calls)))
+	assert.Equal(t, 1, calls)
+
+	// new modtime, same hash: only the modtime changes
+	hash, err := e.CalculateHash("/data/dir/file.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043", hash)
+	assert.NoError(t, e.EnsureFile("/data/dir/file.txt", time.Unix(20, 0), hash, downloadOf("other", &calls)))
+	assert.Equal(t, 1, calls)
+	info, err := e.Stat("/data/dir/file.txt")
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(time.Unix(20, 0)))
+}
+
+func TestMemoryEnsureDirReplacesFile(t *testing.T) {
+	e := executer.NewMemoryExecuter()
+	assert.NoError(t, e.WriteFile(context.Background(), "/data/a", time.Unix(0, 0), bytes.NewReader(nil)))
+
+	assert.NoError(t, e.EnsureDir("/data/a"))
+	info, err := e.Stat("/data/a")
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestMemoryRename(t *testing.T) {
+	e := executer.NewMemoryExecuter()
+	assert.NoError(t, e.WriteFile(context.Background(), "/data/dir/file.txt", time.Unix(0, 0), bytes.NewReader([]byte("x"))))
+
+	err := e.Rename("/data/dir", "/data/missing/dir")
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, e.Rename("/data/dir", "/data/moved"))
+	_, err = e.ReadFile("/data/moved/file.txt")
+	assert.NoError(t, err)
+	_, err = e.Stat("/data/dir")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMemoryWalkDir(t *testing.T) {
+	e := executer.NewMemoryExecuter()
+	assert.NoError(t, e.WriteFile(context.Background(), "/data/a/b.txt", time.Unix(0, 0), bytes.NewReader(nil)))
+	assert.NoError(t, e.WriteFile(context.Background(), "/data/c/d.txt", time.Unix(0, 0), bytes.NewReader(nil)))
+
+	var seen []string
+	err := e.WalkDir("/data", func(p string, isDir bool, continueDescending *bool) {
+		seen = append(seen, p)
+		*continueDescending = p != "/data/c"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/data/a", "/data/a/b.txt", "/data/c"}, seen)
+
+	err = e.WalkDir("/missing", func(string, bool, *bool) {})
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestChaosInjectsFaults(t *testing.T) {
+	inner := executer.NewMemoryExecuter()
+	e := executer.NewChaosExecuter(inner, executer.ChaosConfig{NoSpaceRate: 1})
+
+	err := e.WriteFile(context.Background(), "/data/file.txt", time.Unix(0, 0), bytes.NewReader(nil))
+	assert.True(t, errors.Is(err, syscall.ENOSPC))
+
+	e.SetConfig(executer.ChaosConfig{RenameFailRate: 1})
+	assert.NoError(t, e.WriteFile(context.Background(), "/data/file.txt", time.Unix(0, 0), bytes.NewReader(nil)))
+	err = e.Rename("/data/file.txt", "/data/other.txt")
+	assert.True(t, errors.Is(err, syscall.EIO))
+
+	e.SetConfig(executer.ChaosConfig{IORate: 1})
+	_, err = e.Stat("/data/file.txt")
+	assert.True(t, errors.Is(err, syscall.EIO))
+}
+
+func TestChaosSlowWrite(t *testing.T) {
+	e := executer.NewChaosExecuter(executer.NewMemoryExecuter(), executer.ChaosConfig{
+		SlowWriteRate:  1,
+		SlowWriteDelay: time.Hour,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := e.WriteFile(ctx, "/data/file.txt", time.Unix(0, 0), bytes.NewReader(nil))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -2,20 +2,19 @@ package executer
 
 import (
 	"context"
-	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"time"
+	"unsafe"
 
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/unix"
 )
 
-var Current LinuxExecuter = LinuxExecuter{}
-
 type LinuxExecuter struct {
 }
 
@@ -24,71 +23,14 @@ func CreateLinuxExecuter() LinuxExecuter {
 }
 
 func (le LinuxExecuter) EnsureFile(path string, modTime time.Time, hash string, downloadFunc func() (io.ReadCloser, error)) error {
-	needDownload := false
-	info, err := le.Stat(path)
-	if os.IsNotExist(err) {
-		needDownload = true
-	} else if err != nil {
-		return err
-	} else {
-		if info.IsDir() {
-			err := le.RemovePath(path)
-			if err != nil {
-				return err
-			}
-			needDownload = true
-		}
-
-		if !info.ModTime().Equal(modTime) {
-			isHash, err := le.CalculateHash(path)
-			if err != nil {
-				return err
-			}
-			if isHash != hash {
-				needDownload = true
-			} else {
-				return le.Chtimes(path, modTime)
-			}
-		}
-	}
-
-	if needDownload {
-		log.Info().Msgf("Downloading file to %s", path)
-		r, err := downloadFunc()
-		if err != nil {
-			return fmt.Errorf("download func: %w", err)
-		}
-		defer func() { _ = r.Close() }()
-		return le.downloadToPath(context.Background(), path, modTime, r)
-	}
-
-	return nil
+	return ensureFile(le, path, modTime, hash, downloadFunc)
 }
 
 func (le LinuxExecuter) EnsureDir(path string) error {
-	info, err := le.Stat(path)
-	if os.IsNotExist(err) {
-		zerolog.DefaultContextLogger.Info().Msgf("Creating directory %s", path)
-		return le.MkdirAll(path)
-	} else if err != nil {
-		return err
-	}
-
-	if !info.IsDir() {
-		zerolog.DefaultContextLogger.Info().Msgf("Removing file %s to create directory", path)
-		err := le.RemovePath(path)
-		if err != nil {
-			return err
-		}
-
-		zerolog.DefaultContextLogger.Info().Msgf("Creating directory %s", path)
-		return le.MkdirAll(path)
-	}
-
-	return nil
+	return ensureDir(le, path)
 }
 
-func (le LinuxExecuter) downloadToPath(ctx context.Context, downloadPath string, modTime time.Time, r io.Reader) error {
+func (le LinuxExecuter) WriteFile(ctx context.Context, downloadPath string, modTime time.Time, r io.Reader) error {
 	downloadFile := path.Base(downloadPath)
 	downloadDir := path.Dir(downloadPath)
 	le.MkdirAll(downloadDir)
@@ -130,8 +72,11 @@ func (le LinuxExecuter) CalculateHash(path string) (string, error) {
 	}
 	defer f.Close()
 
-	hasher := sha256.New()
-	io.Copy(hasher, f)
+	hasher := sha512.New()
+	_, err = io.Copy(hasher, f)
+	if err != nil {
+		return "", err
+	}
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
@@ -154,3 +99,52 @@ func (le LinuxExecuter) MkdirAll(path string) error {
 func (le LinuxExecuter) RemovePath(path string) error {
 	return os.RemoveAll(path)
 }
+
+func (le LinuxExecuter) WalkDir(path string, cb func(p string, isDir bool, continueDescending *bool)) error {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	buf := make([]byte, 1024)
+
+	for {
+		n, err := unix.Getdents(fd, buf)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		b := buf[:n]
+		for len(b) > 0 {
+			dirent := (*unix.Dirent)(unsafe.Pointer(&b[0]))
+			if dirent.Ino != 0 {
+				name := unix.ByteSliceToString(int8SliceToByteSlice(dirent.Name[:]))
+				var continueDescending bool = true
+				if dirent.Type == unix.DT_DIR {
+					if name != "." && name != ".." {
+						cb(path+"/"+name, true, &continueDescending)
+						if continueDescending {
+							err := le.WalkDir(path+"/"+name, cb)
+							if err != nil {
+								log.Warn().Err(err).Msgf("Failed to read directory: %s", path+"/"+name)
+							}
+						}
+					}
+				} else {
+					cb(path+"/"+name, false, &continueDescending)
+				}
+			}
+			b = b[dirent.Reclen:]
+		}
+	}
+
+	return nil
+}
+
+func int8SliceToByteSlice(a []int8) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(&a[0])), len(a))
+}
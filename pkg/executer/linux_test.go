@@ -0,0 +1,28 @@
+package executer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/executer"
+	"github.com/stretchr/testify/assert"
+)
+
+// EnsureDir logs through the global logger, so it must not depend on a
+// context logger being set up by main.
+func TestEnsureDirWithoutContextLogger(t *testing.T) {
+	e := executer.CreateLinuxExecuter()
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	assert.NoError(t, e.EnsureDir(dir))
+
+	file := filepath.Join(filepath.Dir(dir), "c")
+	assert.NoError(t, os.WriteFile(file, []byte("x"), 0o644))
+	assert.NoError(t, e.EnsureDir(file))
+
+	for _, p := range []string{dir, file} {
+		info, err := os.Stat(p)
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir(), p)
+	}
+}
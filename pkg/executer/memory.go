@@ -0,0 +1,280 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemoryExecuter is an in-memory filesystem for tests. It follows the
+// semantics of the Linux calls it stands in for, e.g. Rename fails when the
+// target directory does not exist.
+type MemoryExecuter struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	isDir   bool
+	content []byte
+	modTime time.Time
+}
+
+func NewMemoryExecuter() *MemoryExecuter {
+	return &MemoryExecuter{
+		entries: map[string]*memoryEntry{
+			"/": {isDir: true},
+		},
+	}
+}
+
+func (me *MemoryExecuter) EnsureFile(path string, modTime time.Time, hash string, downloadFunc func() (io.ReadCloser, error)) error {
+	return ensureFile(me, path, modTime, hash, downloadFunc)
+}
+
+func (me *MemoryExecuter) EnsureDir(path string) error {
+	return ensureDir(me, path)
+}
+
+func (me *MemoryExecuter) WriteFile(ctx context.Context, p string, modTime time.Time, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := me.MkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	p = cleanPath(p)
+	if e, ok := me.entries[p]; ok && e.isDir {
+		return &fs.PathError{Op: "rename", Path: p, Err: syscall.EISDIR}
+	}
+	me.entries[p] = &memoryEntry{content: content, modTime: modTime}
+	return nil
+}
+
+// ReadFile returns the content of the file at p.
+func (me *MemoryExecuter) ReadFile(p string) ([]byte, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	e, err := me.lookup("open", p)
+	if err != nil {
+		return nil, err
+	}
+	if e.isDir {
+		return nil, &fs.PathError{Op: "read", Path: p, Err: syscall.EISDIR}
+	}
+	return bytes.Clone(e.content), nil
+}
+
+func (me *MemoryExecuter) CalculateHash(p string) (string, error) {
+	content, err := me.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	sum := sha512.Sum512(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (me *MemoryExecuter) Stat(p string) (os.FileInfo, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	e, err := me.lookup("stat", p)
+	if err != nil {
+		return nil, err
+	}
+	return memoryFileInfo{name: path.Base(cleanPath(p)), entry: *e}, nil
+}
+
+func (me *MemoryExecuter) Chtimes(p string, mtime time.Time) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	e, err := me.lookup("chtimes", p)
+	if err != nil {
+		return err
+	}
+	e.modTime = mtime
+	return nil
+}
+
+func (me *MemoryExecuter) Rename(oldPath, newPath string) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	oldPath, newPath = cleanPath(oldPath), cleanPath(newPath)
+	src, err := me.lookup("rename", oldPath)
+	if err != nil {
+		return err
+	}
+	if parent, ok := me.entries[path.Dir(newPath)]; !ok || !parent.isDir {
+		return &fs.PathError{Op: "rename", Path: newPath, Err: syscall.ENOENT}
+	}
+	if oldPath == newPath {
+		return nil
+	}
+	if strings.HasPrefix(newPath, oldPath+"/") {
+		return &fs.PathError{Op: "rename", Path: newPath, Err: syscall.EINVAL}
+	}
+	if dst, ok := me.entries[newPath]; ok {
+		switch {
+		case dst.isDir && !src.isDir:
+			return &fs.PathError{Op: "rename", Path: newPath, Err: syscall.EISDIR}
+		case !dst.isDir && src.isDir:
+			return &fs.PathError{Op: "rename", Path: newPath, Err: syscall.ENOTDIR}
+		case dst.isDir && len(me.children(newPath)) > 0:
+			return &fs.PathError{Op: "rename", Path: newPath, Err: syscall.ENOTEMPTY}
+		}
+	}
+
+	for p, e := range me.entries {
+		if p == oldPath || strings.HasPrefix(p, oldPath+"/") {
+			delete(me.entries, p)
+			me.entries[newPath+strings.TrimPrefix(p, oldPath)] = e
+		}
+	}
+	return nil
+}
+
+func (me *MemoryExecuter) MkdirAll(p string) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	p = cleanPath(p)
+	for current := p; ; current = path.Dir(current) {
+		if e, ok := me.entries[current]; ok {
+			if !e.isDir {
+				return &fs.PathError{Op: "mkdir", Path: current, Err: syscall.ENOTDIR}
+			}
+			break
+		}
+	}
+	for current := p; ; current = path.Dir(current) {
+		if _, ok := me.entries[current]; ok {
+			break
+		}
+		me.entries[current] = &memoryEntry{isDir: true, modTime: time.Now()}
+	}
+	return nil
+}
+
+func (me *MemoryExecuter) RemovePath(p string) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	p = cleanPath(p)
+	for entryPath := range me.entries {
+		if entryPath == p || strings.HasPrefix(entryPath, p+"/") {
+			delete(me.entries, entryPath)
+		}
+	}
+	return nil
+}
+
+func (me *MemoryExecuter) WalkDir(p string, cb func(p string, isDir bool, continueDescending *bool)) error {
+	me.mu.Lock()
+	e, err := me.lookup("open", p)
+	if err == nil && !e.isDir {
+		err = &fs.PathError{Op: "open", Path: p, Err: syscall.ENOTDIR}
+	}
+	var children []string
+	if err == nil {
+		children = me.children(cleanPath(p))
+	}
+	me.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range children {
+		childPath := p + "/" + name
+		info, err := me.Stat(childPath)
+		if err != nil {
+			// removed by the callback
+			continue
+		}
+
+		continueDescending := true
+		cb(childPath, info.IsDir(), &continueDescending)
+		if info.IsDir() && continueDescending {
+			_ = me.WalkDir(childPath, cb)
+		}
+	}
+	return nil
+}
+
+func (me *MemoryExecuter) lookup(op, p string) (*memoryEntry, error) {
+	e, ok := me.entries[cleanPath(p)]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: p, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// children returns the sorted names of the direct children of dir.
+func (me *MemoryExecuter) children(dir string) []string {
+	prefix := dir + "/"
+	if dir == "/" {
+		prefix = "/"
+	}
+
+	var names []string
+	for p := range me.entries {
+		if p != dir && strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+			names = append(names, p[len(prefix):])
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+type memoryFileInfo struct {
+	name  string
+	entry memoryEntry
+}
+
+func (fi memoryFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi memoryFileInfo) Size() int64 {
+	return int64(len(fi.entry.content))
+}
+
+func (fi memoryFileInfo) Mode() fs.FileMode {
+	if fi.entry.isDir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (fi memoryFileInfo) ModTime() time.Time {
+	return fi.entry.modTime
+}
+
+func (fi memoryFileInfo) IsDir() bool {
+	return fi.entry.isDir
+}
+
+func (fi memoryFileInfo) Sys() any {
+	return nil
+}
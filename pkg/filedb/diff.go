@@ -1,101 +1,182 @@
 package filedb
 
 import (
-	"sort"
+	"context"
+	"iter"
+	"slices"
 )
 
-func toDiffSlice(ft *FileTree) []*fileTreeNodeInternal {
-	result := make([]*fileTreeNodeInternal, 0, len(ft.nodes))
-	for _, node := range ft.nodes {
-		result = append(result, node)
+type diffOptions struct {
+	subtree    Uuid
+	hasSubtree bool
+	types      map[DiffItemType]struct{}
+}
+
+func (o *diffOptions) wants(t DiffItemType) bool {
+	if o.types == nil {
+		return true
+	}
+	_, ok := o.types[t]
+	return ok
+}
+
+type DiffOption func(*diffOptions)
+
+// WithSubtree restricts the diff to uuid and its descendants. Nodes that moved
+// across the subtree boundary are reported as added or removed.
+func WithSubtree(uuid Uuid) DiffOption {
+	return func(o *diffOptions) {
+		o.subtree = uuid
+		o.hasSubtree = true
+	}
+}
+
+// WithTypes restricts the diff to the given item types. Work that is only
+// needed for other types is skipped.
+func WithTypes(types ...DiffItemType) DiffOption {
+	return func(o *diffOptions) {
+		if o.types == nil {
+			o.types = make(map[DiffItemType]struct{})
+		}
+		for _, t := range types {
+			o.types[t] = struct{}{}
+		}
 	}
+}
+
+func toDiffSlice(ft *FileTree, opts *diffOptions) []nodeIndex {
+	var result []nodeIndex
+	if opts.hasSubtree {
+		if root, ok := ft.index[opts.subtree]; ok {
+			result = ft.appendSubtree(result, root)
+		}
+	} else {
+		result = make([]nodeIndex, 0, len(ft.index))
+		for idx := range ft.nodes {
+			if ft.nodes[idx].alive {
+				result = append(result, nodeIndex(idx))
+			}
+		}
+	}
+	slices.SortFunc(result, func(a, b nodeIndex) int {
+		return CompareUuids(&ft.nodes[a].Uuid, &ft.nodes[b].Uuid)
+	})
 	return result
 }
 
-type nodeList []*fileTreeNodeInternal
+// Diff returns the items that turn is into should. The items form a plan that
+// can be applied in order: creates and moves come parent-first, content
+// changes follow, and removals come last, children-first.
+//
+// Both trees are read completely before the first item is yielded, so the
+// consumer may update is while iterating.
+func Diff(is, should *FileTree, options ...DiffOption) iter.Seq[DiffItem] {
+	var opts diffOptions
+	for _, option := range options {
+		option(&opts)
+	}
 
-func (l nodeList) Len() int           { return len(l) }
-func (l nodeList) Less(i, j int) bool { return CompareUuids(&l[i].Uuid, &l[j].Uuid) < 0 }
-func (l nodeList) Swap(i, j int) {
-	l[i], l[j] = l[j], l[i]
+	return func(yield func(DiffItem) bool) {
+		diff(is, should, &opts, yield)
+	}
 }
 
-func StartDiff(is, should *FileTree) chan DiffItem {
+// StartDiff streams Diff through a channel. The channel is closed when the
+// diff is complete or ctx is done.
+func StartDiff(ctx context.Context, is, should *FileTree, options ...DiffOption) <-chan DiffItem {
 	diffChannel := make(chan DiffItem, 100)
-	go diff(is, should, diffChannel)
+	go func() {
+		defer close(diffChannel)
+		for item := range Diff(is, should, options...) {
+			select {
+			case diffChannel <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
 	return diffChannel
 }
 
-func diff(is, should *FileTree, diffChannel chan DiffItem) {
-	defer close(diffChannel)
+func diff(is, should *FileTree, opts *diffOptions, yield func(DiffItem) bool) {
+	plan := newDiffPlanner(is, should, opts)
 
-	isNodes := nodeList(toDiffSlice(is))
-	sort.Sort(isNodes)
-	shouldNodes := nodeList(toDiffSlice(should))
-	sort.Sort(shouldNodes)
+	isNodes := toDiffSlice(is, opts)
+	shouldNodes := toDiffSlice(should, opts)
 
 	i, j := 0, 0
 	for i < len(isNodes) && j < len(shouldNodes) {
-		isNode := isNodes[i]
-		shouldNode := shouldNodes[j]
+		isIdx, shouldIdx := isNodes[i], shouldNodes[j]
+		isNode := &is.nodes[isIdx]
+		shouldNode := &should.nodes[shouldIdx]
 
 		if isNode.Uuid == shouldNode.Uuid {
-			var unequal bool
-			if !isNode.Modtime.Equal(shouldNode.Modtime) {
-				unequal = true
-			} else if isNode.getParentUuid() != shouldNode.getParentUuid() {
-				unequal = true
-			} else if isNode.IsDir != shouldNode.IsDir {
-				unequal = true
-			} else if isNode.Hash != shouldNode.Hash {
-				unequal = true
-			}
-
-			if unequal {
-				oldPath := isNode.GetPath()
-				newPath := shouldNode.GetPath()
-				diffChannel <- DiffModified{
-					Uuid:    isNode.Uuid,
-					OldPath: oldPath,
-					NewPath: newPath,
-				}
-			}
+			diffChanged(plan, isIdx, shouldIdx)
 			i++
 			j++
 		} else if CompareUuids(&isNode.Uuid, &shouldNode.Uuid) < 0 {
-			oldPath := isNode.GetPath()
-			diffChannel <- DiffRemoved{
-				Uuid: isNode.Uuid,
-				Path: oldPath,
-			}
+			plan.remove(isNode.Uuid)
 			i++
 		} else {
-			newPath := shouldNode.GetPath()
-			diffChannel <- DiffAdded{
-				Uuid: shouldNode.Uuid,
-				Path: newPath,
-			}
+			plan.add(shouldIdx)
 			j++
 		}
 	}
 
-	for i < len(isNodes) {
-		oldPath := isNodes[i].GetPath()
-		diffChannel <- DiffRemoved{
-			Uuid: isNodes[i].Uuid,
-			Path: oldPath,
-		}
-		i++
+	for ; i < len(isNodes); i++ {
+		plan.remove(is.nodes[isNodes[i]].Uuid)
 	}
 
-	for j < len(shouldNodes) {
-		newPath := shouldNodes[j].GetPath()
-		diffChannel <- DiffAdded{
-			Uuid: shouldNodes[j].Uuid,
-			Path: newPath,
-		}
+	for ; j < len(shouldNodes); j++ {
+		plan.add(shouldNodes[j])
+	}
+
+	plan.emit(yield)
+}
+
+func diffChanged(plan *diffPlanner, isIdx, shouldIdx nodeIndex) {
+	is, should := plan.is, plan.should
+	isNode := &is.nodes[isIdx]
+	shouldNode := &should.nodes[shouldIdx]
+
+	typeChanged := isNode.IsDir != shouldNode.IsDir
+	moved := is.parentUuid(isIdx) != should.parentUuid(shouldIdx) || !sameName(is, should, isIdx, shouldIdx)
+	contentChanged := isNode.Hash != shouldNode.Hash
+	metadataChanged := !isNode.modtimeEqual(shouldNode)
+
+	if typeChanged {
+		plan.changeType(shouldIdx)
+		return
+	}
+	if moved {
+		plan.move(shouldIdx)
+	}
+	if contentChanged {
+		metadataChanged = false
+	}
+	if !(contentChanged && plan.opts.wants(DiffItemTypeContentChanged)) &&
+		!(metadataChanged && plan.opts.wants(DiffItemTypeMetadataChanged)) {
+		return
+	}
+
+	change := DiffChange{
+		Uuid:    isNode.Uuid,
+		OldPath: is.path(isIdx),
+		NewPath: should.path(shouldIdx),
+		Old:     is.toFileTreeNode(isIdx),
+		New:     should.toFileTreeNode(shouldIdx),
+	}
+	if contentChanged {
+		plan.changes = append(plan.changes, DiffContentChanged{change})
+	} else {
+		plan.changes = append(plan.changes, DiffMetadataChanged{change})
+	}
+}
 
-		j++
+func sameName(is, should *FileTree, isIdx, shouldIdx nodeIndex) bool {
+	if is.names == should.names {
+		return is.nodes[isIdx].name == should.nodes[shouldIdx].name
 	}
+	return is.names.get(is.nodes[isIdx].name) == should.names.get(should.nodes[shouldIdx].name)
 }
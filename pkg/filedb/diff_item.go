@@ -5,7 +5,10 @@ type DiffItemType int
 const (
 	DiffItemTypeAdded DiffItemType = iota
 	DiffItemTypeRemoved
-	DiffItemTypeModified
+	DiffItemTypeMoved
+	DiffItemTypeContentChanged
+	DiffItemTypeTypeChanged
+	DiffItemTypeMetadataChanged
 )
 
 type DiffItem interface {
@@ -30,12 +33,48 @@ func (n DiffRemoved) Type() DiffItemType {
 	return DiffItemTypeRemoved
 }
 
-type DiffModified struct {
+// DiffChange holds the state of a node present in both trees.
+type DiffChange struct {
 	Uuid    Uuid
 	OldPath string
 	NewPath string
+	Old     FileTreeNode
+	New     FileTreeNode
 }
 
-func (n DiffModified) Type() DiffItemType {
-	return DiffItemTypeModified
+// DiffMoved is emitted when a node got a new parent or name.
+type DiffMoved struct {
+	DiffChange
+}
+
+func (n DiffMoved) Type() DiffItemType {
+	return DiffItemTypeMoved
+}
+
+// DiffContentChanged is emitted when the hash of a file changed.
+type DiffContentChanged struct {
+	DiffChange
+}
+
+func (n DiffContentChanged) Type() DiffItemType {
+	return DiffItemTypeContentChanged
+}
+
+// DiffTypeChanged is emitted when a node changed between file and directory.
+// It replaces any other change item for that node.
+type DiffTypeChanged struct {
+	DiffChange
+}
+
+func (n DiffTypeChanged) Type() DiffItemType {
+	return DiffItemTypeTypeChanged
+}
+
+// DiffMetadataChanged is emitted when only the modification time changed.
+type DiffMetadataChanged struct {
+	DiffChange
+}
+
+func (n DiffMetadataChanged) Type() DiffItemType {
+	return DiffItemTypeMetadataChanged
 }
@@ -0,0 +1,285 @@
+package filedb
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+type structuralKind int
+
+const (
+	structuralAdd structuralKind = iota
+	structuralMove
+	structuralTypeChange
+)
+
+type structuralChange struct {
+	kind      structuralKind
+	shouldIdx nodeIndex
+	depth     int
+}
+
+type childKey struct {
+	parent Uuid
+	name   FileName
+}
+
+// diffPlanner orders the raw differences between two trees so that they can
+// be applied one after another. Structural changes are replayed on a copy of
+// the is tree, which yields the paths valid at the time each item is applied.
+type diffPlanner struct {
+	is         *FileTree
+	should     *FileTree
+	work       *FileTree
+	opts       *diffOptions
+	yield      func(DiffItem) bool
+	stopped    bool
+	structural []structuralChange
+	changes    []DiffItem
+	removed    []Uuid
+
+	// pending holds nodes that are still at their old location in work but
+	// will be moved or removed later in the plan.
+	pending  map[Uuid]struct{}
+	occupied map[childKey]Uuid
+}
+
+func newDiffPlanner(is, should *FileTree, opts *diffOptions) *diffPlanner {
+	return &diffPlanner{
+		is:      is,
+		should:  should,
+		opts:    opts,
+		pending: make(map[Uuid]struct{}),
+	}
+}
+
+func (p *diffPlanner) add(shouldIdx nodeIndex) {
+	p.structural = append(p.structural, structuralChange{kind: structuralAdd, shouldIdx: shouldIdx})
+}
+
+func (p *diffPlanner) move(shouldIdx nodeIndex) {
+	p.structural = append(p.structural, structuralChange{kind: structuralMove, shouldIdx: shouldIdx})
+	p.pending[p.should.nodes[shouldIdx].Uuid] = struct{}{}
+}
+
+func (p *diffPlanner) changeType(shouldIdx nodeIndex) {
+	p.structural = append(p.structural, structuralChange{kind: structuralTypeChange, shouldIdx: shouldIdx})
+	p.pending[p.should.nodes[shouldIdx].Uuid] = struct{}{}
+}
+
+func (p *diffPlanner) remove(uuid Uuid) {
+	p.removed = append(p.removed, uuid)
+	p.pending[uuid] = struct{}{}
+}
+
+func (p *diffPlanner) emit(yield func(DiffItem) bool) {
+	p.yield = yield
+	p.work = p.is
+	if len(p.structural) > 0 && p.wantsStructural() {
+		p.work = NewFileTree()
+		p.work.CopyFrom(p.is)
+		p.emitStructural()
+	}
+
+	removals := p.prepareRemovals()
+
+	for _, item := range p.changes {
+		if !p.send(item) {
+			return
+		}
+	}
+
+	for _, item := range removals {
+		if !p.send(item) {
+			return
+		}
+	}
+}
+
+// send yields item unless it is filtered out and reports whether the
+// consumer wants more items.
+func (p *diffPlanner) send(item DiffItem) bool {
+	if p.stopped {
+		return false
+	}
+	if p.opts.wants(item.Type()) && !p.yield(item) {
+		p.stopped = true
+	}
+	return !p.stopped
+}
+
+func (p *diffPlanner) wantsStructural() bool {
+	return p.opts.wants(DiffItemTypeAdded) || p.opts.wants(DiffItemTypeMoved) || p.opts.wants(DiffItemTypeTypeChanged)
+}
+
+// emitStructural emits adds, moves and type changes ordered by their depth
+// in the should tree, so every parent is in place before its children.
+func (p *diffPlanner) emitStructural() {
+	for i := range p.structural {
+		p.structural[i].depth = p.should.depth(p.structural[i].shouldIdx)
+	}
+	slices.SortFunc(p.structural, func(a, b structuralChange) int {
+		if c := cmp.Compare(a.depth, b.depth); c != 0 {
+			return c
+		}
+		return CompareUuids(&p.should.nodes[a.shouldIdx].Uuid, &p.should.nodes[b.shouldIdx].Uuid)
+	})
+
+	p.buildOccupancy()
+
+	for _, change := range p.structural {
+		if p.stopped {
+			return
+		}
+		target := p.should.toFileTreeNode(change.shouldIdx)
+		p.clearTarget(target)
+
+		idx, inWork := p.work.index[target.Uuid]
+		switch {
+		case change.kind == structuralAdd || !inWork:
+			// Moved nodes can be missing from work if their old parent
+			// was replaced by a type change; they have to be recreated.
+			p.work.EnsureItems([]FileTreeNode{target})
+			p.send(DiffAdded{
+				Uuid: target.Uuid,
+				Path: p.work.path(p.work.index[target.Uuid]),
+			})
+		case change.kind == structuralMove:
+			old := p.work.toFileTreeNode(idx)
+			oldPath := p.work.path(idx)
+			p.work.Move(target.Uuid, target.Parent, target.Name)
+			p.vacate(old)
+			p.send(DiffMoved{DiffChange{
+				Uuid:    target.Uuid,
+				OldPath: oldPath,
+				NewPath: p.work.path(idx),
+				Old:     old,
+				New:     target,
+			}})
+		case change.kind == structuralTypeChange:
+			old := p.work.toFileTreeNode(idx)
+			oldPath := p.work.path(idx)
+			p.work.Remove(target.Uuid)
+			p.work.EnsureItems([]FileTreeNode{target})
+			p.vacate(old)
+			p.send(DiffTypeChanged{DiffChange{
+				Uuid:    target.Uuid,
+				OldPath: oldPath,
+				NewPath: p.work.path(p.work.index[target.Uuid]),
+				Old:     old,
+				New:     target,
+			}})
+		}
+
+		delete(p.pending, target.Uuid)
+		p.occupied[childKey{parent: target.Parent, name: target.Name}] = target.Uuid
+	}
+}
+
+// buildOccupancy records which names are taken in every directory that
+// receives a new or moved node.
+func (p *diffPlanner) buildOccupancy() {
+	targets := make(map[Uuid]struct{})
+	for _, change := range p.structural {
+		targets[p.should.parentUuid(change.shouldIdx)] = struct{}{}
+	}
+
+	p.occupied = make(map[childKey]Uuid)
+	for idx := range p.work.nodes {
+		node := &p.work.nodes[idx]
+		if !node.alive {
+			continue
+		}
+		parent := p.work.parentUuid(nodeIndex(idx))
+		if _, ok := targets[parent]; ok {
+			p.occupied[childKey{parent: parent, name: p.work.names.get(node.name)}] = node.Uuid
+		}
+	}
+}
+
+// clearTarget moves a node that is in the way of target to a temporary name
+// if that node is going to be moved or removed anyway. This resolves swaps
+// and cyclic renames without clobbering.
+func (p *diffPlanner) clearTarget(target FileTreeNode) {
+	key := childKey{parent: target.Parent, name: target.Name}
+	blocker, ok := p.occupied[key]
+	if !ok || blocker == target.Uuid {
+		return
+	}
+	if _, isPending := p.pending[blocker]; !isPending {
+		return
+	}
+	idx, ok := p.work.index[blocker]
+	if !ok {
+		return
+	}
+
+	old := p.work.toFileTreeNode(idx)
+	oldPath := p.work.path(idx)
+	tmp := old
+	tmp.Name = FileNameFromString(fmt.Sprintf(".filen-mirror-%s.tmp", blocker))
+
+	p.work.Move(blocker, tmp.Parent, tmp.Name)
+	delete(p.occupied, key)
+	p.occupied[childKey{parent: tmp.Parent, name: tmp.Name}] = blocker
+
+	p.send(DiffMoved{DiffChange{
+		Uuid:    blocker,
+		OldPath: oldPath,
+		NewPath: p.work.path(idx),
+		Old:     old,
+		New:     tmp,
+	}})
+}
+
+func (p *diffPlanner) vacate(old FileTreeNode) {
+	key := childKey{parent: old.Parent, name: old.Name}
+	if p.occupied[key] == old.Uuid {
+		delete(p.occupied, key)
+	}
+}
+
+// prepareRemovals orders removed nodes children-first and resolves their
+// paths after all structural changes were applied.
+func (p *diffPlanner) prepareRemovals() []DiffItem {
+	if !p.opts.wants(DiffItemTypeRemoved) {
+		return nil
+	}
+
+	type removal struct {
+		uuid  Uuid
+		path  string
+		depth int
+	}
+
+	removals := make([]removal, 0, len(p.removed))
+	for _, uuid := range p.removed {
+		idx, ok := p.work.index[uuid]
+		if !ok {
+			// already gone together with a replaced parent
+			continue
+		}
+		removals = append(removals, removal{
+			uuid:  uuid,
+			path:  p.work.path(idx),
+			depth: p.work.depth(idx),
+		})
+	}
+
+	slices.SortFunc(removals, func(a, b removal) int {
+		if c := cmp.Compare(b.depth, a.depth); c != 0 {
+			return c
+		}
+		return CompareUuids(&a.uuid, &b.uuid)
+	})
+
+	items := make([]DiffItem, 0, len(removals))
+	for _, r := range removals {
+		items = append(items, DiffRemoved{
+			Uuid: r.uuid,
+			Path: r.path,
+		})
+	}
+	return items
+}
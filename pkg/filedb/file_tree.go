@@ -2,46 +2,69 @@ package filedb
 
 import (
 	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
 type FileTree struct {
-	nodes map[Uuid]*fileTreeNodeInternal
+	index map[Uuid]nodeIndex
+	nodes []fileTreeNodeInternal
+	free  []nodeIndex
+	names *nameTable
+	paths *pathCache
 }
 
 func NewFileTree() *FileTree {
 	return &FileTree{
-		nodes: make(map[Uuid]*fileTreeNodeInternal),
+		index: make(map[Uuid]nodeIndex),
+		names: newNameTable(),
+		paths: newPathCache(defaultPathCacheSize),
 	}
 }
 
 func (ft *FileTree) CopyFrom(other *FileTree) {
-	ft.nodes = make(map[Uuid]*fileTreeNodeInternal)
-	maps.Copy(ft.nodes, other.nodes)
+	ft.index = maps.Clone(other.index)
+	ft.nodes = append(ft.nodes[:0], other.nodes...)
+	ft.free = append(ft.free[:0], other.free...)
+	ft.names = other.names.clone()
+	ft.paths.clear()
 }
 
+// Len returns the number of nodes in the tree.
+func (ft *FileTree) Len() int {
+	return len(ft.index)
+}
+
+// EnsureItems creates or updates items. An item placed into itself or one of
+// its descendants is ignored, like in Move.
 func (ft *FileTree) EnsureItems(items []FileTreeNode) {
+	ft.nodes = slices.Grow(ft.nodes, max(0, len(items)-len(ft.free)))
 	for _, item := range items {
+		idx, exists := ft.index[item.Uuid]
+		if item.Parent == item.Uuid || exists && ft.isWithin(item.Parent, idx) {
+			continue
+		}
+
 		parent := ft.ensureParent(item.Parent)
-		node, exists := ft.nodes[item.Uuid]
 		if !exists {
-			node = &fileTreeNodeInternal{
-				Uuid: item.Uuid,
-			}
+			idx = ft.alloc(item.Uuid)
 		} else {
-			node.invalidatePathCache()
+			ft.unlink(idx)
+			ft.invalidatePaths(idx)
 		}
 
-		node.Name = item.Name
+		name := ft.names.intern(item.Name)
+		node := &ft.nodes[idx]
+		if exists {
+			ft.names.release(node.name)
+		}
+		node.name = name
 		node.Hash = item.Hash
-		node.Modtime = item.Modtime
+		node.setModtime(item.Modtime)
 		node.IsDir = item.IsDir
-		node.Parent = parent
 
-		ft.nodes[item.Uuid] = node
-		if parent != nil {
-			parent.Children = append(parent.Children, node)
-		}
+		ft.link(idx, parent)
 	}
 }
 
@@ -65,112 +88,255 @@ func (ft *FileTree) CreateDir(uuid Uuid, parentUuid Uuid, name string) {
 	}})
 }
 
-func (ft *FileTree) ensureParent(uuid Uuid) *fileTreeNodeInternal {
+func (ft *FileTree) ensureParent(uuid Uuid) nodeIndex {
 	if uuid == NilUuid {
-		return nil
+		return noNode
 	}
 
-	parent, exists := ft.nodes[uuid]
-	if !exists || !parent.IsDir {
+	parent, exists := ft.index[uuid]
+	if !exists || !ft.nodes[parent].IsDir {
 		ft.EnsureItems([]FileTreeNode{{
 			Uuid:   uuid,
 			Name:   FileNameFromString(""),
 			IsDir:  true,
 			Parent: NilUuid,
 		}})
-		parent = ft.nodes[uuid]
+		parent = ft.index[uuid]
 	}
 	return parent
 }
 
+func (ft *FileTree) alloc(uuid Uuid) nodeIndex {
+	var idx nodeIndex
+	if n := len(ft.free); n > 0 {
+		idx = ft.free[n-1]
+		ft.free = ft.free[:n-1]
+	} else {
+		idx = nodeIndex(len(ft.nodes))
+		ft.nodes = append(ft.nodes, fileTreeNodeInternal{})
+	}
+
+	ft.nodes[idx] = fileTreeNodeInternal{
+		Uuid:        uuid,
+		parent:      noNode,
+		firstChild:  noNode,
+		nextSibling: noNode,
+		prevSibling: noNode,
+		alive:       true,
+	}
+	ft.index[uuid] = idx
+	return idx
+}
+
+func (ft *FileTree) link(idx, parent nodeIndex) {
+	node := &ft.nodes[idx]
+	node.parent = parent
+	if parent == noNode {
+		return
+	}
+
+	p := &ft.nodes[parent]
+	node.nextSibling = p.firstChild
+	if p.firstChild != noNode {
+		ft.nodes[p.firstChild].prevSibling = idx
+	}
+	p.firstChild = idx
+}
+
+func (ft *FileTree) unlink(idx nodeIndex) {
+	node := &ft.nodes[idx]
+	if node.prevSibling != noNode {
+		ft.nodes[node.prevSibling].nextSibling = node.nextSibling
+	} else if node.parent != noNode {
+		ft.nodes[node.parent].firstChild = node.nextSibling
+	}
+	if node.nextSibling != noNode {
+		ft.nodes[node.nextSibling].prevSibling = node.prevSibling
+	}
+
+	node.parent = noNode
+	node.nextSibling = noNode
+	node.prevSibling = noNode
+}
+
+// invalidatePaths drops cached paths that may change when idx is moved or
+// removed. Paths of descendants are only cached for directories, so the
+// cache is cleared entirely in that case.
+func (ft *FileTree) invalidatePaths(idx nodeIndex) {
+	if ft.nodes[idx].firstChild != noNode || ft.nodes[idx].IsDir {
+		ft.paths.clear()
+		return
+	}
+	ft.paths.remove(idx)
+}
+
 func (ft *FileTree) Remove(uuid Uuid) {
-	node, exists := ft.nodes[Uuid(uuid)]
+	idx, exists := ft.index[uuid]
 	if !exists {
 		return
 	}
-	delete(ft.nodes, Uuid(uuid))
 
+	ft.invalidatePaths(idx)
+	ft.unlink(idx)
+	ft.release(idx)
+}
+
+func (ft *FileTree) release(idx nodeIndex) {
 	// Recursively remove children
-	for _, child := range node.Children {
-		ft.Remove(child.Uuid)
+	for child := ft.nodes[idx].firstChild; child != noNode; {
+		next := ft.nodes[child].nextSibling
+		ft.release(child)
+		child = next
 	}
 
+	delete(ft.index, ft.nodes[idx].Uuid)
+	ft.names.release(ft.nodes[idx].name)
+	ft.nodes[idx] = fileTreeNodeInternal{}
+	ft.free = append(ft.free, idx)
 }
 
+// Move reparents and renames uuid. Moving a node into itself or one of its
+// descendants is ignored.
 func (ft *FileTree) Move(uuid Uuid, newParentUuid Uuid, newName FileName) {
-	node, exists := ft.nodes[Uuid(uuid)]
+	idx, exists := ft.index[uuid]
 	if !exists {
 		return
 	}
-	node.invalidatePathCache()
-
-	if node.Parent != nil {
-		for i, child := range node.Parent.Children {
-			if child.Uuid == uuid {
-				// Remove from old parent's children
-				node.Parent.Children = append(node.Parent.Children[:i], node.Parent.Children[i+1:]...)
-				break
-			}
-		}
+	if ft.isWithin(newParentUuid, idx) {
+		return
 	}
+	ft.invalidatePaths(idx)
+	ft.unlink(idx)
 
-	node.Parent = ft.ensureParent(newParentUuid)
-	if node.Parent != nil {
-		node.Parent.Children = append(node.Parent.Children, node)
-	}
+	parent := ft.ensureParent(newParentUuid)
+	name := ft.names.intern(newName)
+	ft.names.release(ft.nodes[idx].name)
+	ft.nodes[idx].name = name
+	ft.link(idx, parent)
+}
 
-	node.Name = newName
-	ft.nodes[uuid] = node
+// isWithin reports whether uuid is idx or one of its descendants.
+func (ft *FileTree) isWithin(uuid Uuid, idx nodeIndex) bool {
+	start, ok := ft.index[uuid]
+	if !ok {
+		return false
+	}
+	for p := start; p != noNode; p = ft.nodes[p].parent {
+		if p == idx {
+			return true
+		}
+	}
+	return false
 }
 
 func (ft *FileTree) SetModtime(uuid Uuid, modtime time.Time) {
-	node, exists := ft.nodes[uuid]
+	idx, exists := ft.index[uuid]
 	if !exists {
 		return
 	}
-	node.Modtime = modtime
-	ft.nodes[uuid] = node
+	ft.nodes[idx].setModtime(modtime)
 }
 
-func (ft FileTree) GetNode(uuid Uuid) (FileTreeNode, bool) {
-	node, exists := ft.nodes[uuid]
+func (ft *FileTree) GetNode(uuid Uuid) (FileTreeNode, bool) {
+	idx, exists := ft.index[uuid]
 	if !exists {
 		return FileTreeNode{}, false
 	}
 
+	return ft.toFileTreeNode(idx), true
+}
+
+func (ft *FileTree) toFileTreeNode(idx nodeIndex) FileTreeNode {
+	node := &ft.nodes[idx]
 	return FileTreeNode{
-		Uuid:    uuid,
-		Name:    node.Name,
+		Uuid:    node.Uuid,
+		Name:    ft.names.get(node.name),
 		Hash:    node.Hash,
-		Modtime: node.Modtime,
+		Modtime: node.modtime(),
 		IsDir:   node.IsDir,
-		Parent:  node.getParentUuid(),
-	}, true
+		Parent:  ft.parentUuid(idx),
+	}
+}
+
+func (ft *FileTree) parentUuid(idx nodeIndex) Uuid {
+	parent := ft.nodes[idx].parent
+	if parent == noNode {
+		return NilUuid
+	}
+	return ft.nodes[parent].Uuid
 }
 
 func (ft *FileTree) GetPath(uuid Uuid) (string, bool) {
-	node, exists := ft.nodes[uuid]
+	idx, exists := ft.index[uuid]
 	if !exists {
 		return "", false
 	}
 
-	return node.GetPath(), true
+	return ft.path(idx), true
+}
+
+func (ft *FileTree) path(idx nodeIndex) string {
+	if p, ok := ft.paths.get(idx); ok {
+		return p
+	}
+
+	node := &ft.nodes[idx]
+	name := ft.names.get(node.name).String()
+	var p string
+	if node.parent == noNode {
+		p = name
+	} else {
+		parentPath := ft.path(node.parent)
+		var b strings.Builder
+		b.Grow(len(parentPath) + 1 + len(name))
+		b.WriteString(parentPath)
+		b.WriteByte('/')
+		b.WriteString(name)
+		p = b.String()
+	}
+
+	ft.paths.put(idx, p)
+	return p
 }
 
 func (ft *FileTree) GetParents(uuid Uuid) ([]Uuid, bool) {
+	idx, exists := ft.index[uuid]
+	if !exists {
+		return nil, false
+	}
+
 	var parents []Uuid
-	currentParent := ft.nodes[uuid].Parent
-	for currentParent != nil {
-		parents = append([]Uuid{currentParent.Uuid}, parents...)
-		currentParent = currentParent.Parent
+	for current := ft.nodes[idx].parent; current != noNode; current = ft.nodes[current].parent {
+		parents = append([]Uuid{ft.nodes[current].Uuid}, parents...)
 	}
 	return parents, true
 }
 
-func (ft FileTree) GetPathToUuidMap() map[string]Uuid {
-	var paths = make(map[string]Uuid)
-	for uuid := range ft.nodes {
-		paths[ft.nodes[uuid].GetPath()] = uuid
+// appendSubtree appends idx and all of its descendants to result.
+func (ft *FileTree) appendSubtree(result []nodeIndex, idx nodeIndex) []nodeIndex {
+	result = append(result, idx)
+	for child := ft.nodes[idx].firstChild; child != noNode; child = ft.nodes[child].nextSibling {
+		result = ft.appendSubtree(result, child)
+	}
+	return result
+}
+
+// depth returns the number of ancestors of idx.
+func (ft *FileTree) depth(idx nodeIndex) int {
+	var d int
+	for current := ft.nodes[idx].parent; current != noNode; current = ft.nodes[current].parent {
+		d++
+	}
+	return d
+}
+
+func (ft *FileTree) GetPathToUuidMap() map[string]Uuid {
+	var paths = make(map[string]Uuid, len(ft.index))
+	for idx := range ft.nodes {
+		if !ft.nodes[idx].alive {
+			continue
+		}
+		paths[ft.path(nodeIndex(idx))] = ft.nodes[idx].Uuid
 	}
 	return paths
 }
@@ -1,68 +1,12 @@
 package filedb
 
 import (
-	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
-	"strings"
+	"slices"
 	"time"
 )
 
-type Uuid [8]uint64
-
-var NilUuid Uuid
-
-func UuidFromString(s string) Uuid {
-	var u Uuid
-	bytes := make([]byte, 64)
-	copy(bytes, s)
-	for i := 0; i < 8; i++ {
-		u[i] = binary.LittleEndian.Uint64(bytes[i*8 : (i+1)*8])
-	}
-	return u
-}
-
-func (u Uuid) String() string {
-	var bytes [64]byte
-	for i := 0; i < 8; i++ {
-		binary.LittleEndian.PutUint64(bytes[i*8:(i+1)*8], u[i])
-	}
-
-	var end int
-	for i, b := range bytes {
-		if b == 0 {
-			end = i
-			break
-		}
-	}
-
-	return string(bytes[:end])
-}
-
-func (u Uuid) MarshalJSON() ([]byte, error) {
-	return json.Marshal(u.String())
-}
-
-func (u *Uuid) UnmarshalJSON(data []byte) error {
-	var s string
-	if err := json.Unmarshal(data, &s); err != nil {
-		return err
-	}
-	*u = UuidFromString(s)
-	return nil
-}
-
-func CompareUuids(a, b *Uuid) int {
-	for i := range 8 {
-		if a[i] < b[i] {
-			return -1
-		} else if a[i] > b[i] {
-			return 1
-		}
-	}
-	return 0
-}
-
 type FileName string
 
 func FileNameFromString(s string) FileName {
@@ -86,7 +30,8 @@ func (u *FileName) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-type Hash [32]byte
+// Hash is a SHA-512 file hash as Filen reports it.
+type Hash [64]byte
 
 func (u Hash) MarshalJSON() ([]byte, error) {
 	return json.Marshal(u.String())
@@ -125,44 +70,111 @@ func (i FileTreeNode) SortString() string {
 	return i.Uuid.String()
 }
 
+// nodeIndex addresses a slot in the FileTree node arena.
+type nodeIndex int32
+
+const noNode nodeIndex = -1
+
+// fileTreeNodeInternal is one arena slot. Links to other nodes are arena
+// indices rather than pointers, so the arena is a single allocation the GC
+// does not need to scan and that can be copied with one memmove.
 type fileTreeNodeInternal struct {
-	Uuid     Uuid
-	Name     FileName
-	Hash     Hash
-	Modtime  time.Time
-	IsDir    bool
-	Parent   *fileTreeNodeInternal
-	Children []*fileTreeNodeInternal
-	path     string
-}
-
-func (n *fileTreeNodeInternal) getParentUuid() Uuid {
-	if n.Parent == nil {
-		return NilUuid
+	Uuid        Uuid
+	Hash        Hash
+	modSec      int64
+	modNsec     int32
+	name        nameIndex
+	parent      nodeIndex
+	firstChild  nodeIndex
+	nextSibling nodeIndex
+	prevSibling nodeIndex
+	IsDir       bool
+	alive       bool
+}
+
+func (n *fileTreeNodeInternal) setModtime(t time.Time) {
+	n.modSec = t.Unix()
+	n.modNsec = int32(t.Nanosecond())
+}
+
+func (n *fileTreeNodeInternal) modtime() time.Time {
+	return time.Unix(n.modSec, int64(n.modNsec))
+}
+
+func (n *fileTreeNodeInternal) modtimeEqual(o *fileTreeNodeInternal) bool {
+	return n.modSec == o.modSec && n.modNsec == o.modNsec
+}
+
+type nameIndex uint32
+
+// nameTable interns the file names of one tree. Entries are counted by the
+// nodes using them and the slots of unused names are reused, so the table
+// does not outgrow the tree.
+type nameTable struct {
+	byName  map[FileName]nameIndex
+	entries []FileName
+	refs    []int32
+	free    []nameIndex
+}
+
+func newNameTable() *nameTable {
+	return &nameTable{
+		byName: make(map[FileName]nameIndex),
 	}
-	return n.Parent.Uuid
 }
 
-func (n *fileTreeNodeInternal) GetPath() string {
-	if n.path == "" {
-		parts := n.getPathParts()
-		n.path = strings.Join(parts, "/")
+// clone returns a copy, so that a tree created by CopyFrom can intern
+// names without affecting its source. The lookup map of the copy is only
+// built once a name is interned.
+func (t *nameTable) clone() *nameTable {
+	return &nameTable{
+		entries: slices.Clone(t.entries),
+		refs:    slices.Clone(t.refs),
+		free:    slices.Clone(t.free),
 	}
-	return n.path
 }
 
-func (n *fileTreeNodeInternal) invalidatePathCache() {
-	n.path = ""
-	for _, child := range n.Children {
-		child.invalidatePathCache()
+// intern returns the index of name and counts a reference to it, which
+// has to be dropped with release.
+func (t *nameTable) intern(name FileName) nameIndex {
+	if t.byName == nil {
+		t.byName = make(map[FileName]nameIndex, len(t.entries)-len(t.free))
+		for idx, entry := range t.entries {
+			if t.refs[idx] > 0 {
+				t.byName[entry] = nameIndex(idx)
+			}
+		}
+	}
+	if idx, ok := t.byName[name]; ok {
+		t.refs[idx]++
+		return idx
 	}
+
+	var idx nameIndex
+	if n := len(t.free); n > 0 {
+		idx = t.free[n-1]
+		t.free = t.free[:n-1]
+		t.entries[idx] = name
+		t.refs[idx] = 1
+	} else {
+		idx = nameIndex(len(t.entries))
+		t.entries = append(t.entries, name)
+		t.refs = append(t.refs, 1)
+	}
+	t.byName[name] = idx
+	return idx
 }
 
-func (n *fileTreeNodeInternal) getPathParts() []string {
-	parts := []string{}
-	if n.Parent != nil {
-		parts = n.Parent.getPathParts()
+func (t *nameTable) release(idx nameIndex) {
+	t.refs[idx]--
+	if t.refs[idx] > 0 {
+		return
 	}
+	delete(t.byName, t.entries[idx])
+	t.entries[idx] = ""
+	t.free = append(t.free, idx)
+}
 
-	return append(parts, n.Name.String())
+func (t *nameTable) get(idx nameIndex) FileName {
+	return t.entries[idx]
 }
@@ -1,8 +1,12 @@
 package filedb_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,15 +14,20 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+const benchmarkTreeSize = 1_000_000
+
 var benchmarkTree1 *filedb.FileTree
 var benchmarkTree2 *filedb.FileTree
+var benchmarkTreesOnce sync.Once
 
-func init() {
-	benchmarkTree1 = filedb.NewFileTree()
-	loadTestTree(nil, benchmarkTree1)
+func loadBenchmarkTrees(b *testing.B) {
+	benchmarkTreesOnce.Do(func() {
+		benchmarkTree1 = filedb.NewFileTree()
+		loadTestTree(b, benchmarkTree1)
 
-	benchmarkTree2 = filedb.NewFileTree()
-	loadTestTree(nil, benchmarkTree2)
+		benchmarkTree2 = filedb.NewFileTree()
+		loadTestTree(b, benchmarkTree2)
+	})
 }
 
 func TestDiffRemovedFile(t *testing.T) {
@@ -27,7 +36,7 @@ func TestDiffRemovedFile(t *testing.T) {
 
 	tree2.Remove(filedb.UuidFromString("file1"))
 	var numDiffs int
-	for diffItem := range filedb.StartDiff(tree1, tree2) {
+	for diffItem := range filedb.Diff(tree1, tree2) {
 		numDiffs++
 		d, ok := diffItem.(filedb.DiffRemoved)
 		assert.True(t, ok)
@@ -42,7 +51,7 @@ func TestDiffRemovedDir(t *testing.T) {
 
 	tree2.Remove(filedb.UuidFromString("dir2"))
 	var numDiffs int
-	for diffItem := range filedb.StartDiff(tree1, tree2) {
+	for diffItem := range filedb.Diff(tree1, tree2) {
 		numDiffs++
 		d, ok := diffItem.(filedb.DiffRemoved)
 		assert.True(t, ok)
@@ -57,38 +66,406 @@ func TestDiffMoveDir(t *testing.T) {
 
 	tree2.Move(filedb.UuidFromString("dir2"), filedb.NilUuid, "moved-dir")
 	var numDiffs int
-	for diffItem := range filedb.StartDiff(tree1, tree2) {
+	for diffItem := range filedb.Diff(tree1, tree2) {
 		numDiffs++
-		d, ok := diffItem.(filedb.DiffModified)
+		d, ok := diffItem.(filedb.DiffMoved)
 		assert.True(t, ok)
 		assert.Equal(t, filedb.UuidFromString("dir2"), d.Uuid)
 		assert.Equal(t, "dir1/dir2", d.OldPath)
 		assert.Equal(t, "moved-dir", d.NewPath)
+		assert.Equal(t, filedb.UuidFromString("dir1"), d.Old.Parent)
+		assert.Equal(t, filedb.NilUuid, d.New.Parent)
 	}
 	assert.Equal(t, 1, numDiffs)
 }
 
-func BenchmarkDiff(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		for range filedb.StartDiff(benchmarkTree1, benchmarkTree2) {
+func TestDiffRenameFile(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.Move(filedb.UuidFromString("file1"), filedb.UuidFromString("dir2"), "renamed.txt")
+	diffs := collectDiff(tree1, tree2)
+	assert.Len(t, diffs, 1)
+	d, ok := diffs[0].(filedb.DiffMoved)
+	assert.True(t, ok)
+	assert.Equal(t, "dir1/dir2/renamed.txt", d.NewPath)
+	assert.Equal(t, filedb.FileName("file1.txt"), d.Old.Name)
+}
+
+func TestDiffContentChanged(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.CreateFile(filedb.UuidFromString("file1"), filedb.UuidFromString("dir2"), "file1.txt", time.Unix(10, 0), "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	diffs := collectDiff(tree1, tree2)
+	assert.Len(t, diffs, 1)
+	d, ok := diffs[0].(filedb.DiffContentChanged)
+	assert.True(t, ok)
+	assert.Equal(t, "dir1/dir2/file1.txt", d.NewPath)
+	assert.NotEqual(t, d.Old.Hash, d.New.Hash)
+}
+
+func TestDiffMetadataChanged(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.SetModtime(filedb.UuidFromString("file1"), time.Unix(10, 0))
+	diffs := collectDiff(tree1, tree2)
+	assert.Len(t, diffs, 1)
+	d, ok := diffs[0].(filedb.DiffMetadataChanged)
+	assert.True(t, ok)
+	assert.True(t, d.New.Modtime.Equal(time.Unix(10, 0)))
+}
+
+func TestDiffMovedAndContentChanged(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.CreateFile(filedb.UuidFromString("file1"), filedb.UuidFromString("dir1"), "file1.txt", time.Unix(0, 0), "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	diffs := collectDiff(tree1, tree2)
+	assert.Len(t, diffs, 2)
+	assert.Equal(t, filedb.DiffItemTypeMoved, diffs[0].Type())
+	assert.Equal(t, filedb.DiffItemTypeContentChanged, diffs[1].Type())
+}
+
+func TestDiffTypeChanged(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.CreateDir(filedb.UuidFromString("file1"), filedb.UuidFromString("dir2"), "file1.txt")
+	diffs := collectDiff(tree1, tree2)
+	assert.Len(t, diffs, 1)
+	d, ok := diffs[0].(filedb.DiffTypeChanged)
+	assert.True(t, ok)
+	assert.False(t, d.Old.IsDir)
+	assert.True(t, d.New.IsDir)
+}
 
+func TestDiffCreatesParentsFirst(t *testing.T) {
+	tree1 := filedb.NewFileTree()
+	tree2 := generateTestTree()
+
+	diffs := collectDiff(tree1, tree2)
+	var paths []string
+	for _, d := range diffs {
+		paths = append(paths, d.(filedb.DiffAdded).Path)
+	}
+	assert.Equal(t, []string{"dir1", "dir1/dir2", "dir1/dir2/file1.txt"}, paths)
+}
+
+func TestDiffRemovesChildrenFirst(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := filedb.NewFileTree()
+
+	diffs := collectDiff(tree1, tree2)
+	var paths []string
+	for _, d := range diffs {
+		paths = append(paths, d.(filedb.DiffRemoved).Path)
+	}
+	assert.Equal(t, []string{"dir1/dir2/file1.txt", "dir1/dir2", "dir1"}, paths)
+}
+
+func TestDiffMovesOutBeforeRemove(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.Move(filedb.UuidFromString("file1"), filedb.UuidFromString("dir1"), "file1.txt")
+	tree2.Remove(filedb.UuidFromString("dir2"))
+
+	diffs := collectDiff(tree1, tree2)
+	assert.Len(t, diffs, 2)
+	moved, ok := diffs[0].(filedb.DiffMoved)
+	assert.True(t, ok)
+	assert.Equal(t, "dir1/dir2/file1.txt", moved.OldPath)
+	assert.Equal(t, "dir1/file1.txt", moved.NewPath)
+	removed, ok := diffs[1].(filedb.DiffRemoved)
+	assert.True(t, ok)
+	assert.Equal(t, "dir1/dir2", removed.Path)
+}
+
+func TestDiffMoveIntoNewDir(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.CreateDir(filedb.UuidFromString("dir3"), filedb.NilUuid, "dir3")
+	tree2.Move(filedb.UuidFromString("dir1"), filedb.UuidFromString("dir3"), "dir1")
+
+	diffs := collectDiff(tree1, tree2)
+	assert.Len(t, diffs, 2)
+	assert.Equal(t, filedb.DiffAdded{Uuid: filedb.UuidFromString("dir3"), Path: "dir3"}, diffs[0])
+	moved, ok := diffs[1].(filedb.DiffMoved)
+	assert.True(t, ok)
+	assert.Equal(t, "dir1", moved.OldPath)
+	assert.Equal(t, "dir3/dir1", moved.NewPath)
+}
+
+func TestDiffSwapUsesTemporaryName(t *testing.T) {
+	tree1 := generateTestTree()
+	tree1.CreateFile(filedb.UuidFromString("file2"), filedb.UuidFromString("dir2"), "file2.txt", time.Unix(0, 0), "")
+	tree2 := filedb.NewFileTree()
+	tree2.CopyFrom(tree1)
+
+	tree2.Move(filedb.UuidFromString("file1"), filedb.UuidFromString("dir2"), "file2.txt")
+	tree2.Move(filedb.UuidFromString("file2"), filedb.UuidFromString("dir2"), "file1.txt")
+
+	// replay the plan on a path map to check nothing gets clobbered
+	local := map[string]string{
+		"dir1/dir2/file1.txt": "file1",
+		"dir1/dir2/file2.txt": "file2",
+	}
+	for _, d := range collectDiff(tree1, tree2) {
+		moved, ok := d.(filedb.DiffMoved)
+		assert.True(t, ok)
+		_, clobbers := local[moved.NewPath]
+		assert.False(t, clobbers, "move to %s clobbers", moved.NewPath)
+		local[moved.NewPath] = local[moved.OldPath]
+		delete(local, moved.OldPath)
+	}
+	assert.Equal(t, map[string]string{
+		"dir1/dir2/file1.txt": "file2",
+		"dir1/dir2/file2.txt": "file1",
+	}, local)
+}
+
+func TestDiffStopsEarly(t *testing.T) {
+	tree1 := filedb.NewFileTree()
+	tree2 := generateTestTree()
+
+	var numDiffs int
+	for range filedb.Diff(tree1, tree2) {
+		numDiffs++
+		break
+	}
+	assert.Equal(t, 1, numDiffs)
+}
+
+func TestStartDiffCancel(t *testing.T) {
+	tree1 := filedb.NewFileTree()
+	tree2 := filedb.NewFileTree()
+	tree2.EnsureItems(generateTestNodes(1000))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	diffChannel := filedb.StartDiff(ctx, tree1, tree2)
+	<-diffChannel
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range diffChannel {
 		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("diff channel not closed after cancel")
+	}
+}
+
+func TestDiffSubtree(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.CreateFile(filedb.UuidFromString("file2"), filedb.UuidFromString("dir1"), "file2.txt", time.Unix(0, 0), "")
+	tree2.CreateFile(filedb.UuidFromString("file3"), filedb.UuidFromString("dir2"), "file3.txt", time.Unix(0, 0), "")
+
+	var paths []string
+	for d := range filedb.Diff(tree1, tree2, filedb.WithSubtree(filedb.UuidFromString("dir2"))) {
+		paths = append(paths, d.(filedb.DiffAdded).Path)
+	}
+	assert.Equal(t, []string{"dir1/dir2/file3.txt"}, paths)
+}
+
+func TestDiffTypes(t *testing.T) {
+	tree1 := generateTestTree()
+	tree2 := generateTestTree()
+
+	tree2.CreateFile(filedb.UuidFromString("file2"), filedb.UuidFromString("dir1"), "file2.txt", time.Unix(0, 0), "")
+	tree2.Remove(filedb.UuidFromString("file1"))
+
+	var numDiffs int
+	for d := range filedb.Diff(tree1, tree2, filedb.WithTypes(filedb.DiffItemTypeRemoved)) {
+		numDiffs++
+		assert.Equal(t, filedb.DiffItemTypeRemoved, d.Type())
+	}
+	assert.Equal(t, 1, numDiffs)
+}
+
+func TestMoveUpdatesChildPaths(t *testing.T) {
+	tree := generateTestTree()
+
+	path, _ := tree.GetPath(filedb.UuidFromString("file1"))
+	assert.Equal(t, "dir1/dir2/file1.txt", path)
+
+	tree.Move(filedb.UuidFromString("dir2"), filedb.NilUuid, "moved-dir")
+	path, _ = tree.GetPath(filedb.UuidFromString("file1"))
+	assert.Equal(t, "moved-dir/file1.txt", path)
+
+	parents, ok := tree.GetParents(filedb.UuidFromString("file1"))
+	assert.True(t, ok)
+	assert.Equal(t, []filedb.Uuid{filedb.UuidFromString("dir2")}, parents)
+}
+
+func TestMoveIntoOwnSubtreeIsIgnored(t *testing.T) {
+	tree := generateTestTree()
+
+	tree.Move(filedb.UuidFromString("dir1"), filedb.UuidFromString("dir1"), "dir1")
+	tree.Move(filedb.UuidFromString("dir1"), filedb.UuidFromString("dir2"), "dir1")
+
+	path, ok := tree.GetPath(filedb.UuidFromString("file1"))
+	assert.True(t, ok)
+	assert.Equal(t, "dir1/dir2/file1.txt", path)
+}
+
+func TestEnsureItemsIntoOwnSubtreeIsIgnored(t *testing.T) {
+	tree := generateTestTree()
+
+	tree.CreateDir(filedb.UuidFromString("dir1"), filedb.UuidFromString("dir2"), "dir1")
+	tree.CreateDir(filedb.UuidFromString("dir3"), filedb.UuidFromString("dir3"), "dir3")
+
+	path, ok := tree.GetPath(filedb.UuidFromString("file1"))
+	assert.True(t, ok)
+	assert.Equal(t, "dir1/dir2/file1.txt", path)
+	_, ok = tree.GetNode(filedb.UuidFromString("dir3"))
+	assert.False(t, ok)
+}
+
+func TestConcurrentLookups(t *testing.T) {
+	tree := generateTestTree()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 100 {
+				path, _ := tree.GetPath(filedb.UuidFromString("file1"))
+				assert.Equal(t, "dir1/dir2/file1.txt", path)
+				assert.Len(t, tree.GetPathToUuidMap(), 3)
+			}
+		})
 	}
+	wg.Wait()
+}
+
+func TestRemoveReusesSlots(t *testing.T) {
+	tree := generateTestTree()
+
+	tree.Remove(filedb.UuidFromString("dir2"))
+	assert.Equal(t, 1, tree.Len())
+	_, ok := tree.GetNode(filedb.UuidFromString("file1"))
+	assert.False(t, ok)
+
+	tree.CreateFile(filedb.UuidFromString("file2"), filedb.UuidFromString("dir1"), "file2.txt", time.Unix(5, 0), "")
+	tree.CreateFile(filedb.UuidFromString("file3"), filedb.NilUuid, "file3.txt", time.Unix(6, 0), "")
+	assert.Equal(t, map[string]filedb.Uuid{
+		"dir1":           filedb.UuidFromString("dir1"),
+		"dir1/file2.txt": filedb.UuidFromString("file2"),
+		"file3.txt":      filedb.UuidFromString("file3"),
+	}, tree.GetPathToUuidMap())
+
+	node, ok := tree.GetNode(filedb.UuidFromString("file2"))
+	assert.True(t, ok)
+	assert.Equal(t, filedb.UuidFromString("dir1"), node.Parent)
+	assert.True(t, node.Modtime.Equal(time.Unix(5, 0)))
+}
+
+func TestCopyFromIsIndependent(t *testing.T) {
+	tree := generateTestTree()
+	copied := filedb.NewFileTree()
+	copied.CopyFrom(tree)
+
+	tree.Move(filedb.UuidFromString("file1"), filedb.UuidFromString("dir1"), "renamed.txt")
+	path, _ := copied.GetPath(filedb.UuidFromString("file1"))
+	assert.Equal(t, "dir1/dir2/file1.txt", path)
+	path, _ = tree.GetPath(filedb.UuidFromString("file1"))
+	assert.Equal(t, "dir1/renamed.txt", path)
+}
+
+func TestRenameReusesNames(t *testing.T) {
+	tree := generateTestTree()
+
+	// the name of file1 is dropped and its slot taken by a new name
+	tree.Move(filedb.UuidFromString("file1"), filedb.UuidFromString("dir1"), "renamed.txt")
+	tree.CreateFile(filedb.UuidFromString("file2"), filedb.NilUuid, "file2.txt", time.Unix(5, 0), "")
+	tree.Remove(filedb.UuidFromString("file2"))
+	tree.CreateFile(filedb.UuidFromString("file3"), filedb.UuidFromString("dir2"), "file1.txt", time.Unix(6, 0), "")
+	tree.EnsureItems([]filedb.FileTreeNode{{Uuid: filedb.UuidFromString("dir2"), Name: "dir3", IsDir: true, Parent: filedb.UuidFromString("dir1")}})
+
+	assert.Equal(t, map[string]filedb.Uuid{
+		"dir1":                filedb.UuidFromString("dir1"),
+		"dir1/dir3":           filedb.UuidFromString("dir2"),
+		"dir1/dir3/file1.txt": filedb.UuidFromString("file3"),
+		"dir1/renamed.txt":    filedb.UuidFromString("file1"),
+	}, tree.GetPathToUuidMap())
+}
+
+func BenchmarkDiff(b *testing.B) {
+	loadBenchmarkTrees(b)
+	measureBytesPerNode(b, benchmarkTree1.Len(), func() {
+		for range filedb.Diff(benchmarkTree1, benchmarkTree2) {
+
+		}
+	})
 }
 
 func BenchmarkDiffWithNil(b *testing.B) {
+	loadBenchmarkTrees(b)
 	nilTree := filedb.NewFileTree()
-	for i := 0; i < b.N; i++ {
-		for range filedb.StartDiff(benchmarkTree1, nilTree) {
+	measureBytesPerNode(b, benchmarkTree1.Len(), func() {
+		for range filedb.Diff(benchmarkTree1, nilTree) {
 		}
-	}
+	})
 }
 
 func BenchmarkCopyFrom(b *testing.B) {
+	loadBenchmarkTrees(b)
 	nilTree := filedb.NewFileTree()
-	for i := 0; i < b.N; i++ {
+	measureBytesPerNode(b, benchmarkTree1.Len(), func() {
 		nilTree.CopyFrom(benchmarkTree1)
+	})
+}
+
+func BenchmarkEnsureItems(b *testing.B) {
+	nodes := generateTestNodes(benchmarkTreeSize)
+	measureBytesPerNode(b, len(nodes), func() {
+		tree := filedb.NewFileTree()
+		tree.EnsureItems(nodes)
+	})
+}
+
+// BenchmarkRetainedTree reports the live heap held by a built tree.
+func BenchmarkRetainedTree(b *testing.B) {
+	nodes := generateTestNodes(benchmarkTreeSize)
+	var retained int64
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		tree := filedb.NewFileTree()
+		tree.EnsureItems(nodes)
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		// the heap may shrink in between, which uint64 would wrap around
+		retained += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		runtime.KeepAlive(tree)
+	}
+	b.ReportMetric(float64(retained)/float64(b.N)/float64(len(nodes)), "heap-B/node")
+}
+
+// measureBytesPerNode runs fn b.N times and reports the allocated bytes per
+// tree node next to the usual per-op numbers.
+func measureBytesPerNode(b *testing.B, nodes int, fn func()) {
+	var before, after runtime.MemStats
+	b.ReportAllocs()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fn()
 	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N)/float64(nodes), "B/node")
 }
 
 type FileTreeNode struct {
@@ -100,8 +477,14 @@ type FileTreeNode struct {
 	Parent  filedb.Uuid
 }
 
-func loadTestTree(t *testing.T, tree *filedb.FileTree) {
+// loadTestTree loads the exported node fixture if present and falls back to
+// a generated tree of benchmarkTreeSize nodes otherwise.
+func loadTestTree(t testing.TB, tree *filedb.FileTree) {
 	b, err := os.ReadFile("test-nodes.json")
+	if os.IsNotExist(err) {
+		tree.EnsureItems(generateTestNodes(benchmarkTreeSize))
+		return
+	}
 	assert.NoError(t, err)
 	var nodes []filedb.FileTreeNode
 	err = json.Unmarshal(b, &nodes)
@@ -110,6 +493,42 @@ func loadTestTree(t *testing.T, tree *filedb.FileTree) {
 	tree.EnsureItems(nodes)
 }
 
+// generateTestNodes builds a deterministic tree of n nodes with Filen-style
+// uuids: every tenth node is a directory holding the following files.
+func generateTestNodes(n int) []filedb.FileTreeNode {
+	nodes := make([]filedb.FileTreeNode, 0, n)
+	parent := filedb.NilUuid
+	for i := range n {
+		uuid := filedb.UuidFromString(fmt.Sprintf("%08x-0000-4000-8000-%012x", i, i))
+		if i%10 == 0 {
+			nodes = append(nodes, filedb.FileTreeNode{
+				Uuid:   uuid,
+				Name:   filedb.FileNameFromString(fmt.Sprintf("dir%d", i)),
+				IsDir:  true,
+				Parent: filedb.NilUuid,
+			})
+			parent = uuid
+			continue
+		}
+		nodes = append(nodes, filedb.FileTreeNode{
+			Uuid:    uuid,
+			Name:    filedb.FileNameFromString(fmt.Sprintf("file%d.txt", i)),
+			Hash:    filedb.HashFromString(fmt.Sprintf("%064x", i)),
+			Modtime: time.Unix(int64(i), 0),
+			Parent:  parent,
+		})
+	}
+	return nodes
+}
+
+func collectDiff(is, should *filedb.FileTree) []filedb.DiffItem {
+	var diffs []filedb.DiffItem
+	for diffItem := range filedb.Diff(is, should) {
+		diffs = append(diffs, diffItem)
+	}
+	return diffs
+}
+
 func generateTestTree() *filedb.FileTree {
 	tree := filedb.NewFileTree()
 
@@ -0,0 +1,78 @@
+package filedb
+
+import (
+	"container/list"
+	"sync"
+)
+
+const defaultPathCacheSize = 4096
+
+// pathCache is a bounded LRU of computed node paths keyed by arena slot. It is
+// locked, because lookups on a tree fill it and may run concurrently.
+type pathCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[nodeIndex]*list.Element
+}
+
+type pathCacheEntry struct {
+	idx  nodeIndex
+	path string
+}
+
+func newPathCache(size int) *pathCache {
+	return &pathCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[nodeIndex]*list.Element, size),
+	}
+}
+
+func (c *pathCache) get(idx nodeIndex) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[idx]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*pathCacheEntry).path, true
+}
+
+func (c *pathCache) put(idx nodeIndex, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[idx]; ok {
+		elem.Value.(*pathCacheEntry).path = path
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[idx] = c.order.PushFront(&pathCacheEntry{idx: idx, path: path})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*pathCacheEntry).idx)
+	}
+}
+
+func (c *pathCache) remove(idx nodeIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[idx]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, idx)
+	}
+}
+
+func (c *pathCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
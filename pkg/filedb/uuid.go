@@ -0,0 +1,172 @@
+package filedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+)
+
+// Uuid is the compact form of a Filen item id. Standard lowercase
+// "8-4-4-4-12" UUIDs are stored as their 16 raw bytes. Other ids of up to 15
+// bytes are stored inline, longer ones in a process-wide table, so the
+// conversion is lossless for every input.
+type Uuid [16]byte
+
+var NilUuid Uuid
+
+// Other ids are marked with the variant bits 111 in byte 8, which RFC 4122
+// reserves for future definition. The low bits of byte 8 hold the length of
+// an inline id, whose bytes fill the other 15 bytes, or 0 for a table entry,
+// whose index is held in bytes 0-7.
+const (
+	uuidVariantByte    = 8
+	uuidVariantMask    = 0xe0
+	uuidInternedMarker = 0xe0
+	uuidMaxInline      = 15
+)
+
+// internedUuids holds the ids too long to be stored inline. It only grows,
+// so that every Uuid handed out keeps reading as its id.
+var internedUuids = struct {
+	sync.RWMutex
+	byString map[string]uint64
+	strings  []string
+}{
+	byString: make(map[string]uint64),
+}
+
+func UuidFromString(s string) Uuid {
+	if s == "" {
+		return NilUuid
+	}
+
+	u, ok := parseStandardUuid(s)
+	if ok && u != NilUuid && u[uuidVariantByte]&uuidVariantMask != uuidInternedMarker {
+		return u
+	}
+
+	if len(s) <= uuidMaxInline {
+		return inlineUuid(s)
+	}
+	return internUuid(s)
+}
+
+func (u Uuid) String() string {
+	if u == NilUuid {
+		return ""
+	}
+
+	if u.isInterned() {
+		if n := int(u[uuidVariantByte] &^ uuidVariantMask); n > 0 {
+			b := append(u[:uuidVariantByte:uuidVariantByte], u[uuidVariantByte+1:]...)
+			return string(b[:n])
+		}
+		internedUuids.RLock()
+		defer internedUuids.RUnlock()
+		return internedUuids.strings[u.tableIndex()]
+	}
+
+	var buf [36]byte
+	const hexDigits = "0123456789abcdef"
+	j := 0
+	for i, b := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			buf[j] = '-'
+			j++
+		}
+		buf[j] = hexDigits[b>>4]
+		buf[j+1] = hexDigits[b&0x0f]
+		j += 2
+	}
+	return string(buf[:])
+}
+
+func (u Uuid) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+func (u *Uuid) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*u = UuidFromString(s)
+	return nil
+}
+
+func CompareUuids(a, b *Uuid) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+func (u Uuid) isInterned() bool {
+	return u[uuidVariantByte]&uuidVariantMask == uuidInternedMarker
+}
+
+func (u Uuid) tableIndex() uint64 {
+	return binary.BigEndian.Uint64(u[:8])
+}
+
+func inlineUuid(s string) Uuid {
+	var u Uuid
+	n := copy(u[:uuidVariantByte], s)
+	copy(u[uuidVariantByte+1:], s[n:])
+	u[uuidVariantByte] = uuidInternedMarker | byte(len(s))
+	return u
+}
+
+func internUuid(s string) Uuid {
+	internedUuids.RLock()
+	idx, ok := internedUuids.byString[s]
+	internedUuids.RUnlock()
+
+	if !ok {
+		internedUuids.Lock()
+		idx, ok = internedUuids.byString[s]
+		if !ok {
+			idx = uint64(len(internedUuids.strings))
+			internedUuids.strings = append(internedUuids.strings, s)
+			internedUuids.byString[s] = idx
+		}
+		internedUuids.Unlock()
+	}
+
+	var u Uuid
+	binary.BigEndian.PutUint64(u[:8], idx)
+	u[uuidVariantByte] = uuidInternedMarker
+	return u
+}
+
+// parseStandardUuid only accepts the canonical lowercase form, so that
+// String() reproduces the input exactly.
+func parseStandardUuid(s string) (Uuid, bool) {
+	var u Uuid
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, false
+	}
+
+	j := 0
+	for i := 0; i < len(s); i += 2 {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			i++
+		}
+		hi, ok1 := fromHexChar(s[i])
+		lo, ok2 := fromHexChar(s[i+1])
+		if !ok1 || !ok2 {
+			return u, false
+		}
+		u[j] = hi<<4 | lo
+		j++
+	}
+	return u, true
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	}
+	return 0, false
+}
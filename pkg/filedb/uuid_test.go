@@ -0,0 +1,101 @@
+package filedb_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filedb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUuidSize(t *testing.T) {
+	assert.Equal(t, uintptr(16), unsafe.Sizeof(filedb.Uuid{}))
+}
+
+func TestUuidRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9",
+		"0F1E2D3C-4B5A-4978-8695-A4B3C2D1E0F9",
+		"00000000-0000-0000-0000-000000000000",
+		"0f1e2d3c-4b5a-4978-e695-a4b3c2d1e0f9",
+		"0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0fx",
+		"0f1e2d3c04b5a-4978-8695-a4b3c2d1e0f9",
+		"file1",
+	} {
+		u := filedb.UuidFromString(s)
+		assert.Equal(t, s, u.String())
+		assert.Equal(t, u, filedb.UuidFromString(s))
+	}
+}
+
+func TestUuidInline(t *testing.T) {
+	for _, s := range []string{"a", "\x00", "dir1/\xff", "123456789012345", "1234567890123456", strings.Repeat("x", 64)} {
+		u := filedb.UuidFromString(s)
+		assert.Equal(t, s, u.String())
+		assert.Equal(t, u, filedb.UuidFromString(s))
+	}
+	assert.NotEqual(t, filedb.UuidFromString("a"), filedb.UuidFromString("a\x00"))
+}
+
+// Long ids stay readable however many others are created.
+func TestLongUuidsAreKept(t *testing.T) {
+	long := func(s string) string { return strings.Repeat("x", 32) + s }
+	first := filedb.UuidFromString(long("first"))
+
+	for i := range 10000 {
+		filedb.UuidFromString(long(strconv.Itoa(i)))
+	}
+
+	assert.Equal(t, long("first"), first.String())
+	assert.Equal(t, first, filedb.UuidFromString(long("first")))
+}
+
+func TestUuidNil(t *testing.T) {
+	assert.Equal(t, filedb.NilUuid, filedb.UuidFromString(""))
+	assert.NotEqual(t, filedb.NilUuid, filedb.UuidFromString("00000000-0000-0000-0000-000000000000"))
+}
+
+func TestUuidDistinct(t *testing.T) {
+	a := filedb.UuidFromString("0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9")
+	b := filedb.UuidFromString("0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0fa")
+	c := filedb.UuidFromString("dir1")
+	d := filedb.UuidFromString("dir2")
+
+	assert.NotEqual(t, a, b)
+	assert.NotEqual(t, c, d)
+	assert.Equal(t, -1, filedb.CompareUuids(&a, &b))
+	assert.Equal(t, 0, filedb.CompareUuids(&c, &c))
+}
+
+func TestUuidJSON(t *testing.T) {
+	var nodes []filedb.FileTreeNode
+	err := json.Unmarshal([]byte(`[{"Uuid":"0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9","Parent":"","Name":"a","IsDir":true},{"Uuid":"file1","Parent":"0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9","Name":"b"}]`), &nodes)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, filedb.NilUuid, nodes[0].Parent)
+	assert.Equal(t, nodes[0].Uuid, nodes[1].Parent)
+	assert.Equal(t, "file1", nodes[1].Uuid.String())
+
+	out, err := json.Marshal(nodes[1].Parent)
+	assert.NoError(t, err)
+	assert.Equal(t, `"0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9"`, string(out))
+}
+
+func BenchmarkUuidFromString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		filedb.UuidFromString("0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9")
+	}
+}
+
+func BenchmarkUuidString(b *testing.B) {
+	u := filedb.UuidFromString("0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = u.String()
+	}
+}
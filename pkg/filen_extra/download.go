@@ -3,8 +3,11 @@ package filenextra
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/FilenCloudDienste/filen-sdk-go/filen"
 	"github.com/FilenCloudDienste/filen-sdk-go/filen/client"
@@ -12,7 +15,21 @@ import (
 	"github.com/FilenCloudDienste/filen-sdk-go/filen/types"
 )
 
+// ErrFileNotFound is returned for files the API does not know, e.g. after
+// they were deleted.
+var ErrFileNotFound = errors.New("file not found")
+
 func CreateDownloadReader(ctx context.Context, c *filen.Filen, uuid string) (io.ReadCloser, error) {
+	filenFile, err := GetFile(ctx, c, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetDownloadReader(ctx, filenFile), nil
+}
+
+// GetFile fetches and decrypts the metadata of a single file.
+func GetFile(ctx context.Context, c *filen.Filen, uuid string) (*types.File, error) {
 	finfo, err := getV3FileInfo(ctx, c, uuid)
 	if err != nil {
 		return nil, err
@@ -38,21 +55,22 @@ func CreateDownloadReader(ctx context.Context, c *filen.Filen, uuid string) (io.
 		return nil, fmt.Errorf("ReadDirectory creating encryption key: %v", err)
 	}
 
-	filenFile := &types.File{
+	return &types.File{
 		IncompleteFile: types.IncompleteFile{
 			Name:          metadata.Name,
 			UUID:          finfo.Uuid,
 			MimeType:      metadata.MimeType,
 			EncryptionKey: *encryptionKey,
+			LastModified:  time.UnixMilli(int64(metadata.LastModified)),
+			ParentUUID:    finfo.Parent,
 		},
 		Region:  finfo.Region,
 		Bucket:  finfo.Bucket,
 		Size:    int(finfo.Size),
 		Chunks:  chunks,
+		Hash:    metadata.Hash,
 		Version: crypto.FileEncryptionVersion(finfo.Version),
-	}
-
-	return c.GetDownloadReader(ctx, filenFile), nil
+	}, nil
 }
 
 type V3FileInfoResponse struct {
@@ -76,5 +94,9 @@ func getV3FileInfo(ctx context.Context, c *filen.Filen, uuid string) (*V3FileInf
 	_, err := c.Client.RequestData(ctx, "POST", client.GatewayURL("/v3/file"), struct {
 		UUID string `json:"uuid"`
 	}{UUID: uuid}, &res)
+	// the SDK only reports the API code as part of the error message
+	if err != nil && strings.Contains(err.Error(), "file_not_found") {
+		return nil, fmt.Errorf("%w: %w", ErrFileNotFound, err)
+	}
 	return &res, err
 }
@@ -0,0 +1,128 @@
+package filenextra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/client"
+)
+
+var ErrInvalidEndpoint = errors.New("endpoint must be an http or https base URL")
+
+// Endpoints holds the base URLs requests are sent to. A base URL may carry a
+// path prefix; request paths are appended to it.
+type Endpoints struct {
+	Gateway []string
+	Egest   []string
+	Ingest  []string
+}
+
+// DefaultEndpoints returns the endpoints built into the SDK.
+func DefaultEndpoints() Endpoints {
+	return Endpoints{
+		Gateway: client.URLs(client.URLTypeGateway),
+		Egest:   client.URLs(client.URLTypeEgest),
+		Ingest:  client.URLs(client.URLTypeIngest),
+	}
+}
+
+// WithEndpoints returns a copy of ctx that makes the clients created with it,
+// e.g. by filen.New, send their requests to e instead of the endpoints built
+// into the SDK. Empty lists keep the SDK's endpoints.
+func WithEndpoints(ctx context.Context, e Endpoints) (context.Context, error) {
+	routes := make(map[string][]*url.URL)
+	for urlType, endpoints := range map[int][]string{
+		client.URLTypeGateway: e.Gateway,
+		client.URLTypeEgest:   e.Egest,
+		client.URLTypeIngest:  e.Ingest,
+	} {
+		if len(endpoints) == 0 {
+			continue
+		}
+
+		targets := make([]*url.URL, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+			}
+			if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("invalid endpoint %q: %w", endpoint, ErrInvalidEndpoint)
+			}
+			targets = append(targets, u)
+		}
+		for _, base := range client.URLs(urlType) {
+			routes[base] = targets
+		}
+	}
+
+	return client.WithTransport(ctx, func(next http.RoundTripper) http.RoundTripper {
+		return &endpointTransport{next: next, routes: routes}
+	}), nil
+}
+
+// endpointTransport sends the requests for the SDK's endpoints to the
+// configured ones. The SDK picks a random endpoint of a pool for every
+// request, so the configured endpoint is picked the same way.
+type endpointTransport struct {
+	next   http.RoundTripper
+	routes map[string][]*url.URL
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	targets, ok := t.routes[req.URL.Scheme+"://"+req.URL.Host]
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	target := targets[rand.IntN(len(targets))]
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	if req.URL.RawPath != "" {
+		req.URL.RawPath = target.EscapedPath() + req.URL.RawPath
+	}
+	req.URL.Path = target.Path + req.URL.Path
+	req.Host = ""
+	return t.next.RoundTrip(req)
+}
+
+// ParseEndpointList parses a comma separated list of http or https base URLs.
+// An empty string yields an empty list.
+func ParseEndpointList(s string) ([]string, error) {
+	var endpoints []string
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		u, err := url.Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", part, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", part, ErrInvalidEndpoint)
+		}
+		endpoints = append(endpoints, strings.TrimSuffix(part, "/"))
+	}
+	return endpoints, nil
+}
+
+// ValidateSocketURL checks that s can serve as the base URL of the event
+// socket.
+func ValidateSocketURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid socket URL %q: %w", s, err)
+	}
+	if (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid socket URL %q: %w", s, ErrInvalidWebSocketURL)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package filenextra_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/client"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEndpointList(t *testing.T) {
+	endpoints, err := filenextra.ParseEndpointList("https://gw1.example.com, http://proxy.local/filen/gateway/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://gw1.example.com", "http://proxy.local/filen/gateway"}, endpoints)
+
+	endpoints, err = filenextra.ParseEndpointList("")
+	assert.NoError(t, err)
+	assert.Empty(t, endpoints)
+
+	for _, invalid := range []string{"gateway.example.com", "ftp://example.com", "https://", "https://example.com/?a=b"} {
+		_, err = filenextra.ParseEndpointList(invalid)
+		assert.True(t, errors.Is(err, filenextra.ErrInvalidEndpoint), invalid)
+	}
+}
+
+func TestValidateSocketURL(t *testing.T) {
+	assert.NoError(t, filenextra.ValidateSocketURL("wss://socket.filen.io:443"))
+	assert.NoError(t, filenextra.ValidateSocketURL("ws://proxy.local/filen/socket"))
+	assert.Error(t, filenextra.ValidateSocketURL("https://socket.filen.io"))
+	assert.Error(t, filenextra.ValidateSocketURL("socket.filen.io"))
+}
+
+func TestSDKEndpointPools(t *testing.T) {
+	defaults := filenextra.DefaultEndpoints()
+	assert.Contains(t, defaults.Gateway, "https://gateway.filen.io")
+	assert.Contains(t, defaults.Egest, "https://egest.filen.io")
+	assert.Contains(t, defaults.Ingest, "https://ingest.filen.io")
+}
+
+func TestWithEndpoints(t *testing.T) {
+	paths := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		w.Write([]byte(`{"status":true,"message":"","code":"","data":{"email":"user@example.com","authVersion":2,"salt":"salt","id":1}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, err := filenextra.WithEndpoints(context.Background(), filenextra.Endpoints{Gateway: []string{srv.URL + "/filen/gateway/"}})
+	assert.NoError(t, err)
+	_, err = client.New(ctx).PostV3AuthInfo(ctx, "user@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "/filen/gateway/v3/auth/info", <-paths)
+
+	_, err = filenextra.WithEndpoints(context.Background(), filenextra.Endpoints{Egest: []string{"egest.example.com"}})
+	assert.ErrorIs(t, err, filenextra.ErrInvalidEndpoint)
+}
@@ -0,0 +1,233 @@
+package filenextra
+
+import (
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+)
+
+var ErrUnsupportedEncryption = errors.New("unsupported metadata encryption version")
+
+// MetaDecrypter decrypts a single encrypted metadata string.
+type MetaDecrypter interface {
+	DecryptMeta(encrypted crypto.EncryptedString) (string, error)
+}
+
+// encryptedField is a field of an event that holds encrypted metadata. The
+// decrypted value is a JSON object.
+type encryptedField string
+
+// eventSchemas lists the encrypted fields of each event. Fields of events
+// that are not listed are passed on as they are.
+var eventSchemas = map[string][]encryptedField{
+	"file-new":              {"metadata"},
+	"file-rename":           {"metadata"},
+	"file-archive-restored": {"metadata"},
+	"file-restore":          {"metadata"},
+	"file-move":             {"metadata"},
+	"folder-rename":         {"name"},
+	"folder-move":           {"name"},
+	"folder-sub-created":    {"name"},
+	"folder-restore":        {"name"},
+}
+
+// FieldError reports a field of an event that could not be decrypted.
+type FieldError struct {
+	Event string
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("decrypt field %q of %s event: %v", e.Field, e.Event, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// EventDecrypter decrypts the fields of socket events according to their
+// schema. Items in shared folders are decrypted with the key of the share,
+// everything else with the master keys.
+type EventDecrypter struct {
+	mu      sync.RWMutex
+	master  MetaDecrypter
+	shares  map[string]MetaDecrypter
+	parents *ParentIndex
+}
+
+func NewEventDecrypter(master MetaDecrypter) *EventDecrypter {
+	return &EventDecrypter{
+		master: master,
+		shares: make(map[string]MetaDecrypter),
+	}
+}
+
+// SetMasterKey replaces the master keys, e.g. after logging in again.
+func (d *EventDecrypter) SetMasterKey(master MetaDecrypter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.master = master
+}
+
+// SetParentIndex makes share keys apply to everything below the shared
+// folder that parents knows about, not only to its direct children.
+func (d *EventDecrypter) SetParentIndex(parents *ParentIndex) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.parents = parents
+}
+
+// SetShareKey makes events about folderUUID or items below it use key.
+func (d *EventDecrypter) SetShareKey(folderUUID string, key MetaDecrypter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shares[folderUUID] = key
+}
+
+func (d *EventDecrypter) RemoveShareKey(folderUUID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.shares, folderUUID)
+}
+
+// Decrypt returns a copy of data with the encrypted fields of the event
+// replaced by their decrypted content. Fields that fail to decrypt are left
+// out and reported as *FieldError, joined into the returned error.
+func (d *EventDecrypter) Decrypt(eventName string, data map[string]any) (map[string]any, error) {
+	schema := eventSchemas[eventName]
+	decrypted := maps.Clone(data)
+	if decrypted == nil {
+		decrypted = make(map[string]any)
+	}
+	if len(schema) == 0 {
+		return decrypted, nil
+	}
+
+	key := d.keyFor(data)
+	var errs []error
+	for _, field := range schema {
+		value, ok := data[string(field)]
+		if !ok {
+			continue
+		}
+		delete(decrypted, string(field))
+
+		result, err := decryptField(key, value)
+		if err != nil {
+			errs = append(errs, &FieldError{Event: eventName, Field: string(field), Err: err})
+			continue
+		}
+		decrypted[string(field)] = result
+	}
+	return decrypted, errors.Join(errs...)
+}
+
+// keyFor picks the share key registered for the closest shared folder above
+// the parent or the item itself, falling back to the master keys.
+func (d *EventDecrypter) keyFor(data map[string]any) MetaDecrypter {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, field := range []string{"parent", "uuid"} {
+		if uuid, ok := data[field].(string); ok {
+			if key, ok := d.shareKeyAbove(uuid); ok {
+				return key
+			}
+		}
+	}
+	return d.master
+}
+
+func (d *EventDecrypter) shareKeyAbove(uuid string) (MetaDecrypter, bool) {
+	for range maxDepth {
+		if key, ok := d.shares[uuid]; ok {
+			return key, true
+		}
+		if d.parents == nil {
+			return nil, false
+		}
+		parent, ok := d.parents.Parent(uuid)
+		if !ok {
+			return nil, false
+		}
+		uuid = parent
+	}
+	return nil, false
+}
+
+func decryptField(key MetaDecrypter, value any) (result map[string]any, err error) {
+	encrypted, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected an encrypted string, got %T", value)
+	}
+
+	// the SDK slices encrypted strings without checking their length
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("%w: %v", ErrUnsupportedEncryption, r)
+		}
+	}()
+
+	plain, err := key.DecryptMeta(crypto.EncryptedString(encrypted))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(plain), &result)
+	if err != nil {
+		return nil, fmt.Errorf("decrypted value is not a JSON object: %w", err)
+	}
+	return result, nil
+}
+
+// masterKeys decrypts with the keys of the logged in user.
+type masterKeys struct {
+	filen *filen.Filen
+}
+
+// NewMasterKeyDecrypter returns a MetaDecrypter using the master keys or the
+// DEK of client, depending on the version of the encrypted string.
+func NewMasterKeyDecrypter(client *filen.Filen) MetaDecrypter {
+	return masterKeys{filen: client}
+}
+
+func (k masterKeys) DecryptMeta(encrypted crypto.EncryptedString) (string, error) {
+	// the SDK panics on versions it does not know
+	s := string(encrypted)
+	if !strings.HasPrefix(s, "U2FsdGVk") && !strings.HasPrefix(s, "002") && !strings.HasPrefix(s, "003") {
+		return "", ErrUnsupportedEncryption
+	}
+	return k.filen.DecryptMeta(encrypted)
+}
+
+// privateKey decrypts metadata of items shared with the user, which is
+// encrypted with the user's public key.
+type privateKey struct {
+	key *rsa.PrivateKey
+}
+
+func NewPrivateKeyDecrypter(key *rsa.PrivateKey) MetaDecrypter {
+	return privateKey{key: key}
+}
+
+func (k privateKey) DecryptMeta(encrypted crypto.EncryptedString) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(encrypted))
+	if err != nil {
+		return "", err
+	}
+	plain, err := rsa.DecryptOAEP(sha512.New(), nil, k.key, raw, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,130 @@
+package filenextra_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventDecrypterSchema(t *testing.T) {
+	master, err := crypto.NewEncryptionKey()
+	assert.NoError(t, err)
+	d := filenextra.NewEventDecrypter(master)
+
+	encryptedNote := string(master.EncryptMeta("note"))
+	data, err := d.Decrypt("file-new", map[string]any{
+		"uuid":     "f1",
+		"parent":   "p1",
+		"metadata": string(master.EncryptMeta(`{"name":"a.txt"}`)),
+		"rm":       encryptedNote,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "a.txt"}, data["metadata"])
+	// fields outside the schema are not touched
+	assert.Equal(t, encryptedNote, data["rm"])
+
+	data, err = d.Decrypt("folder-color-changed", map[string]any{"uuid": "d1", "color": encryptedNote})
+	assert.NoError(t, err)
+	assert.Equal(t, encryptedNote, data["color"])
+}
+
+func TestEventDecrypterShareKey(t *testing.T) {
+	master, err := crypto.NewEncryptionKey()
+	assert.NoError(t, err)
+	link, err := crypto.NewEncryptionKey()
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	d := filenextra.NewEventDecrypter(master)
+	d.SetShareKey("linked", link)
+	d.SetShareKey("shared", filenextra.NewPrivateKeyDecrypter(rsaKey))
+
+	data, err := d.Decrypt("folder-sub-created", map[string]any{
+		"uuid":   "d1",
+		"parent": "linked",
+		"name":   string(link.EncryptMeta(`{"name":"docs"}`)),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "docs"}, data["name"])
+
+	encrypted, err := crypto.PublicEncrypt(&rsaKey.PublicKey, `{"name":"b.txt"}`)
+	assert.NoError(t, err)
+	data, err = d.Decrypt("file-rename", map[string]any{"uuid": "shared", "metadata": string(encrypted)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "b.txt"}, data["metadata"])
+
+	// the master key can not decrypt share metadata
+	d.RemoveShareKey("linked")
+	_, err = d.Decrypt("folder-sub-created", map[string]any{
+		"uuid":   "d1",
+		"parent": "linked",
+		"name":   string(link.EncryptMeta(`{"name":"docs"}`)),
+	})
+	assert.Error(t, err)
+}
+
+func TestEventDecrypterNestedShare(t *testing.T) {
+	master, err := crypto.NewEncryptionKey()
+	assert.NoError(t, err)
+	share, err := crypto.NewEncryptionKey()
+	assert.NoError(t, err)
+
+	d := filenextra.NewEventDecrypter(master)
+	d.SetShareKey("shared", share)
+	parents := filenextra.NewParentIndex()
+	parents.Set("sub", "shared")
+	parents.Set("file1", "sub")
+	d.SetParentIndex(parents)
+
+	data, err := d.Decrypt("folder-sub-created", map[string]any{
+		"uuid":   "d1",
+		"parent": "sub",
+		"name":   string(share.EncryptMeta(`{"name":"docs"}`)),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "docs"}, data["name"])
+
+	data, err = d.Decrypt("file-rename", map[string]any{
+		"uuid":     "file1",
+		"metadata": string(share.EncryptMeta(`{"name":"b.txt"}`)),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "b.txt"}, data["metadata"])
+}
+
+func TestEventDecrypterFieldErrors(t *testing.T) {
+	master, err := crypto.NewEncryptionKey()
+	assert.NoError(t, err)
+	d := filenextra.NewEventDecrypter(master)
+
+	for name, value := range map[string]any{
+		"wrong key":   string(mustNewKey(t).EncryptMeta(`{"name":"a.txt"}`)),
+		"not json":    string(master.EncryptMeta("a.txt")),
+		"not string":  float64(1),
+		"unencrypted": "a.txt",
+		"truncated":   "003abc",
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := d.Decrypt("file-move", map[string]any{"uuid": "f1", "metadata": value})
+			var fieldErr *filenextra.FieldError
+			if assert.True(t, errors.As(err, &fieldErr), "error: %v", err) {
+				assert.Equal(t, "file-move", fieldErr.Event)
+				assert.Equal(t, "metadata", fieldErr.Field)
+			}
+			assert.NotContains(t, data, "metadata")
+			assert.Equal(t, "f1", data["uuid"])
+		})
+	}
+}
+
+func mustNewKey(t *testing.T) *crypto.EncryptionKey {
+	key, err := crypto.NewEncryptionKey()
+	assert.NoError(t, err)
+	return key
+}
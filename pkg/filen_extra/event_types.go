@@ -3,6 +3,7 @@ package filenextra
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 )
 
 type TypedEvent struct {
@@ -10,6 +11,21 @@ type TypedEvent struct {
 	Data any
 }
 
+// EventInvalid stands in for an event whose data could not be decoded or
+// failed validation. UUID is the item the event referred to, if known.
+type EventInvalid struct {
+	UUID string
+	Err  error
+}
+
+type validator interface {
+	Validate() error
+}
+
+// InterpretEvent decodes the data of a socket event into its typed form.
+// Unknown events yield an error only. Malformed data of a known event yields
+// an error wrapping ErrInvalidEvent together with an EventInvalid, so that
+// the receiver can resync the affected item.
 func InterpretEvent(eventName string, data map[string]any) (TypedEvent, error) {
 	var event any
 
@@ -47,8 +63,17 @@ func InterpretEvent(eventName string, data map[string]any) (TypedEvent, error) {
 	}
 
 	err := eventFromMap(event, data)
+	if err == nil {
+		if v, ok := event.(validator); ok {
+			err = v.Validate()
+		}
+	}
 	if err != nil {
-		return TypedEvent{}, err
+		uuid, _ := data["uuid"].(string)
+		return TypedEvent{
+			Name: eventName,
+			Data: &EventInvalid{UUID: uuid, Err: err},
+		}, fmt.Errorf("%w %s for %q: %w", ErrInvalidEvent, eventName, uuid, err)
 	}
 
 	return TypedEvent{
@@ -79,61 +104,61 @@ type EventSocketNew struct {
 }
 
 type EventSocketFileNew struct {
-	Parent    string         `json:"parent"`
-	UUID      string         `json:"uuid"`
-	Meta      map[string]any `json:"metadata"`
-	RM        string         `json:"rm"`
-	Time      int64          `json:"timestamp"`
-	Chunks    int            `json:"chunks"`
-	Bucket    string         `json:"bucket"`
-	Region    string         `json:"region"`
-	Version   int            `json:"version"`
-	Favorited int            `json:"favorited"`
+	Parent    string       `json:"parent"`
+	UUID      string       `json:"uuid"`
+	Meta      FileMetadata `json:"metadata"`
+	RM        string       `json:"rm"`
+	Time      int64        `json:"timestamp"`
+	Chunks    int          `json:"chunks"`
+	Bucket    string       `json:"bucket"`
+	Region    string       `json:"region"`
+	Version   int          `json:"version"`
+	Favorited int          `json:"favorited"`
 }
 
 type EventSocketFileRename struct {
-	UUID string         `json:"uuid"`
-	Meta map[string]any `json:"metadata"`
+	UUID string       `json:"uuid"`
+	Meta FileMetadata `json:"metadata"`
 }
 
 type EventSocketFileArchiveRestored struct {
-	CurrentUUID string         `json:"currentUUID"`
-	Parent      string         `json:"parent"`
-	UUID        string         `json:"uuid"`
-	Meta        map[string]any `json:"metadata"`
-	RM          string         `json:"rm"`
-	Time        int64          `json:"timestamp"`
-	Chunks      int            `json:"chunks"`
-	Bucket      string         `json:"bucket"`
-	Region      string         `json:"region"`
-	Version     int            `json:"version"`
-	Favorited   int            `json:"favorited"`
+	CurrentUUID string       `json:"currentUUID"`
+	Parent      string       `json:"parent"`
+	UUID        string       `json:"uuid"`
+	Meta        FileMetadata `json:"metadata"`
+	RM          string       `json:"rm"`
+	Time        int64        `json:"timestamp"`
+	Chunks      int          `json:"chunks"`
+	Bucket      string       `json:"bucket"`
+	Region      string       `json:"region"`
+	Version     int          `json:"version"`
+	Favorited   int          `json:"favorited"`
 }
 
 type EventSocketFileRestore struct {
-	Parent    string         `json:"parent"`
-	UUID      string         `json:"uuid"`
-	Meta      map[string]any `json:"metadata"`
-	RM        string         `json:"rm"`
-	Time      int64          `json:"timestamp"`
-	Chunks    int            `json:"chunks"`
-	Bucket    string         `json:"bucket"`
-	Region    string         `json:"region"`
-	Version   int            `json:"version"`
-	Favorited int            `json:"favorited"`
+	Parent    string       `json:"parent"`
+	UUID      string       `json:"uuid"`
+	Meta      FileMetadata `json:"metadata"`
+	RM        string       `json:"rm"`
+	Time      int64        `json:"timestamp"`
+	Chunks    int          `json:"chunks"`
+	Bucket    string       `json:"bucket"`
+	Region    string       `json:"region"`
+	Version   int          `json:"version"`
+	Favorited int          `json:"favorited"`
 }
 
 type EventSocketFileMove struct {
-	Parent    string         `json:"parent"`
-	UUID      string         `json:"uuid"`
-	Meta      map[string]any `json:"metadata"`
-	RM        string         `json:"rm"`
-	Time      int64          `json:"timestamp"`
-	Chunks    int            `json:"chunks"`
-	Bucket    string         `json:"bucket"`
-	Region    string         `json:"region"`
-	Version   int            `json:"version"`
-	Favorited int            `json:"favorited"`
+	Parent    string       `json:"parent"`
+	UUID      string       `json:"uuid"`
+	Meta      FileMetadata `json:"metadata"`
+	RM        string       `json:"rm"`
+	Time      int64        `json:"timestamp"`
+	Chunks    int          `json:"chunks"`
+	Bucket    string       `json:"bucket"`
+	Region    string       `json:"region"`
+	Version   int          `json:"version"`
+	Favorited int          `json:"favorited"`
 }
 
 type EventSocketFileTrash struct {
@@ -187,6 +212,42 @@ type EventSocketFileDeletedPermanent struct {
 	UUID string `json:"uuid"`
 }
 
+func (e *EventSocketFileNew) Validate() error {
+	return e.Meta.Validate()
+}
+
+func (e *EventSocketFileRename) Validate() error {
+	return e.Meta.Validate()
+}
+
+func (e *EventSocketFileArchiveRestored) Validate() error {
+	return e.Meta.Validate()
+}
+
+func (e *EventSocketFileRestore) Validate() error {
+	return e.Meta.Validate()
+}
+
+func (e *EventSocketFileMove) Validate() error {
+	return e.Meta.Validate()
+}
+
+func (e *EventSocketFolderRename) Validate() error {
+	return e.Name.Validate()
+}
+
+func (e *EventSocketFolderMove) Validate() error {
+	return e.Name.Validate()
+}
+
+func (e *EventSocketFolderSubCreated) Validate() error {
+	return e.Name.Validate()
+}
+
+func (e *EventSocketFolderRestore) Validate() error {
+	return e.Name.Validate()
+}
+
 type NameStruct struct {
 	Name string `json:"name"`
 }
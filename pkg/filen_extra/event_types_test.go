@@ -0,0 +1,60 @@
+package filenextra_test
+
+import (
+	"errors"
+	"testing"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpretEventFileMetadata(t *testing.T) {
+	evt, err := filenextra.InterpretEvent("file-new", map[string]any{
+		"uuid":   "f1",
+		"parent": "p1",
+		"metadata": map[string]any{
+			"name":         "a.txt",
+			"size":         float64(5),
+			"mime":         "text/plain",
+			"key":          "k",
+			"lastModified": "1000",
+			"hash":         "abc",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, filenextra.FileMetadata{
+		Name:         "a.txt",
+		Size:         5,
+		Mime:         "text/plain",
+		Key:          "k",
+		LastModified: 1000,
+		Hash:         "abc",
+	}, evt.Data.(*filenextra.EventSocketFileNew).Meta)
+}
+
+func TestInterpretEventInvalid(t *testing.T) {
+	for name, data := range map[string]map[string]any{
+		"missing metadata": {"uuid": "f1"},
+		"encrypted":        {"uuid": "f1", "metadata": "002abc"},
+		"missing name":     {"uuid": "f1", "metadata": map[string]any{"size": 1}},
+		"invalid name":     {"uuid": "f1", "metadata": map[string]any{"name": "a/b"}},
+		"invalid size":     {"uuid": "f1", "metadata": map[string]any{"name": "a", "size": "x"}},
+		"name type":        {"uuid": "f1", "metadata": map[string]any{"name": 1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			evt, err := filenextra.InterpretEvent("file-rename", data)
+			assert.True(t, errors.Is(err, filenextra.ErrInvalidEvent), "error: %v", err)
+			assert.Equal(t, "file-rename", evt.Name)
+			if assert.IsType(t, &filenextra.EventInvalid{}, evt.Data) {
+				assert.Equal(t, "f1", evt.Data.(*filenextra.EventInvalid).UUID)
+			}
+		})
+	}
+
+	_, err := filenextra.InterpretEvent("folder-rename", map[string]any{"uuid": "d1", "name": map[string]any{"name": ".."}})
+	assert.ErrorIs(t, err, filenextra.ErrInvalidEvent)
+
+	_, err = filenextra.InterpretEvent("unknown", map[string]any{})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, filenextra.ErrInvalidEvent)
+}
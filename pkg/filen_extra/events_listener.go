@@ -1,254 +1,330 @@
 package filenextra
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
-	"net/url"
-	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/FilenCloudDienste/filen-sdk-go/filen"
-	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/socketio"
 	"github.com/rs/zerolog/log"
 )
 
-var ErrInvalidWebSocketURL = errors.New("invalid WebSocket URL")
+var (
+	ErrInvalidWebSocketURL = errors.New("invalid WebSocket URL")
+	ErrAuthFailed          = errors.New("event socket authentication failed")
+)
 
-const packetTypeConnect = '0'
-const messageTypeEvent = '2'
-const packetTypePong = '3'
-const packetTypeMessage = '4'
+// ConnectionState is the state of the event socket as seen by consumers.
+type ConnectionState int
+
+const (
+	// ConnectionConnecting means the socket is being (re)connected.
+	ConnectionConnecting ConnectionState = iota
+	// ConnectionAuthenticating means the socket is connected and waits for
+	// the API key to be accepted.
+	ConnectionAuthenticating
+	// ConnectionReady means events are delivered.
+	ConnectionReady
+	// ConnectionLost means the socket was disconnected. Events sent until it
+	// is ready again are lost.
+	ConnectionLost
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionConnecting:
+		return "connecting"
+	case ConnectionAuthenticating:
+		return "authenticating"
+	case ConnectionReady:
+		return "ready"
+	case ConnectionLost:
+		return "lost"
+	default:
+		return "unknown"
+	}
+}
+
+// EventConnectionState is delivered in between the socket events whenever
+// the connection state changes.
+type EventConnectionState struct {
+	State ConnectionState
+	Err   error
+}
+
+const eventConnectionState = "connection-state"
 
 type FilenEventListener struct {
-	filen     *filen.Filen
-	conn      *WebsocketConnection
-	eventChan chan TypedEvent
+	decrypter *EventDecrypter
+	socket    *socketio.Client
+	index     *ParentIndex
+	// stream is the subscription read by NextEvent.
+	stream *Subscription
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	subMu         sync.Mutex
+	subscriptions []*Subscription
+	closed        bool
+
+	mu       sync.Mutex
+	filen    *filen.Filen
+	session  *Session
+	recorder *Recorder
+	// relogging is set while a login after authFailed is running.
+	relogging atomic.Bool
 }
 
 func NewFilenEvents(u string, client *filen.Filen, requestHeader http.Header) (*FilenEventListener, error) {
-	wsUrl, err := buildWebSocketURL(u)
+	err := ValidateSocketURL(u)
 	if err != nil {
 		return nil, err
 	}
 
-	return &FilenEventListener{
-		filen: client,
-		conn:  NewWebsocketConnection(wsUrl, requestHeader),
-	}, nil
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &FilenEventListener{
+		filen:     client,
+		decrypter: NewEventDecrypter(NewMasterKeyDecrypter(client)),
+		index:     NewParentIndex(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	e.decrypter.SetParentIndex(e.index)
+	e.stream = e.Subscribe(SubscribeOptions{})
+	e.socket, err = socketio.NewClient(socketio.Config{
+		URL:           u,
+		Protocol:      socketio.EIO3,
+		Header:        requestHeader,
+		OnConnect:     e.handleConnect,
+		OnEvent:       e.handleEvent,
+		OnStateChange: e.handleStateChange,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return e, nil
 }
 
-func (e *FilenEventListener) Start() {
-	e.eventChan = make(chan TypedEvent, 100)
-	e.conn.Start()
+// SetSession makes the listener log in again through session when the
+// socket refuses the API key, and follow the clients session hands out.
+// Without a session a refused API key leaves the socket unauthenticated.
+func (e *FilenEventListener) SetSession(session *Session) {
+	e.mu.Lock()
+	e.session = session
+	e.mu.Unlock()
+	session.OnClientChange(e.setClient)
+}
 
-	go func() {
-		for {
-			msg, ok := e.conn.NextMessage()
-			if !ok {
-				break
-			}
-			e.handleMessage(msg)
-		}
-	}()
+// SetRecorder records every message of the socket to recorder, along with
+// the connection state changes.
+func (e *FilenEventListener) SetRecorder(recorder *Recorder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recorder = recorder
 }
 
-func (e *FilenEventListener) NextEvent() (TypedEvent, bool) {
-	event, ok := <-e.eventChan
-	return event, ok
+func (e *FilenEventListener) currentRecorder() *Recorder {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.recorder
 }
 
-func (e *FilenEventListener) Close() error {
-	defer close(e.eventChan)
-	return e.conn.Close()
+func (e *FilenEventListener) setClient(client *filen.Filen) {
+	e.mu.Lock()
+	e.filen = client
+	e.mu.Unlock()
+	e.decrypter.SetMasterKey(NewMasterKeyDecrypter(client))
 }
 
-func (e *FilenEventListener) handleMessage(message []byte) {
-	if len(message) == 0 {
-		log.Warn().Msg("Received empty message")
-		return
-	}
+func (e *FilenEventListener) client() (*filen.Filen, *Session) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.filen, e.session
+}
+
+// SetShareKey decrypts events about items in the shared folder folderUUID
+// with key instead of the master keys. Items below it are found through the
+// parent index.
+func (e *FilenEventListener) SetShareKey(folderUUID string, key MetaDecrypter) {
+	e.decrypter.SetShareKey(folderUUID, key)
+}
 
-	packetType := message[0]
-	payload := message[1:]
+func (e *FilenEventListener) Start() {
+	e.socket.Start()
+}
 
-	switch packetType {
-	case packetTypeConnect:
-		e.handleHandshake(payload)
-	case packetTypePong:
-		log.Debug().Msg("Received pong")
-	case packetTypeMessage:
-		e.handleMessagePayload(payload)
-	default:
-		log.Warn().Msgf("Unknown packet type: %c", packetType)
-	}
+// NextEvent reads the subscription the listener starts with. It blocks the
+// listener once 100 events are waiting, so consumers that only use their own
+// subscriptions close it with CloseNextEvent.
+func (e *FilenEventListener) NextEvent() (TypedEvent, bool) {
+	return e.stream.Next()
 }
 
-func (e *FilenEventListener) handleMessagePayload(payload []byte) {
-	messageType := payload[0]
-	messageData := payload[1:]
+func (e *FilenEventListener) CloseNextEvent() {
+	e.stream.Close()
+}
 
-	if messageType != messageTypeEvent {
-		return
-	}
+// Index returns the index used to place events in the tree for subtree
+// subscriptions.
+func (e *FilenEventListener) Index() *ParentIndex {
+	return e.index
+}
+
+func (e *FilenEventListener) Close() error {
+	defer e.closeSubscriptions()
+	e.cancel()
+	return e.socket.Close()
+}
 
-	var eventData []any
-	err := json.Unmarshal(messageData, &eventData)
+// handleConnect asks the server whether the socket is authenticated, which
+// is answered with an "authed" event.
+func (e *FilenEventListener) handleConnect() {
+	err := e.socket.Emit("authed", time.Now().UnixMilli())
 	if err != nil {
-		log.Error().Err(err).Str("payload", string(messageData)).Msg("Failed to unmarshal event payload")
-		return
+		log.Error().Err(err).Msg("Failed to send authed event")
 	}
+}
 
-	if len(eventData) == 0 {
-		log.Warn().Msg("Empty event data")
+func (e *FilenEventListener) handleStateChange(state socketio.State, err error) {
+	var connectionState ConnectionState
+	switch state {
+	case socketio.StateConnecting:
+		connectionState = ConnectionConnecting
+	case socketio.StateConnected:
+		connectionState = ConnectionAuthenticating
+	case socketio.StateDisconnected:
+		connectionState = ConnectionLost
+	default:
 		return
 	}
+	e.emitConnectionState(connectionState, err)
+}
 
-	eventName, ok := eventData[0].(string)
-	if !ok {
-		log.Warn().Msg("Invalid event name type")
-		return
+func (e *FilenEventListener) emitConnectionState(state ConnectionState, err error) {
+	log.Info().Err(err).Msgf("Event socket %s", state)
+	if recorder := e.currentRecorder(); recorder != nil {
+		rec := Record{Event: eventConnectionState, State: state.String()}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		recorder.record(rec)
+	}
+	e.publish(TypedEvent{
+		Name: eventConnectionState,
+		Data: &EventConnectionState{State: state, Err: err},
+	})
+}
+
+func (e *FilenEventListener) handleEvent(evt socketio.Event) {
+	eventName := evt.Name
+	var rec *Record
+	if recorder := e.currentRecorder(); recorder != nil {
+		rec = &Record{Event: eventName, Raw: decodeArgs(evt.Args)}
+		defer func() { recorder.record(*rec) }()
 	}
 
 	switch eventName {
 	case "authFailed":
-		e.Close()
-		log.Fatal().Msg("Authentication failed")
+		e.emitConnectionState(ConnectionLost, ErrAuthFailed)
+		e.relogin()
 	case "authSuccess":
 		log.Info().Msg("Authentication successful")
+		e.emitConnectionState(ConnectionReady, nil)
 	case "authed":
-		if len(eventData) < 2 || eventData[1] == false {
-			err := e.sendEvent("auth", map[string]string{
-				"apiKey": e.filen.Client.APIKey,
-			})
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to send auth event")
-			}
+		var authed bool
+		if len(evt.Args) > 0 {
+			_ = json.Unmarshal(evt.Args[0], &authed)
+		}
+		if authed {
+			e.emitConnectionState(ConnectionReady, nil)
+			return
 		}
+		client, _ := e.client()
+		e.sendAuth(client)
 	default:
-		filenEventData := make(map[string]any)
-		if len(eventData) >= 2 {
-			if dataMap, ok := eventData[1].(map[string]any); ok {
-				filenEventData = e.decryptFields(dataMap)
-				unmarshalKey(filenEventData, "metadata")
-				unmarshalKey(filenEventData, "name")
-			}
+		var rawData map[string]any
+		if len(evt.Args) > 0 {
+			_ = json.Unmarshal(evt.Args[0], &rawData)
 		}
 
-		filenEvent, err := InterpretEvent(eventName, filenEventData)
+		filenEventData, err := e.decrypter.Decrypt(eventName, rawData)
 		if err != nil {
-			log.Error().Err(err).Msgf("Failed to parse event: %s", eventName)
+			log.Error().Err(err).Msgf("Failed to decrypt event: %s", eventName)
+			if rec != nil {
+				rec.Error = err.Error()
+			}
+			uuid, _ := rawData["uuid"].(string)
+			e.publish(TypedEvent{
+				Name: eventName,
+				Data: &EventInvalid{UUID: uuid, Err: err},
+			})
 			return
 		}
-		e.eventChan <- filenEvent
-	}
-}
 
-func unmarshalKey(m map[string]any, key string) {
-	if raw, ok := m[key]; ok {
-		if str, ok := raw.(string); ok {
-			var result map[string]any
-			err := json.Unmarshal([]byte(str), &result)
-			if err == nil {
-				m[key] = result
+		filenEvent, err := InterpretEvent(eventName, filenEventData)
+		if rec != nil {
+			rec.Decoded = filenEventData
+			if err != nil {
+				rec.Error = err.Error()
 			}
 		}
-	}
-}
-
-func (e *FilenEventListener) decryptFields(data map[string]any) map[string]any {
-	decryptedData := make(map[string]any)
-	for key, value := range data {
-		if strValue, ok := value.(string); ok {
-			if strings.HasPrefix(strValue, "U2FsdGVk") || strings.HasPrefix(strValue, "002") || strings.HasPrefix(strValue, "003") {
-				decrypted, err := e.filen.DecryptMeta(crypto.EncryptedString(strValue))
-				if err != nil {
-					decryptedData[key] = value
-				} else {
-					decryptedData[key] = decrypted
-				}
-			} else {
-				decryptedData[key] = value
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to parse event: %s", eventName)
+			if !errors.Is(err, ErrInvalidEvent) {
+				return
 			}
-		} else if nestedMap, ok := value.(map[string]any); ok {
-			decryptedData[key] = e.decryptFields(nestedMap)
-		} else {
-			decryptedData[key] = value
 		}
+		e.publish(filenEvent)
 	}
-
-	return decryptedData
 }
 
-type handshakePayload struct {
-	PingInterval int `json:"pingInterval"`
-}
-
-func (e *FilenEventListener) handleHandshake(payload []byte) {
-	hp, err := parseHandshakePayload(payload)
+func (e *FilenEventListener) sendAuth(client *filen.Filen) {
+	err := e.socket.Emit("auth", map[string]string{
+		"apiKey": client.Client.APIKey,
+	})
 	if err != nil {
-		log.Warn().Err(err).Msg("Failed to parse handshake payload")
+		log.Error().Err(err).Msg("Failed to send auth event")
 	}
-	e.conn.SetPingInterval(time.Duration(hp.PingInterval) * time.Millisecond)
+}
 
-	err = e.conn.SendString(string(packetTypeMessage) + string(packetTypeConnect))
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to send connect acknowledgment")
+// relogin replaces the refused client through the session and
+// authenticates the socket with the new API key. The login runs in the
+// background, as it retries until it succeeds.
+func (e *FilenEventListener) relogin() {
+	stale, session := e.client()
+	if session == nil {
+		log.Error().Msg("Event socket refused the API key and there is no session to log in again")
 		return
 	}
-
-	err = e.sendEvent("authed", time.Now().UnixMilli())
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to send authed event")
+	if !e.relogging.CompareAndSwap(false, true) {
 		return
 	}
-}
 
-func parseHandshakePayload(payload []byte) (*handshakePayload, error) {
-	var hp handshakePayload
-	err := json.Unmarshal(payload, &hp)
-	if err != nil {
-		return nil, err
-	}
-
-	if hp.PingInterval == 0 {
-		hp.PingInterval = 15000
-	}
-
-	return &hp, nil
-}
-
-func (e *FilenEventListener) sendEvent(event string, data any) error {
-	var message []any
-	message = append(message, event)
-	if data != nil {
-		message = append(message, data)
-	}
-	payloadBytes, err := json.Marshal(message)
-	if err != nil {
-		return err
-	}
-
-	fullMessage := string(packetTypeMessage) + string(messageTypeEvent) + string(payloadBytes)
-	return e.conn.SendString(fullMessage)
+	go func() {
+		defer e.relogging.Store(false)
+		client, err := session.Relogin(e.ctx, stale)
+		if err != nil {
+			return
+		}
+		e.sendAuth(client)
+	}()
 }
 
-func buildWebSocketURL(baseURL string) (string, error) {
-	params := map[string]string{
-		"EIO":       "3",
-		"transport": "websocket",
-		"t":         strconv.FormatInt(time.Now().UnixMilli(), 10),
-	}
-
-	wsUrl, err := url.Parse(baseURL + "/socket.io/")
-	if err != nil {
-		return "", err
-	}
-	query := wsUrl.Query()
-	for k, v := range params {
-		query.Set(k, v)
+// decodeArgs decodes event arguments for recording. Arguments that are not
+// valid JSON are kept as their text.
+func decodeArgs(args []json.RawMessage) []any {
+	decoded := make([]any, len(args))
+	for i, arg := range args {
+		err := json.Unmarshal(arg, &decoded[i])
+		if err != nil {
+			decoded[i] = string(arg)
+		}
 	}
-	wsUrl.RawQuery = query.Encode()
-	return wsUrl.String(), nil
+	return decoded
 }
@@ -0,0 +1,104 @@
+package filenextra
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+// FileMetadata is the decrypted metadata of a file as sent in socket events.
+// Size and LastModified may arrive as numbers or numeric strings.
+type FileMetadata struct {
+	Name         string `json:"name"`
+	Size         int64  `json:"size"`
+	Mime         string `json:"mime"`
+	Key          string `json:"key"`
+	LastModified int64  `json:"lastModified"`
+	Hash         string `json:"hash"`
+}
+
+func (m *FileMetadata) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name         string      `json:"name"`
+		Size         json.Number `json:"size"`
+		Mime         string      `json:"mime"`
+		Key          string      `json:"key"`
+		LastModified json.Number `json:"lastModified"`
+		Hash         string      `json:"hash"`
+	}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	size, err := parseOptionalInt(raw.Size)
+	if err != nil {
+		return fmt.Errorf("size: %w", err)
+	}
+	lastModified, err := parseOptionalInt(raw.LastModified)
+	if err != nil {
+		return fmt.Errorf("lastModified: %w", err)
+	}
+
+	*m = FileMetadata{
+		Name:         raw.Name,
+		Size:         size,
+		Mime:         raw.Mime,
+		Key:          raw.Key,
+		LastModified: lastModified,
+		Hash:         raw.Hash,
+	}
+	return nil
+}
+
+// Validate checks that the metadata can be used to place a file locally.
+func (m FileMetadata) Validate() error {
+	err := validateName(m.Name)
+	if err != nil {
+		return err
+	}
+	if m.Size < 0 {
+		return fmt.Errorf("negative size %d", m.Size)
+	}
+	if m.LastModified < 0 {
+		return fmt.Errorf("negative lastModified %d", m.LastModified)
+	}
+	return nil
+}
+
+// ModTime returns LastModified as a time.
+func (m FileMetadata) ModTime() time.Time {
+	return time.UnixMilli(m.LastModified)
+}
+
+func (n NameStruct) Validate() error {
+	return validateName(n.Name)
+}
+
+func validateName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("missing name")
+	case name == "." || name == ".." || strings.Contains(name, "/"):
+		return fmt.Errorf("invalid name %q", name)
+	}
+	return nil
+}
+
+func parseOptionalInt(n json.Number) (int64, error) {
+	if n == "" {
+		return 0, nil
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, err
+	}
+	return int64(f), nil
+}
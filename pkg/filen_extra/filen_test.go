@@ -0,0 +1,234 @@
+package filenextra_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filentest"
+	"github.com/stretchr/testify/assert"
+)
+
+var account = filentest.Account{
+	Email:         "user@example.com",
+	Password:      "password",
+	TwoFactorCode: "123456",
+}
+
+func login(t *testing.T) (*filentest.Server, *filen.Filen) {
+	srv := filentest.Start(t, account)
+	client, err := filen.New(srv.Context(context.Background()), account.Email, account.Password, account.TwoFactorCode)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return srv, client
+}
+
+func TestLoginWrongTwoFactorCode(t *testing.T) {
+	srv := filentest.Start(t, account)
+	_, err := filen.New(srv.Context(context.Background()), account.Email, account.Password, "000000")
+	assert.Error(t, err)
+}
+
+func TestDownload(t *testing.T) {
+	srv, client := login(t)
+	assert.Equal(t, srv.BaseFolderUUID(), client.BaseFolder.UUID)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), filen.ChunkSize/16*2+100)
+	uuid, err := srv.AddFile(srv.BaseFolderUUID(), "file.bin", content, time.UnixMilli(1000))
+	assert.NoError(t, err)
+
+	file, err := filenextra.GetFile(context.Background(), client, uuid)
+	assert.NoError(t, err)
+	assert.Equal(t, "file.bin", file.Name)
+	assert.Equal(t, len(content), file.Size)
+	assert.Equal(t, 3, file.Chunks)
+	assert.True(t, file.LastModified.Equal(time.UnixMilli(1000)))
+
+	r, err := filenextra.CreateDownloadReader(context.Background(), client, uuid)
+	assert.NoError(t, err)
+	downloaded, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, content, downloaded)
+}
+
+func TestEvents(t *testing.T) {
+	srv, client := login(t)
+
+	events, err := filenextra.NewFilenEvents(srv.SocketURL(), client, http.Header{})
+	assert.NoError(t, err)
+	events.Start()
+	defer events.Close()
+	waitForState(t, events, filenextra.ConnectionReady)
+	assert.Equal(t, 1, srv.AuthedSockets())
+
+	dir := srv.CreateDir(srv.BaseFolderUUID(), "dir1")
+	_, err = srv.CreateFile(dir, "file1.txt", []byte("hello"), time.UnixMilli(2000))
+	assert.NoError(t, err)
+
+	evt := nextEvent(t, events)
+	if assert.IsType(t, &filenextra.EventSocketFolderSubCreated{}, evt.Data) {
+		assert.Equal(t, "dir1", evt.Data.(*filenextra.EventSocketFolderSubCreated).Name.Name)
+	}
+
+	evt = nextEvent(t, events)
+	if assert.IsType(t, &filenextra.EventSocketFileNew{}, evt.Data) {
+		e := evt.Data.(*filenextra.EventSocketFileNew)
+		assert.Equal(t, dir, e.Parent)
+		assert.Equal(t, "file1.txt", e.Meta.Name)
+	}
+}
+
+func TestEventsReconnect(t *testing.T) {
+	srv, client := login(t)
+
+	events, err := filenextra.NewFilenEvents(srv.SocketURL(), client, http.Header{})
+	assert.NoError(t, err)
+	events.Start()
+	defer events.Close()
+	waitForState(t, events, filenextra.ConnectionReady)
+
+	srv.DisconnectSockets()
+	lost := waitForState(t, events, filenextra.ConnectionLost)
+	assert.Error(t, lost.Err)
+
+	// the socket is authenticated again after the reconnect
+	waitForState(t, events, filenextra.ConnectionReady)
+	assert.Equal(t, 1, srv.AuthedSockets())
+
+	srv.CreateDir(srv.BaseFolderUUID(), "dir1")
+	assert.IsType(t, &filenextra.EventSocketFolderSubCreated{}, nextEvent(t, events).Data)
+}
+
+func TestEventsRelogin(t *testing.T) {
+	srv, client := login(t)
+	session := filenextra.NewSession(client, func(ctx context.Context) (*filen.Filen, error) {
+		return filen.New(srv.Context(ctx), account.Email, account.Password, account.TwoFactorCode)
+	}, filenextra.SessionConfig{})
+
+	events, err := filenextra.NewFilenEvents(srv.SocketURL(), client, http.Header{})
+	assert.NoError(t, err)
+	events.SetSession(session)
+	events.Start()
+	defer events.Close()
+	waitForState(t, events, filenextra.ConnectionReady)
+
+	srv.RevokeAPIKey()
+	waitForState(t, events, filenextra.ConnectionLost)
+	// the reconnected socket is refused with the old API key
+	lost := waitForState(t, events, filenextra.ConnectionLost)
+	assert.ErrorIs(t, lost.Err, filenextra.ErrAuthFailed)
+
+	waitForState(t, events, filenextra.ConnectionReady)
+	assert.NotSame(t, client, session.Client())
+	assert.Equal(t, srv.APIKey(), session.Client().Client.APIKey)
+	assert.Equal(t, 1, srv.AuthedSockets())
+
+	srv.CreateDir(srv.BaseFolderUUID(), "dir1")
+	assert.IsType(t, &filenextra.EventSocketFolderSubCreated{}, nextEvent(t, events).Data)
+}
+
+func TestEventsInNestedShare(t *testing.T) {
+	srv, client := login(t)
+	share := srv.AddSharedDir("", "share")
+	sub := srv.AddSharedDir(share, "sub")
+
+	events, err := filenextra.NewFilenEvents(srv.SocketURL(), client, http.Header{})
+	assert.NoError(t, err)
+	assert.NoError(t, events.RegisterShares(context.Background()))
+	events.Start()
+	defer events.Close()
+	waitForState(t, events, filenextra.ConnectionReady)
+
+	_, err = srv.CreateSharedDir(sub, "nested")
+	assert.NoError(t, err)
+	evt := nextEvent(t, events)
+	if assert.IsType(t, &filenextra.EventSocketFolderSubCreated{}, evt.Data) {
+		assert.Equal(t, "nested", evt.Data.(*filenextra.EventSocketFolderSubCreated).Name.Name)
+	}
+}
+
+func TestEventsRecordAndReplay(t *testing.T) {
+	srv, client := login(t)
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recorder, err := filenextra.NewRecorder(filenextra.RecorderConfig{Path: path})
+	assert.NoError(t, err)
+
+	events, err := filenextra.NewFilenEvents(srv.SocketURL(), client, http.Header{})
+	assert.NoError(t, err)
+	events.SetRecorder(recorder)
+	events.Start()
+	waitForState(t, events, filenextra.ConnectionReady)
+
+	dir := srv.CreateDir(srv.BaseFolderUUID(), "dir1")
+	_, err = srv.CreateFile(dir, "file1.txt", []byte("hello"), time.UnixMilli(2000))
+	assert.NoError(t, err)
+	received := []filenextra.TypedEvent{nextEvent(t, events), nextEvent(t, events)}
+	assert.NoError(t, events.Close())
+	assert.NoError(t, recorder.Close())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), srv.APIKey())
+	assert.NotContains(t, string(content), received[1].Data.(*filenextra.EventSocketFileNew).Meta.Key)
+
+	replay, err := filenextra.NewReplaySource(path)
+	assert.NoError(t, err)
+	replay.Start()
+	var replayed []filenextra.TypedEvent
+	var states []filenextra.ConnectionState
+	for {
+		evt, ok := replay.NextEvent()
+		if !ok {
+			break
+		}
+		if e, ok := evt.Data.(*filenextra.EventConnectionState); ok {
+			states = append(states, e.State)
+			continue
+		}
+		replayed = append(replayed, evt)
+	}
+	assert.Equal(t, []filenextra.ConnectionState{
+		filenextra.ConnectionConnecting, filenextra.ConnectionAuthenticating, filenextra.ConnectionReady,
+	}, states)
+
+	if assert.Len(t, replayed, 2) {
+		assert.Equal(t, received[0], replayed[0])
+		fileNew := *received[1].Data.(*filenextra.EventSocketFileNew)
+		fileNew.Meta.Key = "[redacted]"
+		assert.Equal(t, &fileNew, replayed[1].Data)
+	}
+}
+
+// nextEvent returns the next event that is not a connection state change.
+func nextEvent(t *testing.T, events *filenextra.FilenEventListener) filenextra.TypedEvent {
+	for {
+		evt, ok := events.NextEvent()
+		if !assert.True(t, ok) {
+			t.FailNow()
+		}
+		if _, ok := evt.Data.(*filenextra.EventConnectionState); !ok {
+			return evt
+		}
+	}
+}
+
+func waitForState(t *testing.T, events *filenextra.FilenEventListener, state filenextra.ConnectionState) *filenextra.EventConnectionState {
+	for {
+		evt, ok := events.NextEvent()
+		if !assert.True(t, ok) {
+			t.FailNow()
+		}
+		if e, ok := evt.Data.(*filenextra.EventConnectionState); ok && e.State == state {
+			return e
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package filenextra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrTwoFactorRejected is returned by Login if the server rejected every 2FA
+// code tried, or asked for one when none was given.
+var ErrTwoFactorRejected = errors.New("two factor code rejected")
+
+// NoTwoFactorCode is sent in place of a code for accounts without 2FA.
+const NoTwoFactorCode = "XXXXXX"
+
+// twoFactorWindows are the time windows tried by Login, relative to the
+// current one.
+var twoFactorWindows = []int{0, -1, 1}
+
+// Login logs in to the account. code returns the 2FA code of the time window
+// at the given offset from the current one, or is nil if the account has no
+// 2FA. A rejected code is retried with the previous and the next window,
+// which covers a code expiring during the login and a small clock skew.
+func Login(ctx context.Context, email, password string, code func(window int) (string, error)) (*filen.Filen, error) {
+	if code == nil {
+		client, err := filen.New(ctx, email, password, NoTwoFactorCode)
+		if isTwoFactorError(err) {
+			return nil, fmt.Errorf("%w: the account requires a 2FA code: %w", ErrTwoFactorRejected, err)
+		}
+		return client, err
+	}
+
+	var err error
+	for _, window := range twoFactorWindows {
+		var otp string
+		otp, err = code(window)
+		if err != nil {
+			return nil, err
+		}
+		var client *filen.Filen
+		client, err = filen.New(ctx, email, password, otp)
+		if !isTwoFactorError(err) {
+			return client, err
+		}
+		log.Warn().Msgf("2FA code of time window %+d rejected", window)
+	}
+	return nil, fmt.Errorf("%w in the current and the adjacent time windows: %w", ErrTwoFactorRejected, err)
+}
+
+// twoFactorErrorCodes are the API codes of logins refused because of a
+// missing or wrong 2FA code.
+var twoFactorErrorCodes = []string{"enter_2fa", "wrong_2fa"}
+
+func isTwoFactorError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	for _, code := range twoFactorErrorCodes {
+		if strings.Contains(message, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClockSkew estimates how far the local clock is ahead of the server at
+// baseURL, using the Date header of a HEAD request. The header has a
+// resolution of one second.
+func ClockSkew(ctx context.Context, baseURL string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	sent := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	_ = resp.Body.Close()
+
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return 0, fmt.Errorf("%s sent no Date header", baseURL)
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, fmt.Errorf("%s sent an invalid Date header %q: %w", baseURL, date, err)
+	}
+
+	// the header is truncated to the second, so its middle is the best guess
+	serverTime = serverTime.Add(500 * time.Millisecond)
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(serverTime), nil
+}
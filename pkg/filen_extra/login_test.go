@@ -0,0 +1,69 @@
+package filenextra_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filentest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginRetriesAdjacentWindows(t *testing.T) {
+	srv := filentest.Start(t, account)
+
+	var windows []int
+	client, err := filenextra.Login(srv.Context(context.Background()), account.Email, account.Password, func(window int) (string, error) {
+		windows = append(windows, window)
+		if window == 1 {
+			return account.TwoFactorCode, nil
+		}
+		return "000000", nil
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, client)
+	assert.Equal(t, []int{0, -1, 1}, windows)
+	assert.Equal(t, 1, srv.Logins())
+}
+
+func TestLoginTwoFactorRejected(t *testing.T) {
+	srv := filentest.Start(t, account)
+
+	_, err := filenextra.Login(srv.Context(context.Background()), account.Email, account.Password, func(int) (string, error) {
+		return "000000", nil
+	})
+	assert.ErrorIs(t, err, filenextra.ErrTwoFactorRejected)
+	assert.True(t, filenextra.IsCredentialError(err))
+
+	_, err = filenextra.Login(srv.Context(context.Background()), account.Email, account.Password, nil)
+	assert.ErrorIs(t, err, filenextra.ErrTwoFactorRejected)
+	assert.ErrorContains(t, err, "the account requires a 2FA code")
+}
+
+func TestLoginWithoutTwoFactor(t *testing.T) {
+	withoutTwoFactor := account
+	withoutTwoFactor.TwoFactorCode = ""
+	srv := filentest.Start(t, withoutTwoFactor)
+
+	client, err := filenextra.Login(srv.Context(context.Background()), account.Email, account.Password, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, client)
+
+	_, err = filenextra.Login(srv.Context(context.Background()), account.Email, "wrong", nil)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, filenextra.ErrTwoFactorRejected)
+}
+
+func TestClockSkew(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	skew, err := filenextra.ClockSkew(context.Background(), srv.URL)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), skew.Seconds(), 1.5)
+}
@@ -0,0 +1,176 @@
+package filenextra
+
+import "sync"
+
+// maxDepth bounds the walk up the tree, in case the index holds a cycle.
+const maxDepth = 1024
+
+// ParentIndex maps items to their parent folder, so that events naming only
+// an item can be placed in the tree. Only the items below its roots, the
+// subscribed subtrees and shared folders, are kept. The listener keeps it up
+// to date from the events it receives and adds the items that existed before
+// when a root is added.
+type ParentIndex struct {
+	mu       sync.RWMutex
+	roots    map[string]struct{}
+	parents  map[string]string
+	children map[string]map[string]struct{}
+}
+
+func NewParentIndex() *ParentIndex {
+	return &ParentIndex{
+		roots:    make(map[string]struct{}),
+		parents:  make(map[string]string),
+		children: make(map[string]map[string]struct{}),
+	}
+}
+
+// AddRoot makes the index keep track of the items below uuid.
+func (x *ParentIndex) AddRoot(uuid string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.roots[uuid] = struct{}{}
+}
+
+func (x *ParentIndex) Set(uuid, parent string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.set(uuid, parent)
+}
+
+func (x *ParentIndex) Remove(uuid string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.unset(uuid)
+}
+
+func (x *ParentIndex) Parent(uuid string) (string, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	parent, ok := x.parents[uuid]
+	return parent, ok
+}
+
+// InSubtree reports whether uuid is root or lies below it.
+func (x *ParentIndex) InSubtree(uuid, root string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	for range maxDepth {
+		if uuid == root {
+			return true
+		}
+		parent, ok := x.parents[uuid]
+		if !ok {
+			return false
+		}
+		uuid = parent
+	}
+	return false
+}
+
+func (x *ParentIndex) set(uuid, parent string) {
+	x.unset(uuid)
+	x.parents[uuid] = parent
+	if x.children[parent] == nil {
+		x.children[parent] = make(map[string]struct{})
+	}
+	x.children[parent][uuid] = struct{}{}
+}
+
+func (x *ParentIndex) unset(uuid string) {
+	parent, ok := x.parents[uuid]
+	if !ok {
+		return
+	}
+	delete(x.parents, uuid)
+	delete(x.children[parent], uuid)
+	if len(x.children[parent]) == 0 {
+		delete(x.children, parent)
+	}
+}
+
+// tracked reports whether the items in uuid are kept.
+func (x *ParentIndex) tracked(uuid string) bool {
+	if _, ok := x.roots[uuid]; ok {
+		return true
+	}
+	_, ok := x.parents[uuid]
+	return ok
+}
+
+// removeSubtree removes uuid and everything below it. Roots below uuid keep
+// their items.
+func (x *ParentIndex) removeSubtree(uuid string) {
+	x.unset(uuid)
+	if _, ok := x.roots[uuid]; ok {
+		return
+	}
+	for child := range x.children[uuid] {
+		x.removeSubtree(child)
+	}
+	delete(x.children, uuid)
+}
+
+// observe records the placement an event reports. Items that leave the
+// tracked subtrees are dropped with everything below them.
+func (x *ParentIndex) observe(evt TypedEvent) {
+	uuid, parent := eventLocation(evt)
+	if uuid == "" {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	switch evt.Data.(type) {
+	case *EventSocketFileTrash, *EventSocketFileDeletedPermanent, *EventSocketFolderTrash:
+		x.removeSubtree(uuid)
+		return
+	}
+	if parent == "" {
+		return
+	}
+	if x.tracked(parent) {
+		x.set(uuid, parent)
+	} else {
+		x.removeSubtree(uuid)
+	}
+}
+
+// eventLocation returns the item an event is about and, if the event tells,
+// its parent folder.
+func eventLocation(evt TypedEvent) (uuid, parent string) {
+	switch e := evt.Data.(type) {
+	case *EventSocketFileNew:
+		return e.UUID, e.Parent
+	case *EventSocketFileRestore:
+		return e.UUID, e.Parent
+	case *EventSocketFileMove:
+		return e.UUID, e.Parent
+	case *EventSocketFileArchiveRestored:
+		return e.UUID, e.Parent
+	case *EventSocketFolderTrash:
+		return e.UUID, e.Parent
+	case *EventSocketFolderMove:
+		return e.UUID, e.Parent
+	case *EventSocketFolderSubCreated:
+		return e.UUID, e.Parent
+	case *EventSocketFolderRestore:
+		return e.UUID, e.Parent
+	case *EventSocketFileRename:
+		return e.UUID, ""
+	case *EventSocketFileTrash:
+		return e.UUID, ""
+	case *EventSocketFileArchived:
+		return e.UUID, ""
+	case *EventSocketFolderRename:
+		return e.UUID, ""
+	case *EventSocketFolderColorChanged:
+		return e.UUID, ""
+	case *EventSocketFileDeletedPermanent:
+		return e.UUID, ""
+	case *EventInvalid:
+		return e.UUID, ""
+	default:
+		return "", ""
+	}
+}
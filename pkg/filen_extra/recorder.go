@@ -0,0 +1,197 @@
+package filenextra
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// redacted replaces the values of secretFields in recordings.
+const redacted = "[redacted]"
+
+// secretFields are left out of recordings wherever they appear. "key" is the
+// encryption key of a file, which is part of the decrypted metadata.
+var secretFields = map[string]bool{
+	"apiKey":     true,
+	"key":        true,
+	"password":   true,
+	"privateKey": true,
+	"masterKeys": true,
+}
+
+// Record is one line of a recording.
+type Record struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	// Raw holds the arguments as received from the socket.
+	Raw []any `json:"raw,omitempty"`
+	// Decoded holds the decrypted data InterpretEvent was called with.
+	Decoded map[string]any `json:"decoded,omitempty"`
+	// State is set for connection state changes.
+	State string `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type RecorderConfig struct {
+	// Path of the recording. Rotated recordings are kept next to it with the
+	// suffixes .1 (the most recent) to .MaxFiles.
+	Path string
+	// MaxSize is the size in bytes after which the recording is rotated.
+	// Defaults to 10 MiB.
+	MaxSize int64
+	// MaxFiles is the number of rotated recordings kept. Defaults to 5.
+	MaxFiles int
+}
+
+// Recorder writes the messages of an event socket to a JSONL file, with
+// secrets redacted.
+type Recorder struct {
+	cfg RecorderConfig
+	now func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 << 20
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = 5
+	}
+	r := &Recorder{cfg: cfg, now: time.Now}
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) open() error {
+	file, err := os.OpenFile(r.cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Record appends rec, setting its time if unset.
+func (r *Recorder) Record(rec Record) error {
+	if rec.Time.IsZero() {
+		rec.Time = r.now()
+	}
+	if rec.Raw != nil {
+		rec.Raw = redact(rec.Raw).([]any)
+	}
+	if rec.Decoded != nil {
+		rec.Decoded = redact(rec.Decoded).(map[string]any)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(line)) > r.cfg.MaxSize {
+		err = r.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// rotate shifts the rotated recordings by one, dropping the oldest, and
+// starts a new recording.
+func (r *Recorder) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return err
+	}
+
+	_ = os.Remove(rotatedPath(r.cfg.Path, r.cfg.MaxFiles))
+	for i := r.cfg.MaxFiles - 1; i >= 1; i-- {
+		err = os.Rename(rotatedPath(r.cfg.Path, i), rotatedPath(r.cfg.Path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	err = os.Rename(r.cfg.Path, rotatedPath(r.cfg.Path, 1))
+	if err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func rotatedPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// record is used by the listener, which must not fail because of the
+// recording.
+func (r *Recorder) record(rec Record) {
+	if r == nil {
+		return
+	}
+	err := r.Record(rec)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to record event")
+	}
+}
+
+// redact returns a copy of value with secretFields replaced.
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redactedMap := make(map[string]any, len(v))
+		for key, field := range v {
+			if secretFields[key] {
+				redactedMap[key] = redacted
+				continue
+			}
+			redactedMap[key] = redact(field)
+		}
+		return redactedMap
+	case []any:
+		redactedSlice := make([]any, len(v))
+		for i, item := range v {
+			redactedSlice[i] = redact(item)
+		}
+		return redactedSlice
+	default:
+		return value
+	}
+}
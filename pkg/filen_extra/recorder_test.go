@@ -0,0 +1,90 @@
+package filenextra_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorderRedactsSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recorder, err := filenextra.NewRecorder(filenextra.RecorderConfig{Path: path})
+	assert.NoError(t, err)
+
+	raw := []any{map[string]any{"apiKey": "secret", "uuid": "u1"}}
+	assert.NoError(t, recorder.Record(filenextra.Record{
+		Event:   "file-new",
+		Raw:     raw,
+		Decoded: map[string]any{"uuid": "u1", "metadata": map[string]any{"name": "a.txt", "key": "file key"}},
+	}))
+	assert.NoError(t, recorder.Close())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "secret")
+	assert.NotContains(t, string(content), "file key")
+	// the caller's data is left alone
+	assert.Equal(t, "secret", raw[0].(map[string]any)["apiKey"])
+
+	records, err := filenextra.ReadRecords(bytes.NewReader(content))
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "file-new", records[0].Event)
+		assert.False(t, records[0].Time.IsZero())
+		assert.Equal(t, "a.txt", records[0].Decoded["metadata"].(map[string]any)["name"])
+	}
+}
+
+func TestRecorderRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recorder, err := filenextra.NewRecorder(filenextra.RecorderConfig{Path: path, MaxSize: 200, MaxFiles: 2})
+	assert.NoError(t, err)
+	defer recorder.Close()
+
+	for i := range 10 {
+		assert.NoError(t, recorder.Record(filenextra.Record{
+			Time:    time.UnixMilli(int64(i)),
+			Event:   "file-trash",
+			Decoded: map[string]any{"uuid": fmt.Sprintf("%050d", i)},
+		}))
+	}
+
+	files, err := filepath.Glob(path + "*")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{path, path + ".1", path + ".2"}, files)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(200))
+	}
+
+	// the oldest records were dropped, the rest replay in order
+	replay, err := filenextra.NewReplaySource(path+".2", path+".1", path)
+	assert.NoError(t, err)
+	replay.Start()
+	var uuids []string
+	for {
+		evt, ok := replay.NextEvent()
+		if !ok {
+			break
+		}
+		uuids = append(uuids, evt.Data.(*filenextra.EventSocketFileTrash).UUID)
+	}
+	assert.NotEmpty(t, uuids)
+	assert.Less(t, len(uuids), 10)
+	for i, uuid := range uuids {
+		assert.Equal(t, fmt.Sprintf("%050d", 10-len(uuids)+i), uuid)
+	}
+}
+
+func TestReadRecordsInvalidLine(t *testing.T) {
+	_, err := filenextra.ReadRecords(strings.NewReader("{\"event\":\"x\"}\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
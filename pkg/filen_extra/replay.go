@@ -0,0 +1,138 @@
+package filenextra
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReadRecords reads a recording written by a Recorder.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		err := json.Unmarshal(scanner.Bytes(), &rec)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// ReplaySource delivers the events of recordings, decoded again with
+// InterpretEvent, so that a mirror can be run against them offline. It
+// closes once all records were delivered.
+type ReplaySource struct {
+	records   []Record
+	eventChan chan TypedEvent
+}
+
+// NewReplaySource reads the recordings at paths, which are replayed in the
+// given order. Rotated recordings have to be passed oldest first.
+func NewReplaySource(paths ...string) (*ReplaySource, error) {
+	var records []Record
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		fileRecords, err := ReadRecords(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		records = append(records, fileRecords...)
+	}
+	return NewReplaySourceFromRecords(records), nil
+}
+
+func NewReplaySourceFromRecords(records []Record) *ReplaySource {
+	return &ReplaySource{
+		records:   records,
+		eventChan: make(chan TypedEvent, 100),
+	}
+}
+
+func (s *ReplaySource) Start() {
+	go func() {
+		defer close(s.eventChan)
+		for _, rec := range s.records {
+			evt, ok := replayEvent(rec)
+			if ok {
+				s.eventChan <- evt
+			}
+		}
+	}()
+}
+
+func (s *ReplaySource) NextEvent() (TypedEvent, bool) {
+	event, ok := <-s.eventChan
+	return event, ok
+}
+
+// replayEvent turns a record back into the event the listener delivered for
+// it. Records the listener did not deliver an event for are skipped.
+func replayEvent(rec Record) (TypedEvent, bool) {
+	if rec.Event == eventConnectionState {
+		state, ok := parseConnectionState(rec.State)
+		if !ok {
+			log.Warn().Msgf("Skipping record with unknown connection state %q", rec.State)
+			return TypedEvent{}, false
+		}
+		var err error
+		if rec.Error != "" {
+			err = errors.New(rec.Error)
+		}
+		return TypedEvent{
+			Name: eventConnectionState,
+			Data: &EventConnectionState{State: state, Err: err},
+		}, true
+	}
+
+	if rec.Decoded == nil {
+		// authentication and events that failed to decrypt
+		if rec.Error == "" {
+			return TypedEvent{}, false
+		}
+		uuid, _ := firstArg(rec.Raw)["uuid"].(string)
+		return TypedEvent{
+			Name: rec.Event,
+			Data: &EventInvalid{UUID: uuid, Err: errors.New(rec.Error)},
+		}, true
+	}
+
+	evt, err := InterpretEvent(rec.Event, rec.Decoded)
+	if err != nil && !errors.Is(err, ErrInvalidEvent) {
+		log.Warn().Err(err).Msgf("Skipping recorded %s event", rec.Event)
+		return TypedEvent{}, false
+	}
+	return evt, true
+}
+
+func firstArg(raw []any) map[string]any {
+	if len(raw) == 0 {
+		return nil
+	}
+	arg, _ := raw[0].(map[string]any)
+	return arg
+}
+
+func parseConnectionState(s string) (ConnectionState, bool) {
+	for state := ConnectionConnecting; state <= ConnectionLost; state++ {
+		if state.String() == s {
+			return state, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,158 @@
+package filenextra
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/socketio"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidCredentials = errors.New("filen credentials were rejected")
+
+// LoginFunc logs in to Filen. It is called for every login attempt, so it
+// has to generate a fresh two factor code each time.
+type LoginFunc func(ctx context.Context) (*filen.Filen, error)
+
+// credentialErrorCodes are the API codes of logins refused because of the
+// credentials. The SDK only reports them as part of the error message.
+var credentialErrorCodes = []string{
+	"email_or_password_wrong",
+	"enter_2fa",
+	"wrong_2fa",
+	"account_not_found",
+}
+
+// IsCredentialError reports whether a login failed because the credentials
+// were refused, as opposed to the API being unreachable.
+func IsCredentialError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrInvalidCredentials) {
+		return true
+	}
+	message := err.Error()
+	for _, code := range credentialErrorCodes {
+		if strings.Contains(message, code) {
+			return true
+		}
+	}
+	return false
+}
+
+type SessionConfig struct {
+	// Backoff is the delay between login attempts. Defaults to
+	// socketio.DefaultBackoff.
+	Backoff socketio.Backoff
+	// AlertAfter is the number of refused logins in a row after which the
+	// credentials are considered invalid. A single refusal may be a two
+	// factor code from the edge of its window. Defaults to 3.
+	AlertAfter int
+	// OnAlert is called once the credentials are considered invalid. Logins
+	// are still retried, at the maximum backoff.
+	OnAlert func(err error)
+}
+
+// Session holds the current Filen client and replaces it by logging in
+// again when the API key was revoked or rotated.
+type Session struct {
+	login LoginFunc
+	cfg   SessionConfig
+
+	// reloginMu makes concurrent Relogin calls share one login.
+	reloginMu sync.Mutex
+
+	mu       sync.Mutex
+	client   *filen.Filen
+	onChange []func(*filen.Filen)
+}
+
+func NewSession(client *filen.Filen, login LoginFunc, cfg SessionConfig) *Session {
+	if cfg.Backoff == (socketio.Backoff{}) {
+		cfg.Backoff = socketio.DefaultBackoff
+	}
+	if cfg.AlertAfter <= 0 {
+		cfg.AlertAfter = 3
+	}
+	return &Session{
+		login:  login,
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+func (s *Session) Client() *filen.Filen {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// OnClientChange registers f to be called with every new client.
+func (s *Session) OnClientChange(f func(*filen.Filen)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = append(s.onChange, f)
+}
+
+// Relogin replaces stale, the client whose API key was refused, and returns
+// the new client. If the client was already replaced, the current one is
+// returned right away. Failed logins are retried until ctx is done.
+func (s *Session) Relogin(ctx context.Context, stale *filen.Filen) (*filen.Filen, error) {
+	s.reloginMu.Lock()
+	defer s.reloginMu.Unlock()
+
+	if current := s.Client(); current != stale {
+		return current, nil
+	}
+
+	refused := 0
+	for attempt := 0; ; attempt++ {
+		client, err := s.login(ctx)
+		if err == nil {
+			s.setClient(client)
+			log.Info().Msg("Logged in to Filen again")
+			return client, nil
+		}
+
+		delay := s.cfg.Backoff.Delay(attempt)
+		if IsCredentialError(err) {
+			refused++
+			if refused == s.cfg.AlertAfter {
+				log.Error().Err(err).Bool("alert", true).Msgf("Filen refused the credentials %d times in a row", refused)
+				if s.cfg.OnAlert != nil {
+					s.cfg.OnAlert(err)
+				}
+			}
+			if refused >= s.cfg.AlertAfter {
+				delay = s.cfg.Backoff.Max
+			}
+		} else {
+			refused = 0
+		}
+		log.Warn().Err(err).Msgf("Filen login failed, retrying in %s", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Session) setClient(client *filen.Filen) {
+	s.mu.Lock()
+	s.client = client
+	onChange := s.onChange
+	s.mu.Unlock()
+
+	for _, f := range onChange {
+		f(client)
+	}
+}
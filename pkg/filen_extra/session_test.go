@@ -0,0 +1,88 @@
+package filenextra_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filentest"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/socketio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionReloginIsShared(t *testing.T) {
+	srv, client := login(t)
+	session := filenextra.NewSession(client, func(ctx context.Context) (*filen.Filen, error) {
+		return filen.New(srv.Context(ctx), account.Email, account.Password, account.TwoFactorCode)
+	}, filenextra.SessionConfig{})
+
+	changes := make(chan *filen.Filen, 4)
+	session.OnClientChange(func(c *filen.Filen) { changes <- c })
+
+	var wg sync.WaitGroup
+	clients := make([]*filen.Filen, 3)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			clients[i], err = session.Relogin(context.Background(), client)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, srv.Logins())
+	assert.Len(t, changes, 1)
+	for _, c := range clients {
+		assert.Same(t, session.Client(), c)
+	}
+}
+
+func TestSessionAlertsOnRefusedCredentials(t *testing.T) {
+	srv, client := login(t)
+
+	alerts := make(chan error, 1)
+	session := filenextra.NewSession(client, func(ctx context.Context) (*filen.Filen, error) {
+		return filen.New(srv.Context(ctx), account.Email, "wrong password", account.TwoFactorCode)
+	}, filenextra.SessionConfig{
+		Backoff:    socketio.Backoff{Initial: time.Millisecond, Max: 10 * time.Millisecond},
+		AlertAfter: 2,
+		OnAlert:    func(err error) { alerts <- err },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		_, err := session.Relogin(ctx, client)
+		result <- err
+	}()
+
+	select {
+	case err := <-alerts:
+		assert.True(t, filenextra.IsCredentialError(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert raised")
+	}
+
+	// logins are still retried until given up
+	cancel()
+	assert.ErrorIs(t, <-result, context.Canceled)
+	assert.Same(t, client, session.Client())
+}
+
+func TestIsCredentialError(t *testing.T) {
+	srv := filentest.Start(t, account)
+	_, err := filen.New(srv.Context(context.Background()), account.Email, account.Password, "000000")
+	assert.Error(t, err)
+	assert.True(t, filenextra.IsCredentialError(err))
+
+	assert.False(t, filenextra.IsCredentialError(nil))
+	assert.False(t, filenextra.IsCredentialError(errors.New("connection refused")))
+	assert.True(t, filenextra.IsCredentialError(filenextra.ErrInvalidCredentials))
+}
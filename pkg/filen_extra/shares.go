@@ -0,0 +1,72 @@
+package filenextra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/client"
+)
+
+// sharedInRoot is the uuid the API lists the folders shared with the user
+// under.
+const sharedInRoot = "shared-in"
+
+type v3SharedInItem struct {
+	UUID   string `json:"uuid"`
+	Parent string `json:"parent"`
+}
+
+type v3SharedInResponse struct {
+	Uploads []v3SharedInItem `json:"uploads"`
+	Folders []v3SharedInItem `json:"folders"`
+}
+
+func getV3SharedIn(ctx context.Context, c *filen.Filen, uuid string) (*v3SharedInResponse, error) {
+	var res v3SharedInResponse
+	_, err := c.Client.RequestData(ctx, "POST", client.GatewayURL("/v3/shared/in"), struct {
+		UUID string `json:"uuid"`
+	}{UUID: uuid}, &res)
+	if err != nil {
+		return nil, fmt.Errorf("list shared folder %s: %w", uuid, err)
+	}
+	return &res, nil
+}
+
+// RegisterShares looks up the folders other users share with the user and
+// decrypts events about them with the user's private key. The items already
+// in them are added to the parent index, so that events about nested items
+// find the share. Folders the user shares or links are owned by the user,
+// whose events about them use the master keys.
+func (e *FilenEventListener) RegisterShares(ctx context.Context) error {
+	c, _ := e.client()
+	key := NewPrivateKeyDecrypter(&c.PrivateKey)
+
+	roots, err := getV3SharedIn(ctx, c, sharedInRoot)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots.Folders {
+		e.decrypter.SetShareKey(root.UUID, key)
+		e.index.AddRoot(root.UUID)
+
+		queue := []string{root.UUID}
+		for len(queue) > 0 {
+			folder := queue[0]
+			queue = queue[1:]
+
+			res, err := getV3SharedIn(ctx, c, folder)
+			if err != nil {
+				return err
+			}
+			for _, file := range res.Uploads {
+				e.index.Set(file.UUID, folder)
+			}
+			for _, sub := range res.Folders {
+				e.index.Set(sub.UUID, folder)
+				queue = append(queue, sub.UUID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package filenextra
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+)
+
+// OverflowPolicy decides what happens to events for a subscriber whose
+// buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the subscriber catches up. This holds up the
+	// socket and with it all other subscribers, but loses nothing.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the event that does not fit.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest buffered event to make room.
+	OverflowDropOldest
+)
+
+type SubscribeOptions struct {
+	// Events limits the subscription to these event names, e.g. "file-new"
+	// or "connection-state". Empty means all events.
+	Events []string
+	// Subtree limits the subscription to events about the folder with this
+	// UUID and the items below it, as far as the listener's ParentIndex can
+	// tell. Connection state changes are always delivered.
+	Subtree string
+	// Buffer is the number of events held for the subscriber. Defaults to
+	// 100.
+	Buffer int
+	// Overflow defaults to OverflowBlock.
+	Overflow OverflowPolicy
+}
+
+// Subscription is an independent stream of events of a FilenEventListener.
+type Subscription struct {
+	opts     SubscribeOptions
+	match    func(TypedEvent) bool
+	listener *FilenEventListener
+
+	ch        chan TypedEvent
+	done      chan struct{}
+	closeOnce sync.Once
+	dropped   atomic.Uint64
+
+	// sendMu is held while an event is handed to ch, so that ch is not
+	// closed during a send.
+	sendMu   sync.Mutex
+	chClosed bool
+}
+
+// Subscribe starts a new stream of events. Events sent before are not
+// delivered. A subtree subscription lists the subtree first, so that events
+// about items that existed before are matched as well.
+func (e *FilenEventListener) Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.Subtree != "" {
+		e.index.AddRoot(opts.Subtree)
+		e.seedIndex(opts.Subtree)
+	}
+	return e.subscribe(opts, nil)
+}
+
+// seedIndex adds the items below root to the index.
+func (e *FilenEventListener) seedIndex(root string) {
+	client, _ := e.client()
+	res, err := client.Client.PostV3DirDownload(e.ctx, root)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to list %s, events about items in it may be missed", root)
+		return
+	}
+	for _, folder := range res.Folders {
+		// the listing includes root itself
+		if folder.UUID != root {
+			e.index.Set(folder.UUID, folder.Parent)
+		}
+	}
+	for _, file := range res.Files {
+		e.index.Set(file.UUID, file.Parent)
+	}
+}
+
+func (e *FilenEventListener) subscribe(opts SubscribeOptions, match func(TypedEvent) bool) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 100
+	}
+	sub := &Subscription{
+		opts:     opts,
+		match:    match,
+		listener: e,
+		ch:       make(chan TypedEvent, opts.Buffer),
+		done:     make(chan struct{}),
+	}
+
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+	if e.closed {
+		sub.closeChannel()
+		return sub
+	}
+	e.subscriptions = append(e.subscriptions, sub)
+	return sub
+}
+
+// Next returns the next event. It returns false once the subscription or the
+// listener was closed.
+func (s *Subscription) Next() (TypedEvent, bool) {
+	evt, ok := <-s.ch
+	return evt, ok
+}
+
+// Events returns the channel the events are delivered on. It is closed
+// together with the subscription.
+func (s *Subscription) Events() <-chan TypedEvent {
+	return s.ch
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close ends the subscription. Buffered events are discarded.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		// unblocks a send waiting for the subscriber
+		close(s.done)
+
+		e := s.listener
+		e.subMu.Lock()
+		if i := slices.Index(e.subscriptions, s); i >= 0 {
+			e.subscriptions = slices.Delete(e.subscriptions, i, i+1)
+		}
+		e.subMu.Unlock()
+		s.closeChannel()
+	})
+}
+
+func (s *Subscription) closeChannel() {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if !s.chClosed {
+		s.chClosed = true
+		close(s.ch)
+	}
+}
+
+func (s *Subscription) wants(evt TypedEvent, index *ParentIndex, previousParent string) bool {
+	if len(s.opts.Events) > 0 && !slices.Contains(s.opts.Events, evt.Name) {
+		return false
+	}
+	if s.match != nil && !s.match(evt) {
+		return false
+	}
+	if s.opts.Subtree == "" || evt.Name == eventConnectionState {
+		return true
+	}
+
+	// an item moving out of the subtree is reported as well
+	uuid, parent := eventLocation(evt)
+	for _, candidate := range []string{uuid, parent, previousParent} {
+		if candidate != "" && index.InSubtree(candidate, s.opts.Subtree) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver hands evt to the subscriber. A blocking send ends when the
+// subscription or the listener is closed.
+func (s *Subscription) deliver(evt TypedEvent) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if s.chClosed {
+		return
+	}
+
+	switch s.opts.Overflow {
+	case OverflowDropNewest:
+		select {
+		case s.ch <- evt:
+		default:
+			s.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.ch <- evt:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- evt:
+		case <-s.done:
+		case <-s.listener.ctx.Done():
+		}
+	}
+}
+
+// publish hands evt to all subscribers that want it. The events are
+// delivered outside of subMu, so that a blocking subscriber does not hold up
+// subscribing and closing.
+func (e *FilenEventListener) publish(evt TypedEvent) {
+	uuid, _ := eventLocation(evt)
+	previousParent, _ := e.index.Parent(uuid)
+	e.index.observe(evt)
+
+	e.subMu.Lock()
+	var targets []*Subscription
+	for _, sub := range e.subscriptions {
+		if sub.wants(evt, e.index, previousParent) {
+			targets = append(targets, sub)
+		}
+	}
+	e.subMu.Unlock()
+
+	for _, sub := range targets {
+		sub.deliver(evt)
+	}
+}
+
+func (e *FilenEventListener) closeSubscriptions() {
+	e.subMu.Lock()
+	e.closed = true
+	subs := e.subscriptions
+	e.subscriptions = nil
+	e.subMu.Unlock()
+
+	for _, sub := range subs {
+		sub.closeChannel()
+	}
+}
+
+// On subscribes f to the events with data of type T, e.g.
+// On(listener, SubscribeOptions{}, func(e *EventSocketFileNew) {...}). f is
+// called on a goroutine of its own, one event at a time.
+func On[T any](e *FilenEventListener, opts SubscribeOptions, f func(*T)) *Subscription {
+	sub := e.subscribe(opts, func(evt TypedEvent) bool {
+		_, ok := evt.Data.(*T)
+		return ok
+	})
+	go func() {
+		for evt := range sub.ch {
+			f(evt.Data.(*T))
+		}
+	}()
+	return sub
+}
+
+func (e *FilenEventListener) OnFileNew(f func(*EventSocketFileNew)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFileRename(f func(*EventSocketFileRename)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFileMove(f func(*EventSocketFileMove)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFileTrash(f func(*EventSocketFileTrash)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFileRestore(f func(*EventSocketFileRestore)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFileDeletedPermanent(f func(*EventSocketFileDeletedPermanent)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFolderSubCreated(f func(*EventSocketFolderSubCreated)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFolderRename(f func(*EventSocketFolderRename)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFolderMove(f func(*EventSocketFolderMove)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFolderTrash(f func(*EventSocketFolderTrash)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnFolderRestore(f func(*EventSocketFolderRestore)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
+
+func (e *FilenEventListener) OnConnectionState(f func(*EventConnectionState)) *Subscription {
+	return On(e, SubscribeOptions{}, f)
+}
@@ -0,0 +1,222 @@
+package filenextra_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filentest"
+	"github.com/stretchr/testify/assert"
+)
+
+func startListener(t *testing.T) (*filentest.Server, *filenextra.FilenEventListener) {
+	srv, client := login(t)
+	events, err := filenextra.NewFilenEvents(srv.SocketURL(), client, http.Header{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	events.Start()
+	t.Cleanup(func() { _ = events.Close() })
+	waitForState(t, events, filenextra.ConnectionReady)
+	return srv, events
+}
+
+func nextFromSubscription(t *testing.T, sub *filenextra.Subscription) filenextra.TypedEvent {
+	select {
+	case evt, ok := <-sub.Events():
+		assert.True(t, ok)
+		return evt
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return filenextra.TypedEvent{}
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	srv, events := startListener(t)
+	events.CloseNextEvent()
+
+	slow := events.Subscribe(filenextra.SubscribeOptions{Buffer: 1, Overflow: filenextra.OverflowDropNewest})
+	names := make(chan string, 10)
+	events.OnFileNew(func(e *filenextra.EventSocketFileNew) { names <- e.Meta.Name })
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		_, err := srv.CreateFile(srv.BaseFolderUUID(), name, []byte(name), time.UnixMilli(1000))
+		assert.NoError(t, err)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		select {
+		case received := <-names:
+			assert.Equal(t, name, received)
+		case <-time.After(5 * time.Second):
+			t.Fatal("callback not called")
+		}
+	}
+
+	assert.Equal(t, uint64(2), slow.Dropped())
+	assert.Equal(t, "file-new", nextFromSubscription(t, slow).Name)
+}
+
+func TestSubscriptionDropOldest(t *testing.T) {
+	srv, events := startListener(t)
+
+	latest := events.Subscribe(filenextra.SubscribeOptions{
+		Events:   []string{"folder-sub-created"},
+		Buffer:   2,
+		Overflow: filenextra.OverflowDropOldest,
+	})
+	all := events.Subscribe(filenextra.SubscribeOptions{Events: []string{"folder-sub-created"}})
+
+	for _, name := range []string{"d1", "d2", "d3", "d4"} {
+		srv.CreateDir(srv.BaseFolderUUID(), name)
+	}
+	for range 4 {
+		nextFromSubscription(t, all)
+	}
+
+	assert.Equal(t, uint64(2), latest.Dropped())
+	for _, name := range []string{"d3", "d4"} {
+		evt := nextFromSubscription(t, latest)
+		assert.Equal(t, name, evt.Data.(*filenextra.EventSocketFolderSubCreated).Name.Name)
+	}
+}
+
+func TestSubscriptionSubtree(t *testing.T) {
+	srv, events := startListener(t)
+	a := srv.AddDir(srv.BaseFolderUUID(), "a")
+	// found through the listing when subscribing
+	old := srv.AddDir(a, "old")
+
+	sub := events.Subscribe(filenextra.SubscribeOptions{
+		Events:  []string{"file-new", "file-move", "folder-sub-created"},
+		Subtree: a,
+	})
+
+	b := srv.CreateDir(srv.BaseFolderUUID(), "b")
+	sub1 := srv.CreateDir(a, "sub")
+	_, err := srv.CreateFile(b, "outside.txt", []byte("x"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	_, err = srv.CreateFile(old, "in-old.txt", []byte("x"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	inside, err := srv.CreateFile(sub1, "inside.txt", []byte("x"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	assert.NoError(t, srv.MoveFile(inside, b))
+	_, err = srv.CreateFile(b, "outside2.txt", []byte("x"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	// a marker to know all events before were handled
+	srv.CreateDir(a, "last")
+
+	var received []string
+	for {
+		evt := nextFromSubscription(t, sub)
+		received = append(received, evt.Name)
+		if e, ok := evt.Data.(*filenextra.EventSocketFolderSubCreated); ok && e.Name.Name == "last" {
+			break
+		}
+	}
+	// the move out of the subtree is reported, the files in b are not
+	assert.Equal(t, []string{"folder-sub-created", "file-new", "file-new", "file-move", "folder-sub-created"}, received)
+}
+
+func TestTrashRemovesFromIndex(t *testing.T) {
+	srv, events := startListener(t)
+	all := events.Subscribe(filenextra.SubscribeOptions{Events: []string{"file-trash", "folder-trash"}, Subtree: srv.BaseFolderUUID()})
+
+	a := srv.CreateDir(srv.BaseFolderUUID(), "a")
+	sub := srv.CreateDir(a, "sub")
+	inSub, err := srv.CreateFile(sub, "in-sub.txt", []byte("x"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	file, err := srv.CreateFile(srv.BaseFolderUUID(), "file.txt", []byte("x"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, ok := events.Index().Parent(file)
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, srv.TrashFile(file))
+	assert.Equal(t, "file-trash", nextFromSubscription(t, all).Name)
+	_, ok := events.Index().Parent(file)
+	assert.False(t, ok)
+
+	assert.NoError(t, srv.TrashDir(a))
+	assert.Equal(t, "folder-trash", nextFromSubscription(t, all).Name)
+	for _, uuid := range []string{a, sub, inSub} {
+		_, ok := events.Index().Parent(uuid)
+		assert.False(t, ok, uuid)
+	}
+}
+
+func TestIndexKeepsSubscribedSubtrees(t *testing.T) {
+	srv, events := startListener(t)
+	a := srv.CreateDir(srv.BaseFolderUUID(), "a")
+	sub := events.Subscribe(filenextra.SubscribeOptions{Events: []string{"folder-sub-created"}, Subtree: a})
+
+	inA := srv.CreateDir(a, "in-a")
+	outside := srv.CreateDir(srv.BaseFolderUUID(), "outside")
+	inside, err := srv.CreateFile(inA, "inside.txt", []byte("x"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	assert.Equal(t, inA, nextFromSubscription(t, sub).Data.(*filenextra.EventSocketFolderSubCreated).UUID)
+	assert.Eventually(t, func() bool {
+		_, ok := events.Index().Parent(inside)
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	_, ok := events.Index().Parent(outside)
+	assert.False(t, ok)
+
+	// moving out of the subtree drops the folder and its items
+	assert.NoError(t, srv.MoveDir(inA, outside))
+	assert.Eventually(t, func() bool {
+		_, ok := events.Index().Parent(inside)
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+	_, ok = events.Index().Parent(inA)
+	assert.False(t, ok)
+}
+
+func TestBlockedSubscriberDoesNotHoldUpSubscribe(t *testing.T) {
+	srv, events := startListener(t)
+	first := events.Subscribe(filenextra.SubscribeOptions{Events: []string{"folder-sub-created"}})
+	blocked := events.Subscribe(filenextra.SubscribeOptions{Events: []string{"folder-sub-created"}, Buffer: 1})
+
+	// the second event waits for blocked
+	srv.CreateDir(srv.BaseFolderUUID(), "d1")
+	srv.CreateDir(srv.BaseFolderUUID(), "d2")
+	nextFromSubscription(t, first)
+	nextFromSubscription(t, first)
+
+	subscribed := make(chan *filenextra.Subscription)
+	go func() { subscribed <- events.Subscribe(filenextra.SubscribeOptions{}) }()
+	select {
+	case sub := <-subscribed:
+		sub.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe blocked")
+	}
+
+	// closing blocked lets the events through to the others
+	srv.CreateDir(srv.BaseFolderUUID(), "d3")
+	blocked.Close()
+	evt := nextFromSubscription(t, first)
+	assert.Equal(t, "d3", evt.Data.(*filenextra.EventSocketFolderSubCreated).Name.Name)
+}
+
+func TestSubscriptionsCloseWithListener(t *testing.T) {
+	_, events := startListener(t)
+	sub := events.Subscribe(filenextra.SubscribeOptions{})
+	closed := events.Subscribe(filenextra.SubscribeOptions{})
+	closed.Close()
+	closed.Close()
+
+	_, ok := closed.Next()
+	assert.False(t, ok)
+
+	assert.NoError(t, events.Close())
+	for {
+		if _, ok := sub.Next(); !ok {
+			break
+		}
+	}
+	_, ok = events.Subscribe(filenextra.SubscribeOptions{}).Next()
+	assert.False(t, ok)
+}
@@ -0,0 +1,281 @@
+package filentest
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/types"
+)
+
+const (
+	region = "de-1"
+	bucket = "filen-1"
+)
+
+type file struct {
+	uuid         string
+	parent       string
+	name         string
+	mime         string
+	content      []byte
+	lastModified time.Time
+	key          *crypto.EncryptionKey
+	region       string
+	bucket       string
+	chunks       [][]byte
+}
+
+type dir struct {
+	uuid   string
+	parent string
+	name   string
+}
+
+// AddFile stores a file without emitting an event, for setting up the
+// initial drive. The content is split into chunks and encrypted with a fresh
+// file key.
+func (s *Server) AddFile(parentUuid, name string, content []byte, modTime time.Time) (string, error) {
+	f, err := newFile(parentUuid, name, content, modTime)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[f.uuid] = f
+	return f.uuid, nil
+}
+
+// AddDir stores a directory without emitting an event.
+func (s *Server) AddDir(parentUuid, name string) string {
+	d := &dir{uuid: newUuid(), parent: parentUuid, name: name}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirs[d.uuid] = d
+	return d.uuid
+}
+
+func (s *Server) CreateFile(parentUuid, name string, content []byte, modTime time.Time) (string, error) {
+	f, err := newFile(parentUuid, name, content, modTime)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[f.uuid] = f
+	s.emit("file-new", s.fileEventData(f))
+	return f.uuid, nil
+}
+
+func (s *Server) CreateDir(parentUuid, name string) string {
+	d := &dir{uuid: newUuid(), parent: parentUuid, name: name}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirs[d.uuid] = d
+	s.emit("folder-sub-created", s.dirEventData(d))
+	return d.uuid
+}
+
+func (s *Server) RenameFile(uuid, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[uuid]
+	if !ok {
+		return fmt.Errorf("file %s not found", uuid)
+	}
+	f.name = name
+	s.emit("file-rename", map[string]any{
+		"uuid":     f.uuid,
+		"metadata": s.fileMetadata(f),
+	})
+	return nil
+}
+
+func (s *Server) MoveFile(uuid, parentUuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[uuid]
+	if !ok {
+		return fmt.Errorf("file %s not found", uuid)
+	}
+	f.parent = parentUuid
+	s.emit("file-move", s.fileEventData(f))
+	return nil
+}
+
+func (s *Server) TrashFile(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.files[uuid]; !ok {
+		return fmt.Errorf("file %s not found", uuid)
+	}
+	delete(s.files, uuid)
+	s.emit("file-trash", map[string]any{"uuid": uuid})
+	return nil
+}
+
+func (s *Server) DeleteFilePermanent(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.files[uuid]; !ok {
+		return fmt.Errorf("file %s not found", uuid)
+	}
+	delete(s.files, uuid)
+	s.emit("file-deleted-permanent", map[string]any{"uuid": uuid})
+	return nil
+}
+
+func (s *Server) RenameDir(uuid, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.dirs[uuid]
+	if !ok {
+		return fmt.Errorf("directory %s not found", uuid)
+	}
+	d.name = name
+	s.emit("folder-rename", map[string]any{
+		"uuid": d.uuid,
+		"name": s.dirMetadata(d),
+	})
+	return nil
+}
+
+func (s *Server) MoveDir(uuid, parentUuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.dirs[uuid]
+	if !ok {
+		return fmt.Errorf("directory %s not found", uuid)
+	}
+	d.parent = parentUuid
+	s.emit("folder-move", s.dirEventData(d))
+	return nil
+}
+
+// TrashDir removes a directory together with everything below it.
+func (s *Server) TrashDir(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.dirs[uuid]
+	if !ok {
+		return fmt.Errorf("directory %s not found", uuid)
+	}
+	for fileUuid, f := range s.files {
+		if s.isBelow(f.parent, uuid) {
+			delete(s.files, fileUuid)
+		}
+	}
+	for dirUuid, sub := range s.dirs {
+		if dirUuid != uuid && s.isBelow(sub.parent, uuid) {
+			delete(s.dirs, dirUuid)
+		}
+	}
+	delete(s.dirs, uuid)
+	s.emit("folder-trash", map[string]any{
+		"uuid":   uuid,
+		"parent": d.parent,
+	})
+	return nil
+}
+
+// Emit sends an arbitrary event to all authenticated sockets without
+// touching the drive.
+func (s *Server) Emit(name string, data any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emit(name, data)
+}
+
+func newFile(parentUuid, name string, content []byte, modTime time.Time) (*file, error) {
+	key, err := crypto.NewEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := mime.TypeByExtension(path.Ext(name))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	f := &file{
+		uuid:         newUuid(),
+		parent:       parentUuid,
+		name:         name,
+		mime:         mimeType,
+		content:      content,
+		lastModified: modTime,
+		key:          key,
+		region:       region,
+		bucket:       bucket,
+	}
+	for offset := 0; offset < len(content); offset += filen.ChunkSize {
+		chunk := content[offset:min(offset+filen.ChunkSize, len(content))]
+		f.chunks = append(f.chunks, key.EncryptData(append([]byte(nil), chunk...)))
+	}
+	return f, nil
+}
+
+func (f *file) hash() string {
+	sum := sha512.Sum512(f.content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) fileMetadata(f *file) crypto.EncryptedString {
+	metadata, _ := json.Marshal(types.FileMetadata{
+		Name:         f.name,
+		Size:         len(f.content),
+		MimeType:     f.mime,
+		Key:          f.key.ToString(),
+		LastModified: types.IntFromMaybeString(f.lastModified.UnixMilli()),
+		Created:      int(f.lastModified.UnixMilli()),
+		Hash:         f.hash(),
+	})
+	return s.dek.EncryptMeta(string(metadata))
+}
+
+func (s *Server) dirMetadata(d *dir) crypto.EncryptedString {
+	metadata, _ := json.Marshal(types.DirectoryMetaData{Name: d.name})
+	return s.dek.EncryptMeta(string(metadata))
+}
+
+func (s *Server) fileEventData(f *file) map[string]any {
+	return map[string]any{
+		"parent":    f.parent,
+		"uuid":      f.uuid,
+		"metadata":  s.fileMetadata(f),
+		"rm":        "",
+		"timestamp": time.Now().UnixMilli(),
+		"chunks":    len(f.chunks),
+		"bucket":    f.bucket,
+		"region":    f.region,
+		"version":   3,
+		"favorited": 0,
+	}
+}
+
+func (s *Server) dirEventData(d *dir) map[string]any {
+	return map[string]any{
+		"uuid":      d.uuid,
+		"name":      s.dirMetadata(d),
+		"parent":    d.parent,
+		"timestamp": time.Now().Unix(),
+		"favorited": 0,
+	}
+}
@@ -0,0 +1,434 @@
+// Package filentest provides an in-process stand-in for the Filen backend.
+//
+// A Server serves the gateway endpoints used for login, listing, file info
+// and folders shared with the account, the egest endpoint for encrypted
+// chunks and the socket.io endpoint for events. Items are encrypted the way Filen stores them, so the real SDK
+// can be pointed at it with filenextra.WithEndpoints.
+package filentest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	gatewayPrefix = "/gateway"
+	egestPrefix   = "/egest"
+	socketPrefix  = "/socket"
+)
+
+// Account holds the credentials the server accepts. An empty TwoFactorCode
+// accepts any code.
+type Account struct {
+	Email         string
+	Password      string
+	TwoFactorCode string
+}
+
+type Server struct {
+	*httptest.Server
+
+	account      Account
+	salt         string
+	derivedPass  crypto.DerivedPassword
+	kek          *crypto.EncryptionKey
+	dek          *crypto.EncryptionKey
+	privateKey   string
+	publicKey    string
+	rsaPublic    *rsa.PublicKey
+	pingInterval int
+
+	mu       sync.Mutex
+	apiKey   string
+	logins   int
+	baseUUID string
+	files    map[string]*file
+	dirs     map[string]*dir
+	shared   map[string]*dir
+	sockets  map[*websocket.Conn]*socket
+}
+
+// NewServer starts a server with an empty drive for account.
+func NewServer(account Account) (*Server, error) {
+	salt := hex.EncodeToString(crypto.GenerateRandomBytes(32))
+	kek, derivedPass, err := crypto.DeriveKEKAndAuthFromPassword(account.Password, salt)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := crypto.NewEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		account:      account,
+		salt:         salt,
+		derivedPass:  derivedPass,
+		kek:          kek,
+		dek:          dek,
+		privateKey:   base64.StdEncoding.EncodeToString(privateKey),
+		publicKey:    base64.StdEncoding.EncodeToString(publicKey),
+		rsaPublic:    &rsaKey.PublicKey,
+		apiKey:       hex.EncodeToString(crypto.GenerateRandomBytes(32)),
+		pingInterval: 25000,
+		baseUUID:     newUuid(),
+		files:        make(map[string]*file),
+		dirs:         make(map[string]*dir),
+		shared:       make(map[string]*dir),
+		sockets:      make(map[*websocket.Conn]*socket),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+gatewayPrefix+"/v3/auth/info", s.handleAuthInfo)
+	mux.HandleFunc("POST "+gatewayPrefix+"/v3/login", s.handleLogin)
+	mux.HandleFunc("GET "+gatewayPrefix+"/v3/user/dek", s.authorized(s.handleUserDek))
+	mux.HandleFunc("GET "+gatewayPrefix+"/v3/user/keyPair/info", s.authorized(s.handleKeyPairInfo))
+	mux.HandleFunc("GET "+gatewayPrefix+"/v3/user/baseFolder", s.authorized(s.handleBaseFolder))
+	mux.HandleFunc("POST "+gatewayPrefix+"/v3/dir/download", s.authorized(s.handleDirDownload))
+	mux.HandleFunc("POST "+gatewayPrefix+"/v3/file", s.authorized(s.handleFile))
+	mux.HandleFunc("POST "+gatewayPrefix+"/v3/shared/in", s.authorized(s.handleSharedIn))
+	mux.HandleFunc("GET "+egestPrefix+"/{region}/{bucket}/{uuid}/{chunk}", s.authorized(s.handleChunk))
+	mux.HandleFunc("GET "+socketPrefix+"/socket.io/", s.handleSocket)
+	s.Server = httptest.NewServer(mux)
+
+	return s, nil
+}
+
+// Endpoints returns the endpoints to pass to filenextra.WithEndpoints.
+func (s *Server) Endpoints() filenextra.Endpoints {
+	return filenextra.Endpoints{
+		Gateway: []string{s.URL + gatewayPrefix},
+		Egest:   []string{s.URL + egestPrefix},
+		Ingest:  []string{s.URL + "/ingest"},
+	}
+}
+
+// SocketURL returns the base URL for filenextra.NewFilenEvents.
+func (s *Server) SocketURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + socketPrefix
+}
+
+// APIKey returns the key handed out on login.
+func (s *Server) APIKey() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKey
+}
+
+// RevokeAPIKey replaces the API key, as a revoked session or a rotated key
+// would, and drops the sockets authenticated with the old one. Clients have
+// to log in again.
+func (s *Server) RevokeAPIKey() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKey = hex.EncodeToString(crypto.GenerateRandomBytes(32))
+	for conn := range s.sockets {
+		_ = conn.Close()
+	}
+}
+
+// Logins returns the number of successful logins.
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// BaseFolderUUID returns the uuid of the drive's root directory.
+func (s *Server) BaseFolderUUID() string {
+	return s.baseUUID
+}
+
+// Close disconnects all sockets and shuts the server down.
+func (s *Server) Close() {
+	s.DisconnectSockets()
+	s.Server.Close()
+}
+
+type apiResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Code    string `json:"code"`
+	Data    any    `json:"data"`
+}
+
+func writeData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(apiResponse{Status: true, Message: "", Code: "", Data: data})
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(apiResponse{Status: false, Message: message, Code: code})
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request, dest any) bool {
+	err := json.NewDecoder(r.Body).Decode(dest)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_params", err.Error())
+		return false
+	}
+	return true
+}
+
+func (s *Server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.APIKey() {
+			writeError(w, http.StatusUnauthorized, "api_key_not_found", "Invalid API key.")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) handleAuthInfo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	writeData(w, map[string]any{
+		"email":       req.Email,
+		"authVersion": 3,
+		"salt":        s.salt,
+	})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email         string `json:"email"`
+		Password      string `json:"password"`
+		TwoFactorCode string `json:"twoFactorCode"`
+		AuthVersion   int    `json:"authVersion"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	if req.Email != s.account.Email || req.Password != string(s.derivedPass) {
+		writeError(w, http.StatusUnauthorized, "email_or_password_wrong", "Invalid email or password.")
+		return
+	}
+	if s.account.TwoFactorCode != "" && req.TwoFactorCode != s.account.TwoFactorCode {
+		writeError(w, http.StatusUnauthorized, "enter_2fa", "Invalid two factor authentication code.")
+		return
+	}
+
+	s.mu.Lock()
+	s.logins++
+	apiKey := s.apiKey
+	s.mu.Unlock()
+
+	writeData(w, map[string]any{
+		"apiKey":     apiKey,
+		"publicKey":  s.publicKey,
+		"privateKey": s.dek.EncryptMeta(s.privateKey),
+		"dek":        s.kek.EncryptMeta(s.dek.ToString()),
+	})
+}
+
+func (s *Server) handleUserDek(w http.ResponseWriter, r *http.Request) {
+	writeData(w, map[string]any{
+		"dek": s.kek.EncryptMeta(s.dek.ToString()),
+	})
+}
+
+func (s *Server) handleKeyPairInfo(w http.ResponseWriter, r *http.Request) {
+	writeData(w, map[string]any{
+		"publicKey":  s.publicKey,
+		"privateKey": s.dek.EncryptMeta(s.privateKey),
+	})
+}
+
+func (s *Server) handleBaseFolder(w http.ResponseWriter, r *http.Request) {
+	writeData(w, map[string]any{
+		"uuid": s.baseUUID,
+	})
+}
+
+func (s *Server) handleDirDownload(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UUID string `json:"uuid"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.UUID != s.baseUUID && s.dirs[req.UUID] == nil {
+		writeError(w, http.StatusNotFound, "folder_not_found", "Folder not found.")
+		return
+	}
+
+	// the listed directory itself comes first, with parent "base"
+	folders := []map[string]any{{
+		"uuid":      req.UUID,
+		"name":      s.dek.EncryptMeta(`{"name":"Cloud Drive"}`),
+		"parent":    "base",
+		"timestamp": 0,
+	}}
+	files := []map[string]any{}
+	for _, d := range s.dirs {
+		if s.isBelow(d.parent, req.UUID) {
+			folders = append(folders, map[string]any{
+				"uuid":      d.uuid,
+				"name":      s.dirMetadata(d),
+				"parent":    d.parent,
+				"timestamp": 0,
+				"favorited": false,
+			})
+		}
+	}
+	for _, f := range s.files {
+		if s.isBelow(f.parent, req.UUID) {
+			files = append(files, map[string]any{
+				"uuid":      f.uuid,
+				"bucket":    f.bucket,
+				"region":    f.region,
+				"chunks":    len(f.chunks),
+				"parent":    f.parent,
+				"metadata":  s.fileMetadata(f),
+				"version":   3,
+				"size":      fmt.Sprint(len(f.content)),
+				"timestamp": f.lastModified.Unix(),
+			})
+		}
+	}
+
+	writeData(w, map[string]any{
+		"files":   files,
+		"folders": folders,
+	})
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UUID string `json:"uuid"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[req.UUID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "file_not_found", "File not found.")
+		return
+	}
+
+	writeData(w, map[string]any{
+		"uuid":          f.uuid,
+		"region":        f.region,
+		"bucket":        f.bucket,
+		"nameEncrypted": s.dek.EncryptMeta(f.name),
+		"nameHashed":    "",
+		"sizeEncrypted": s.dek.EncryptMeta(fmt.Sprint(len(f.content))),
+		"mimeEncrypted": s.dek.EncryptMeta(f.mime),
+		"metadata":      s.fileMetadata(f),
+		"size":          len(f.content),
+		"parent":        f.parent,
+		"versioned":     false,
+		"trash":         false,
+		"version":       3,
+	})
+}
+
+func (s *Server) handleChunk(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	f, ok := s.files[r.PathValue("uuid")]
+	s.mu.Unlock()
+
+	if !ok || f.region != r.PathValue("region") || f.bucket != r.PathValue("bucket") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var index int
+	_, err := fmt.Sscan(r.PathValue("chunk"), &index)
+	if err != nil || index < 0 || index >= len(f.chunks) {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(f.chunks[index])
+}
+
+// isBelow reports whether parent is uuid or one of its descendants.
+func (s *Server) isBelow(parent, uuid string) bool {
+	for {
+		if parent == uuid {
+			return true
+		}
+		d, ok := s.dirs[parent]
+		if !ok {
+			return false
+		}
+		parent = d.parent
+	}
+}
+
+func newUuid() string {
+	b := crypto.GenerateRandomBytes(16)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// Start starts a server for the duration of t.
+func Start(t testing.TB, account Account) *Server {
+	t.Helper()
+
+	s, err := NewServer(account)
+	if err != nil {
+		t.Fatalf("starting fake Filen server: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Context returns a copy of ctx that points the SDK clients created with it,
+// e.g. by filen.New, at s.
+func (s *Server) Context(ctx context.Context) context.Context {
+	ctx, err := filenextra.WithEndpoints(ctx, s.Endpoints())
+	if err != nil {
+		panic(err)
+	}
+	return ctx
+}
@@ -0,0 +1,94 @@
+package filentest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/types"
+)
+
+// sharedInRoot is the parent of the folders shared at the top level.
+const sharedInRoot = "shared-in"
+
+// AddSharedDir stores a folder another user shares with the account,
+// without emitting an event. An empty parentUuid shares it at the top level.
+func (s *Server) AddSharedDir(parentUuid, name string) string {
+	if parentUuid == "" {
+		parentUuid = sharedInRoot
+	}
+	d := &dir{uuid: newUuid(), parent: parentUuid, name: name}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shared[d.uuid] = d
+	return d.uuid
+}
+
+// CreateSharedDir stores a folder inside a share and emits its event, with
+// the name encrypted with the account's public key.
+func (s *Server) CreateSharedDir(parentUuid, name string) (string, error) {
+	d := &dir{uuid: newUuid(), parent: parentUuid, name: name}
+	metadata, err := s.sharedMetadata(d)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shared[d.uuid] = d
+	s.emit("folder-sub-created", map[string]any{
+		"uuid":      d.uuid,
+		"name":      metadata,
+		"parent":    d.parent,
+		"timestamp": 0,
+		"favorited": 0,
+	})
+	return d.uuid, nil
+}
+
+func (s *Server) sharedMetadata(d *dir) (crypto.EncryptedString, error) {
+	metadata, _ := json.Marshal(types.DirectoryMetaData{Name: d.name})
+	return crypto.PublicEncrypt(s.rsaPublic, string(metadata))
+}
+
+func (s *Server) handleSharedIn(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UUID string `json:"uuid"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.UUID != sharedInRoot && s.shared[req.UUID] == nil {
+		writeError(w, http.StatusNotFound, "folder_not_found", "Folder not found.")
+		return
+	}
+
+	folders := []map[string]any{}
+	for _, d := range s.shared {
+		if d.parent != req.UUID {
+			continue
+		}
+		metadata, err := s.sharedMetadata(d)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		folders = append(folders, map[string]any{
+			"uuid":        d.uuid,
+			"parent":      d.parent,
+			"metadata":    metadata,
+			"sharerEmail": "sharer@example.com",
+			"timestamp":   0,
+		})
+	}
+
+	writeData(w, map[string]any{
+		"uploads": []map[string]any{},
+		"folders": folders,
+	})
+}
@@ -0,0 +1,152 @@
+package filentest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// socket is a socket.io client connected with Engine.IO protocol 3.
+type socket struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	authed  bool
+}
+
+var upgrader = websocket.Upgrader{}
+
+func (s *Server) handleSocket(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("EIO") != "3" || r.URL.Query().Get("transport") != "websocket" {
+		http.Error(w, "unsupported transport", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	sock := &socket{conn: conn}
+
+	s.mu.Lock()
+	s.sockets[conn] = sock
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.sockets, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	handshake, _ := json.Marshal(map[string]any{
+		"sid":          newUuid(),
+		"upgrades":     []string{},
+		"pingInterval": s.pingInterval,
+		"pingTimeout":  60000,
+	})
+	if sock.write("0"+string(handshake)) != nil || sock.write("40") != nil {
+		return
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if !s.handleSocketMessage(sock, string(message)) {
+			return
+		}
+	}
+}
+
+func (s *Server) handleSocketMessage(sock *socket, message string) bool {
+	switch {
+	case message == "2":
+		return sock.write("3") == nil
+	case strings.HasPrefix(message, "42"):
+		var event []json.RawMessage
+		if json.Unmarshal([]byte(message[2:]), &event) != nil || len(event) == 0 {
+			return true
+		}
+		var name string
+		if json.Unmarshal(event[0], &name) != nil {
+			return true
+		}
+		return s.handleSocketEvent(sock, name, event[1:])
+	default:
+		return true
+	}
+}
+
+func (s *Server) handleSocketEvent(sock *socket, name string, args []json.RawMessage) bool {
+	switch name {
+	case "authed":
+		s.mu.Lock()
+		authed := sock.authed
+		s.mu.Unlock()
+		return sock.writeEvent("authed", authed) == nil
+	case "auth":
+		var auth struct {
+			APIKey string `json:"apiKey"`
+		}
+		if len(args) == 0 || json.Unmarshal(args[0], &auth) != nil || auth.APIKey != s.APIKey() {
+			return sock.writeEvent("authFailed") == nil
+		}
+		s.mu.Lock()
+		sock.authed = true
+		s.mu.Unlock()
+		return sock.writeEvent("authSuccess") == nil
+	default:
+		return true
+	}
+}
+
+// AuthedSockets returns the number of connected and authenticated sockets.
+// Events are only delivered to those.
+func (s *Server) AuthedSockets() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, sock := range s.sockets {
+		if sock.authed {
+			n++
+		}
+	}
+	return n
+}
+
+// DisconnectSockets drops all socket connections, as a network failure
+// would. Events emitted until the clients reconnect are lost.
+func (s *Server) DisconnectSockets() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.sockets {
+		_ = conn.Close()
+	}
+}
+
+// emit must be called with s.mu held.
+func (s *Server) emit(name string, data any) {
+	for _, sock := range s.sockets {
+		if sock.authed {
+			_ = sock.writeEvent(name, data)
+		}
+	}
+}
+
+func (sock *socket) writeEvent(name string, args ...any) error {
+	payload, err := json.Marshal(append([]any{name}, args...))
+	if err != nil {
+		return err
+	}
+	return sock.write("42" + string(payload))
+}
+
+func (sock *socket) write(message string) error {
+	sock.writeMu.Lock()
+	defer sock.writeMu.Unlock()
+	return sock.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
@@ -0,0 +1,52 @@
+package mirror_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+// Filen sends lastModified in milliseconds.
+func TestEventFileNewModTime(t *testing.T) {
+	store := remote.NewMemoryStore()
+	syncDir := startMirror(t, store)
+
+	modTime := time.UnixMilli(1700000000123)
+	store.CreateFile(store.BaseFolderUUID(), "file1.txt", []byte("hello"), modTime)
+
+	path := filepath.Join(syncDir, "file1.txt")
+	assertFileContent(t, path, "hello")
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		info, err := os.Stat(path)
+		assert.NoError(c, err)
+		assert.True(c, info.ModTime().Equal(modTime), "mod time %s", info.ModTime())
+	}, waitFor, tick)
+}
+
+// A local file with the content announced in the event is kept.
+func TestEventFileNewKeepsSameContent(t *testing.T) {
+	mem := remote.NewMemoryStore()
+	store := &countingStore{MemoryStore: mem}
+	syncDir := t.TempDir()
+	m := mirror.NewFilenMirror(store, mem, mirror.FilenMirrorConfig{SyncDir: syncDir})
+	m.Start()
+	t.Cleanup(func() { _ = mem.Close() })
+	assert.Eventually(t, func() bool { return m.Status().LastSync != nil }, waitFor, tick)
+
+	path := filepath.Join(syncDir, "file1.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0o644))
+	modTime := time.UnixMilli(2000)
+	mem.CreateFile(mem.BaseFolderUUID(), "file1.txt", []byte("hello"), modTime)
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		info, err := os.Stat(path)
+		assert.NoError(c, err)
+		assert.True(c, info.ModTime().Equal(modTime), "mod time %s", info.ModTime())
+	}, waitFor, tick)
+	assert.Equal(t, int32(0), store.downloads.Load())
+}
@@ -0,0 +1,85 @@
+package mirror_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filentest"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+var e2eAccount = filentest.Account{
+	Email:    "user@example.com",
+	Password: "password",
+}
+
+// startFilenMirror runs the mirror against a fake Filen server, going through
+// the SDK login, the gateway and egest endpoints and the socket.
+func startFilenMirror(t *testing.T, setup func(srv *filentest.Server)) (*filentest.Server, string) {
+	srv := filentest.Start(t, e2eAccount)
+	if setup != nil {
+		setup(srv)
+	}
+
+	client, err := filen.New(srv.Context(context.Background()), e2eAccount.Email, e2eAccount.Password, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	events, err := filenextra.NewFilenEvents(srv.SocketURL(), client, http.Header{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	syncDir := t.TempDir()
+	m := mirror.NewFilenMirror(remote.NewFilenStore(client), events, mirror.FilenMirrorConfig{
+		SyncDir: syncDir,
+	})
+	m.Start()
+
+	assert.Eventually(t, func() bool { return srv.AuthedSockets() == 1 }, waitFor, tick)
+	return srv, syncDir
+}
+
+func TestE2EFullSync(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), filen.ChunkSize+1)
+	_, syncDir := startFilenMirror(t, func(srv *filentest.Server) {
+		dir := srv.AddDir(srv.BaseFolderUUID(), "dir1")
+		_, err := srv.AddFile(dir, "small.txt", []byte("hello"), time.UnixMilli(1000))
+		assert.NoError(t, err)
+		_, err = srv.AddFile(srv.BaseFolderUUID(), "large.bin", large, time.UnixMilli(2000))
+		assert.NoError(t, err)
+	})
+
+	assertFileContent(t, filepath.Join(syncDir, "dir1", "small.txt"), "hello")
+	assertFileContent(t, filepath.Join(syncDir, "large.bin"), string(large))
+}
+
+func TestE2EEvents(t *testing.T) {
+	var dir, file string
+	srv, syncDir := startFilenMirror(t, func(srv *filentest.Server) {
+		dir = srv.AddDir(srv.BaseFolderUUID(), "dir1")
+		var err error
+		file, err = srv.AddFile(srv.BaseFolderUUID(), "file1.txt", []byte("hello"), time.UnixMilli(1000))
+		assert.NoError(t, err)
+	})
+	assertFileContent(t, filepath.Join(syncDir, "file1.txt"), "hello")
+
+	_, err := srv.CreateFile(dir, "file2.txt", []byte("world"), time.UnixMilli(2000))
+	assert.NoError(t, err)
+	assertFileContent(t, filepath.Join(syncDir, "dir1", "file2.txt"), "world")
+
+	assert.NoError(t, srv.RenameFile(file, "renamed.txt"))
+	assertFileContent(t, filepath.Join(syncDir, "renamed.txt"), "hello")
+	assertNotExists(t, filepath.Join(syncDir, "file1.txt"))
+
+	assert.NoError(t, srv.TrashDir(dir))
+	assertNotExists(t, filepath.Join(syncDir, "dir1"))
+}
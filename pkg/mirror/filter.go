@@ -0,0 +1,82 @@
+package mirror
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// filter decides which paths are left out of the mirror. Patterns use the
+// syntax of path.Match. A pattern without a slash matches any name in the
+// path, e.g. "*.tmp" or ".cache"; a pattern with a slash matches the path
+// relative to the sync root, e.g. "photos/raw" or "/tmp". Everything below a matching
+// directory is left out as well.
+type filter struct {
+	patterns []string
+	names    []string
+	paths    []string
+}
+
+// ValidateExclude returns an error for the first malformed pattern.
+func ValidateExclude(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.Trim(pattern, "/") == "" {
+			return fmt.Errorf("empty exclude pattern %q", pattern)
+		}
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("exclude pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func newFilter(patterns []string) filter {
+	var f filter
+	for _, pattern := range patterns {
+		err := ValidateExclude([]string{pattern})
+		if err != nil {
+			log.Warn().Err(err).Msg("Ignoring exclude pattern")
+			continue
+		}
+		f.patterns = append(f.patterns, pattern)
+		if strings.Contains(pattern, "/") {
+			f.paths = append(f.paths, strings.Trim(pattern, "/"))
+		} else {
+			f.names = append(f.names, pattern)
+		}
+	}
+	return f
+}
+
+func (f filter) empty() bool {
+	return len(f.patterns) == 0
+}
+
+// excluded reports whether relPath, relative to the sync root, or one of its
+// parents matches a pattern.
+func (f filter) excluded(relPath string) bool {
+	if f.empty() {
+		return false
+	}
+	for i := 0; i <= len(relPath); i++ {
+		if i < len(relPath) && relPath[i] != '/' {
+			continue
+		}
+		prefix := relPath[:i]
+		name := path.Base(prefix)
+		for _, pattern := range f.names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		for _, pattern := range f.paths {
+			if ok, _ := path.Match(pattern, prefix); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,49 +2,117 @@ package mirror
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"iter"
 	"os"
 	"path"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/FilenCloudDienste/filen-sdk-go/filen"
-	"github.com/FilenCloudDienste/filen-sdk-go/filen/types"
 	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/executer"
 	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filedb"
 	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
 )
 
 type FilenMirrorConfig struct {
 	SyncDir string
+	// Executer performs the local filesystem operations. Defaults to
+	// executer.LinuxExecuter.
+	Executer executer.Executer
+	// Exclude lists patterns of paths that are neither downloaded nor
+	// removed locally, see ValidateExclude.
+	Exclude []string
+	// Workers is the number of concurrent downloads. Defaults to 4.
+	Workers int
+	// FullSyncInterval is the time between the periodic full syncs of a
+	// started mirror. Defaults to an hour.
+	FullSyncInterval time.Duration
+	// DownloadLimit is the number of bytes per second all downloads together
+	// may read. 0 means unlimited.
+	DownloadLimit int64
 }
 
+// ErrFilesFailed is returned by a sync that completed, but failed to bring
+// some files up to date.
+var ErrFilesFailed = errors.New("some files failed to sync")
+
 type FilenMirror struct {
-	client             *filen.Filen
-	filenEventListener *filenextra.FilenEventListener
+	store              remote.RemoteStore
+	filenEventListener remote.EventSource
 	osDb               *filedb.FileTree
 	baseDirUuid        filedb.Uuid
 	syncDir            string
+	executer           executer.Executer
 	taskRunner         *TaskRunner
+	syncRequests       chan struct{}
+	exclude            filter
+	workers            int
+	fullSyncInterval   time.Duration
+	downloadLimit      int64
+	downloadLimiter    *rate.Limiter
+
+	// reconfigMu guards the settings Reconfigure hands over to the running
+	// mirror.
+	reconfigMu       sync.Mutex
+	requestedExclude []string
+	nextExclude      *filter
+	excludeRequests  chan struct{}
+	intervalRequests chan time.Duration
+
+	statusMu sync.Mutex
+	status   Status
 }
 
-func NewFilenMirror(client *filen.Filen, events *filenextra.FilenEventListener, cfg FilenMirrorConfig) *FilenMirror {
-	baseDirUuid := filedb.UuidFromString(client.BaseFolder.UUID)
+func NewFilenMirror(store remote.RemoteStore, events remote.EventSource, cfg FilenMirrorConfig) *FilenMirror {
+	baseDirUuid := filedb.UuidFromString(store.BaseFolderUUID())
+	exec := cfg.Executer
+	if exec == nil {
+		exec = executer.CreateLinuxExecuter()
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.FullSyncInterval <= 0 {
+		cfg.FullSyncInterval = time.Hour
+	}
+	downloadLimiter := rate.NewLimiter(rate.Inf, 0)
+	setDownloadLimit(downloadLimiter, cfg.DownloadLimit)
 
 	return &FilenMirror{
 		filenEventListener: events,
-		client:             client,
+		store:              store,
 		osDb:               filedb.NewFileTree(),
 		baseDirUuid:        baseDirUuid,
 		syncDir:            cfg.SyncDir,
+		executer:           exec,
 		taskRunner:         NewTaskRunner(),
+		syncRequests:       make(chan struct{}, 1),
+		exclude:            newFilter(cfg.Exclude),
+		workers:            cfg.Workers,
+		fullSyncInterval:   cfg.FullSyncInterval,
+		downloadLimit:      cfg.DownloadLimit,
+		downloadLimiter:    downloadLimiter,
+		requestedExclude:   slices.Clone(cfg.Exclude),
+		excludeRequests:    make(chan struct{}, 1),
+		intervalRequests:   make(chan time.Duration, 1),
+		status:             Status{SyncDir: cfg.SyncDir},
 	}
 }
 
 func (m *FilenMirror) fullSyncOnce(ctx context.Context) error {
-	err := m.removeLocalDbItemsNotInFs(m.osDb.GetPathToUuidMap())
+	err := m.executer.MkdirAll(m.syncDir)
+	if err != nil {
+		return err
+	}
+
+	err = m.removeLocalDbItemsNotInFs(m.osDb.GetPathToUuidMap())
 	if err != nil {
 		return err
 	}
@@ -54,8 +122,10 @@ func (m *FilenMirror) fullSyncOnce(ctx context.Context) error {
 		return err
 	}
 
-	diffChannel := filedb.StartDiff(m.osDb, remoteDb)
-	m.applyDiffItems(diffChannel, remoteDb)
+	filesErr := m.applyDiffItems(ctx, filedb.Diff(m.osDb, remoteDb), remoteDb)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	m.osDb.CopyFrom(remoteDb)
 
@@ -64,11 +134,11 @@ func (m *FilenMirror) fullSyncOnce(ctx context.Context) error {
 		return err
 	}
 
-	return nil
+	return filesErr
 }
 
 func (m *FilenMirror) removeLocalDbItemsNotInFs(paths map[string]filedb.Uuid) error {
-	err := fastReadDirDirs(m.syncDir, func(p string, isDir bool, continueDescending *bool) {
+	err := m.executer.WalkDir(m.syncDir, func(p string, isDir bool, continueDescending *bool) {
 		*continueDescending = true
 		relPath := strings.TrimPrefix(p, m.syncDir+"/")
 		delete(paths, relPath)
@@ -89,13 +159,17 @@ func (m *FilenMirror) removeLocalDbItemsNotInFs(paths map[string]filedb.Uuid) er
 }
 
 func (m *FilenMirror) removeLocalFilesNotInDb(items map[string]filedb.Uuid) error {
-	err := fastReadDirDirs(m.syncDir, func(p string, isDir bool, continueDescending *bool) {
+	err := m.executer.WalkDir(m.syncDir, func(p string, isDir bool, continueDescending *bool) {
 		*continueDescending = true
 		relPath := strings.TrimPrefix(p, m.syncDir+"/")
+		if m.exclude.excluded(relPath) {
+			*continueDescending = false
+			return
+		}
 		if _, ok := items[relPath]; !ok {
 			*continueDescending = false
 			log.Info().Msgf("Removing local file not in database: %s", p)
-			err := executer.Current.RemovePath(p)
+			err := m.executer.RemovePath(p)
 			if err != nil {
 				log.Warn().Err(err).Msgf("Failed to remove local file: %s", p)
 			}
@@ -109,10 +183,18 @@ func (m *FilenMirror) removeLocalFilesNotInDb(items map[string]filedb.Uuid) erro
 	return nil
 }
 
-func (m *FilenMirror) applyDiffItems(diffItems chan filedb.DiffItem, remoteDb *filedb.FileTree) {
+// applyDiffItems returns an error wrapping ErrFilesFailed if files failed
+// to download.
+func (m *FilenMirror) applyDiffItems(ctx context.Context, diffItems iter.Seq[filedb.DiffItem], remoteDb *filedb.FileTree) error {
 	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
 
 	for item := range diffItems {
+		if ctx.Err() != nil {
+			break
+		}
+
 		var needReensure bool
 		var reensurePath string
 		var reensureUuid filedb.Uuid
@@ -125,66 +207,98 @@ func (m *FilenMirror) applyDiffItems(diffItems chan filedb.DiffItem, remoteDb *f
 		case filedb.DiffRemoved:
 			localPath := m.syncDir + "/" + item.Path
 			log.Info().Msgf("Removing local file due to diff: %s", localPath)
-			err := executer.Current.RemovePath(localPath)
+			err := m.executer.RemovePath(localPath)
 			if err != nil {
 				log.Warn().Err(err).Msgf("Failed to remove local file: %s", localPath)
 			}
 			m.osDb.Remove(item.Uuid)
-		case filedb.DiffModified:
+		case filedb.DiffMoved:
+			if err := m.moveLocalPath(item.OldPath, item.NewPath); err != nil {
+				// keep the old entry so the next diff retries the move
+				continue
+			}
+			m.osDb.Move(item.Uuid, item.New.Parent, item.New.Name)
+		case filedb.DiffContentChanged:
+			needReensure = true
+			reensurePath = item.NewPath
+			reensureUuid = item.Uuid
+		case filedb.DiffTypeChanged:
 			oldLocalPath := m.syncDir + "/" + item.OldPath
-			newLocalPath := m.syncDir + "/" + item.NewPath
+			log.Info().Msgf("Removing local path due to type change: %s", oldLocalPath)
+			err := m.executer.RemovePath(oldLocalPath)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed to remove local path: %s", oldLocalPath)
+				continue
+			}
+			m.osDb.Remove(item.Uuid)
 			needReensure = true
 			reensurePath = item.NewPath
 			reensureUuid = item.Uuid
+		case filedb.DiffMetadataChanged:
+			if item.New.IsDir {
+				continue
+			}
+			localPath := m.syncDir + "/" + item.NewPath
+			err := m.executer.Chtimes(localPath, item.New.Modtime)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed to update modification time: %s", localPath)
+				continue
+			}
+			m.osDb.SetModtime(item.Uuid, item.New.Modtime)
+		}
 
-			if oldLocalPath != newLocalPath {
-				err := executer.Current.MkdirAll(path.Dir(newLocalPath))
-				if err != nil {
-					log.Warn().Err(err).Msgf("Failed to create parent directories for move: %s", newLocalPath)
-					continue
-				}
+		if needReensure {
+			remoteFile, _ := remoteDb.GetNode(reensureUuid)
+			localPath := m.syncDir + "/" + reensurePath
 
-				log.Info().Msgf("Moving file from %s to %s", oldLocalPath, newLocalPath)
-				err = executer.Current.Rename(oldLocalPath, newLocalPath)
+			// Directories are created in plan order so that later items
+			// can rely on their parents being present.
+			if remoteFile.IsDir {
+				err := m.executer.EnsureDir(localPath)
 				if err != nil {
-					log.Warn().Err(err).Msgf("Failed to move file from %s to %s", oldLocalPath, newLocalPath)
-					continue
+					log.Warn().Err(err).Msgf("Failed to create local directory: %s", localPath)
 				}
+				continue
 			}
-			// Update the database entry
-			if itemNode, ok := remoteDb.GetNode(item.Uuid); ok {
-				m.osDb.Move(item.Uuid, itemNode.Parent, itemNode.Name)
-			}
-		}
 
-		if needReensure {
 			wg.Add(1)
 			m.taskRunner.Schedule(TaskFunc(func() error {
 				defer wg.Done()
-				remoteFile, _ := remoteDb.GetNode(reensureUuid)
-
-				localPath := m.syncDir + "/" + reensurePath
-
-				if remoteFile.IsDir {
-					return executer.Current.EnsureDir(localPath)
+				if err := ctx.Err(); err != nil {
+					return err
 				}
-
-				return executer.Current.EnsureFile(localPath, remoteFile.Modtime, remoteFile.Hash.String(), func() (io.ReadCloser, error) {
-					return filenextra.CreateDownloadReader(context.Background(), m.client, reensureUuid.String())
+				err := m.executer.EnsureFile(localPath, remoteFile.Modtime, remoteFile.Hash.String(), func() (io.ReadCloser, error) {
+					return m.download(ctx, reensureUuid.String())
 				})
+				if err != nil {
+					errsMu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", reensurePath, err))
+					errsMu.Unlock()
+				}
+				return err
 			}))
 		}
 	}
 
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrFilesFailed, errors.Join(errs...))
+	}
+	return nil
 }
 
 func (m *FilenMirror) fetchRemoteDb(ctx context.Context) (*filedb.FileTree, error) {
-
-	allFiles, allDirs, err := m.client.ListRecursive(ctx, types.DirectoryInterface(m.client.BaseFolder))
+	allFiles, allDirs, err := m.store.ListRecursive(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return m.buildRemoteDb(allFiles, allDirs), nil
+}
+
+func (m *FilenMirror) buildRemoteDb(allFiles []remote.File, allDirs []remote.Directory) *filedb.FileTree {
 	remoteDb := filedb.NewFileTree()
 
 	var dbItems []filedb.FileTreeNode
@@ -219,21 +333,36 @@ func (m *FilenMirror) fetchRemoteDb(ctx context.Context) (*filedb.FileTree, erro
 
 	remoteDb.EnsureItems(dbItems)
 
-	return remoteDb, nil
+	for p, uuid := range remoteDb.GetPathToUuidMap() {
+		if m.exclude.excluded(p) {
+			remoteDb.Remove(uuid)
+		}
+	}
+
+	return remoteDb
 }
 
+// Start starts listening for events and runs the initial full sync in the
+// background. Events sent during the sync are applied on top of it.
 func (m *FilenMirror) Start() {
-	m.taskRunner.Start(4)
+	m.taskRunner.Start(m.currentWorkers())
 
-	m.fullSync()
 	m.filenEventListener.Start()
-	go m.runFilenEventHandler()
+	go m.runFilenEventHandler(true)
 	go m.runPeriodicFullSync()
 }
 
+// fullSync retries until the remote tree was synced. Files that failed to
+// download are left to the next sync.
 func (m *FilenMirror) fullSync() {
+	m.setSyncing()
 	for {
 		err := m.fullSyncOnce(context.Background())
+		m.setSynced(err)
+		if errors.Is(err, ErrFilesFailed) {
+			log.Warn().Err(err).Msg("Full sync incomplete")
+			break
+		}
 		if err != nil {
 			log.Error().Err(err).Msg("Initial full sync failed")
 		} else {
@@ -243,77 +372,257 @@ func (m *FilenMirror) fullSync() {
 	}
 }
 
+// Run is Start for an event source that ends, e.g. a replay. It returns once
+// the source is closed, its events were applied and the downloads they
+// started are done. It must not be combined with Start or SyncOnce.
+func (m *FilenMirror) Run() {
+	m.taskRunner.Start(m.currentWorkers())
+	defer m.taskRunner.Stop()
+
+	m.filenEventListener.Start()
+	m.runFilenEventHandler(true)
+}
+
+// SyncOnce runs a single full sync without listening for events, e.g. from
+// cron. It must not be combined with Start.
+func (m *FilenMirror) SyncOnce(ctx context.Context) error {
+	m.taskRunner.Start(m.currentWorkers())
+	defer m.taskRunner.Stop()
+
+	m.setSyncing()
+	err := m.fullSyncOnce(ctx)
+	m.setSynced(err)
+	return err
+}
+
 func (m *FilenMirror) runPeriodicFullSync() {
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(m.currentFullSyncInterval())
 	defer ticker.Stop()
 
 	for {
-		<-ticker.C
-		m.fullSync()
+		select {
+		case <-ticker.C:
+			m.requestFullSync()
+		case interval := <-m.intervalRequests:
+			ticker.Reset(interval)
+		}
 	}
 }
 
-func (m *FilenMirror) runFilenEventHandler() {
-	for {
-		evt, ok := m.filenEventListener.NextEvent()
-		if !ok {
-			break
+// requestFullSync makes the event handler run a full sync. Requests made
+// while one is pending are merged.
+func (m *FilenMirror) requestFullSync() {
+	select {
+	case m.syncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// runFilenEventHandler applies events until the event source is closed.
+// Full syncs run in between: initially if initialSync is set, after the
+// event stream had a gap, when requested, or when the periodic sync is due.
+// Events arriving during a sync are queued and applied on top of it.
+func (m *FilenMirror) runFilenEventHandler(initialSync bool) {
+	events := make(chan filenextra.TypedEvent)
+	go func() {
+		defer close(events)
+		for {
+			evt, ok := m.filenEventListener.NextEvent()
+			if !ok {
+				return
+			}
+			events <- evt
+		}
+	}()
+
+	var queue []filenextra.TypedEvent
+	var syncDone chan struct{}
+	var gap, syncPending, excludePending bool
+	runSync := func(sync func()) {
+		syncDone = make(chan struct{})
+		go func() {
+			defer close(syncDone)
+			sync()
+		}()
+	}
+	startSync := func() {
+		if syncDone != nil {
+			syncPending = true
+			return
 		}
+		syncPending = false
+		runSync(func() {
+			log.Info().Msg("Catching up with the remote tree")
+			m.fullSync()
+		})
+	}
+	if initialSync {
+		startSync()
+	}
+	// the filter is only swapped while no sync is running, so that syncs
+	// and events see a consistent filter
+	startExcludeChange := func() {
+		if syncDone != nil {
+			excludePending = true
+			return
+		}
+		excludePending = false
+		m.reconfigMu.Lock()
+		next := m.nextExclude
+		m.nextExclude = nil
+		m.reconfigMu.Unlock()
+		if next == nil {
+			return
+		}
+		previous := m.exclude
+		m.exclude = *next
+		runSync(func() {
+			m.resyncExcludeChange(previous)
+		})
+	}
 
-		switch e := evt.Data.(type) {
-		case *filenextra.EventSocketFileNew:
-			m.ensureLocalFile(
-				filedb.UuidFromString(e.UUID),
-				filedb.UuidFromString(e.Parent),
-				e.Meta["name"].(string),
-				time.Unix(maybeString(e.Meta, "lastModified"), 0),
-				"",
-			)
-		case *filenextra.EventSocketFileDeletedPermanent:
-			m.removeLocalFile(filedb.UuidFromString(e.UUID))
-		case *filenextra.EventSocketFileTrash:
-			m.removeLocalFile(filedb.UuidFromString(e.UUID))
-		case *filenextra.EventSocketFileRename:
-			parent, ok := m.osDb.GetNode(filedb.UuidFromString(e.UUID))
+	for events != nil || syncDone != nil {
+		select {
+		case evt, ok := <-events:
 			if !ok {
-				log.Warn().Msgf("Failed to get parent UUID for rename of UUID: %s", e.UUID)
+				events = nil
 				continue
 			}
-			m.moveLocalFile(filedb.UuidFromString(e.UUID), parent.Parent, e.Meta["name"].(string))
-		case *filenextra.EventSocketFileMove:
-			m.moveLocalFile(filedb.UuidFromString(e.UUID), filedb.UuidFromString(e.Parent), e.Meta["name"].(string))
-		case *filenextra.EventSocketFolderTrash:
-			m.removeLocalFile(filedb.UuidFromString(e.UUID))
-		case *filenextra.EventSocketFolderRename:
-			parent, ok := m.osDb.GetNode(filedb.UuidFromString(e.UUID))
-			if !ok {
-				log.Warn().Msgf("Failed to get parent UUID for rename of UUID: %s", e.UUID)
+
+			if state, ok := evt.Data.(*filenextra.EventConnectionState); ok {
+				m.setConnection(state.State)
+				switch state.State {
+				case filenextra.ConnectionLost:
+					gap = true
+				case filenextra.ConnectionReady:
+					if gap {
+						gap = false
+						startSync()
+					}
+				}
 				continue
 			}
-			m.moveLocalFile(filedb.UuidFromString(e.UUID), parent.Uuid, e.Name.Name)
-		case *filenextra.EventSocketFolderMove:
-			m.moveLocalFile(filedb.UuidFromString(e.UUID), filedb.UuidFromString(e.Parent), e.Name.Name)
-		case *filenextra.EventSocketFolderSubCreated:
-			parent := e.Parent
-			if filedb.UuidFromString(e.Parent) == m.baseDirUuid {
-				parent = ""
+
+			if syncDone != nil {
+				queue = append(queue, evt)
+				continue
 			}
-			name := e.Name.Name
-			parentPath, ok := m.osDb.GetPath(filedb.UuidFromString(e.Parent))
-			if !ok {
-				log.Warn().Msgf("Failed to get path for UUID: %s", e.UUID)
+			m.handleEvent(evt)
+		case <-m.syncRequests:
+			startSync()
+		case <-m.excludeRequests:
+			startExcludeChange()
+		case <-syncDone:
+			syncDone = nil
+			if excludePending {
+				startExcludeChange()
+				if syncDone != nil {
+					continue
+				}
+			}
+			if syncPending {
+				startSync()
 				continue
 			}
-			m.osDb.CreateDir(filedb.UuidFromString(e.UUID), filedb.UuidFromString(parent), name)
-			localPath := m.syncDir + "/" + parentPath + "/" + name
-			err := executer.Current.EnsureDir(localPath)
-			if err != nil {
-				log.Warn().Err(err).Msgf("Failed to create local directory: %s", localPath)
+			for _, evt := range queue {
+				m.handleEvent(evt)
 			}
-		default:
-			log.Info().Msgf("Unhandled event type: %s with data: %+v", evt.Name, evt.Data)
+			queue = nil
+		}
+	}
+}
+
+func (m *FilenMirror) handleEvent(evt filenextra.TypedEvent) {
+	switch e := evt.Data.(type) {
+	case *filenextra.EventSocketFileNew:
+		m.ensureLocalFile(
+			filedb.UuidFromString(e.UUID),
+			filedb.UuidFromString(e.Parent),
+			e.Meta.Name,
+			e.Meta.ModTime(),
+			e.Meta.Hash,
+		)
+	case *filenextra.EventSocketFileDeletedPermanent:
+		m.removeLocalFile(filedb.UuidFromString(e.UUID))
+	case *filenextra.EventSocketFileTrash:
+		m.removeLocalFile(filedb.UuidFromString(e.UUID))
+	case *filenextra.EventSocketFileRename:
+		parent, ok := m.osDb.GetNode(filedb.UuidFromString(e.UUID))
+		if !ok {
+			log.Warn().Msgf("Failed to get parent UUID for rename of UUID: %s", e.UUID)
+			return
+		}
+		m.moveLocalFile(filedb.UuidFromString(e.UUID), parent.Parent, e.Meta.Name)
+	case *filenextra.EventSocketFileMove:
+		m.moveLocalFile(filedb.UuidFromString(e.UUID), filedb.UuidFromString(e.Parent), e.Meta.Name)
+	case *filenextra.EventSocketFolderTrash:
+		m.removeLocalFile(filedb.UuidFromString(e.UUID))
+	case *filenextra.EventSocketFolderRename:
+		parent, ok := m.osDb.GetNode(filedb.UuidFromString(e.UUID))
+		if !ok {
+			log.Warn().Msgf("Failed to get parent UUID for rename of UUID: %s", e.UUID)
+			return
 		}
+		m.moveLocalFile(filedb.UuidFromString(e.UUID), parent.Parent, e.Name.Name)
+	case *filenextra.EventSocketFolderMove:
+		m.moveLocalFile(filedb.UuidFromString(e.UUID), filedb.UuidFromString(e.Parent), e.Name.Name)
+	case *filenextra.EventSocketFolderSubCreated:
+		parent := e.Parent
+		if filedb.UuidFromString(e.Parent) == m.baseDirUuid {
+			parent = ""
+		}
+		name := e.Name.Name
+		localPath, ok := m.childLocalPath(filedb.UuidFromString(parent), name)
+		if !ok {
+			log.Warn().Msgf("Failed to get path for UUID: %s", e.UUID)
+			return
+		}
+		if m.excludedLocalPath(localPath) {
+			return
+		}
+		m.osDb.CreateDir(filedb.UuidFromString(e.UUID), filedb.UuidFromString(parent), name)
+		err := m.executer.EnsureDir(localPath)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to create local directory: %s", localPath)
+		}
+	case *filenextra.EventInvalid:
+		m.resyncItem(evt.Name, e.UUID)
+	default:
+		log.Info().Msgf("Unhandled event type: %s with data: %+v", evt.Name, evt.Data)
+	}
+}
+
+// resyncItem brings the item an unusable event referred to up to date. Files
+// are looked up individually; anything else falls back to a full sync.
+func (m *FilenMirror) resyncItem(eventName, uuid string) {
+	if uuid == "" || !strings.HasPrefix(eventName, "file-") {
+		log.Info().Msgf("Resyncing everything after invalid %s event", eventName)
+		m.requestFullSync()
+		return
+	}
+
+	log.Info().Msgf("Resyncing %s after invalid %s event", uuid, eventName)
+	file, err := m.store.FileInfo(context.Background(), uuid)
+	if errors.Is(err, remote.ErrNotFound) {
+		m.removeLocalFile(filedb.UuidFromString(uuid))
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to get file info for UUID: %s", uuid)
+		m.requestFullSync()
+		return
+	}
+
+	if _, ok := m.osDb.GetNode(filedb.UuidFromString(uuid)); ok {
+		m.moveLocalFile(filedb.UuidFromString(uuid), filedb.UuidFromString(file.ParentUUID), file.Name)
 	}
+	m.ensureLocalFile(
+		filedb.UuidFromString(uuid),
+		filedb.UuidFromString(file.ParentUUID),
+		file.Name,
+		file.LastModified,
+		file.Hash,
+	)
 }
 
 func (m *FilenMirror) removeLocalFile(uuid filedb.Uuid) {
@@ -324,7 +633,7 @@ func (m *FilenMirror) removeLocalFile(uuid filedb.Uuid) {
 	}
 	localPath := m.syncDir + "/" + p
 	log.Info().Msgf("Removing local file due to permanent deletion: %s", localPath)
-	err := executer.Current.RemovePath(localPath)
+	err := m.executer.RemovePath(localPath)
 	if err != nil {
 		log.Warn().Err(err).Msgf("Failed to remove local file: %s", localPath)
 	}
@@ -336,21 +645,40 @@ func (m *FilenMirror) ensureLocalFile(uuid, parent filedb.Uuid, name string, mod
 		parent = filedb.NilUuid
 	}
 
-	parentPath, ok := m.osDb.GetPath(parent)
+	localPath, ok := m.childLocalPath(parent, name)
 	if !ok {
 		log.Warn().Msgf("Failed to get path for UUID: %s", uuid)
 		return
 	}
-	localPath := m.syncDir + "/" + parentPath + "/" + name
+	if m.excludedLocalPath(localPath) {
+		return
+	}
 	m.osDb.CreateFile(uuid, parent, name, modTime, hash)
 
 	m.taskRunner.Schedule(TaskFunc(func() error {
-		return executer.Current.EnsureFile(localPath, modTime, hash, func() (io.ReadCloser, error) {
-			return filenextra.CreateDownloadReader(context.Background(), m.client, uuid.String())
+		return m.executer.EnsureFile(localPath, modTime, hash, func() (io.ReadCloser, error) {
+			return m.download(context.Background(), uuid.String())
 		})
 	}))
 }
 
+func (m *FilenMirror) excludedLocalPath(localPath string) bool {
+	return m.exclude.excluded(strings.TrimPrefix(localPath, m.syncDir+"/"))
+}
+
+// childLocalPath returns the local path of an item called name below parent.
+func (m *FilenMirror) childLocalPath(parent filedb.Uuid, name string) (string, bool) {
+	if parent == filedb.NilUuid {
+		return m.syncDir + "/" + name, true
+	}
+
+	parentPath, ok := m.osDb.GetPath(parent)
+	if !ok {
+		return "", false
+	}
+	return m.syncDir + "/" + parentPath + "/" + name, true
+}
+
 func (m *FilenMirror) moveLocalFile(uuid, newParent filedb.Uuid, newName string) {
 	if newParent == m.baseDirUuid {
 		newParent = filedb.NilUuid
@@ -359,28 +687,53 @@ func (m *FilenMirror) moveLocalFile(uuid, newParent filedb.Uuid, newName string)
 	oldPath, ok := m.osDb.GetPath(uuid)
 	if !ok {
 		log.Warn().Msgf("Failed to get old path for UUID: %s", uuid)
+		if !m.exclude.empty() {
+			// the item may come from an excluded path
+			m.requestFullSync()
+		}
 		return
 	}
+	old, _ := m.osDb.GetNode(uuid)
 	m.osDb.Move(uuid, newParent, filedb.FileNameFromString(newName))
 	newPath, ok := m.osDb.GetPath(uuid)
 	if !ok {
 		log.Warn().Msgf("Failed to get new path for UUID: %s", uuid)
 		return
 	}
+	if m.exclude.excluded(newPath) {
+		log.Info().Msgf("Removing local path moved to an excluded path: %s", oldPath)
+		err := m.executer.RemovePath(m.syncDir + "/" + oldPath)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove local path: %s", oldPath)
+		}
+		m.osDb.Remove(uuid)
+		return
+	}
+
+	if err := m.moveLocalPath(oldPath, newPath); err != nil {
+		// the file is still at its old path
+		m.osDb.Move(uuid, old.Parent, old.Name)
+	}
+}
 
+func (m *FilenMirror) moveLocalPath(oldPath, newPath string) error {
 	oldLocalPath := m.syncDir + "/" + oldPath
 	newLocalPath := m.syncDir + "/" + newPath
+	if oldLocalPath == newLocalPath {
+		return nil
+	}
 
-	err := executer.Current.MkdirAll(path.Dir(newLocalPath))
+	err := m.executer.MkdirAll(path.Dir(newLocalPath))
 	if err != nil {
 		log.Warn().Err(err).Msgf("Failed to create parent directories for move: %s", newLocalPath)
-		return
+		return err
 	}
 
 	log.Info().Msgf("Moving file from %s to %s", oldLocalPath, newLocalPath)
-	err = executer.Current.Rename(oldLocalPath, newLocalPath)
+	err = m.executer.Rename(oldLocalPath, newLocalPath)
 	if err != nil {
 		log.Warn().Err(err).Msgf("Failed to move file from %s to %s", oldLocalPath, newLocalPath)
-		return
+		return err
 	}
+	return nil
 }
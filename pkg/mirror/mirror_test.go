@@ -0,0 +1,553 @@
+package mirror_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/executer"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+const waitFor = 5 * time.Second
+const tick = 10 * time.Millisecond
+
+func startMirror(t *testing.T, store *remote.MemoryStore) string {
+	syncDir := t.TempDir()
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{
+		SyncDir: syncDir,
+	})
+	m.Start()
+	t.Cleanup(func() { _ = store.Close() })
+	return syncDir
+}
+
+func assertFileContent(t *testing.T, path string, content string) {
+	t.Helper()
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		b, err := os.ReadFile(path)
+		assert.NoError(c, err)
+		assert.Equal(c, content, string(b))
+	}, waitFor, tick)
+}
+
+func assertNotExists(t *testing.T, path string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	}, waitFor, tick)
+}
+
+func TestFullSync(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetFile("file1", "dir1", "file1.txt", []byte("hello"), time.UnixMilli(1000))
+
+	syncDir := startMirror(t, store)
+
+	assertFileContent(t, filepath.Join(syncDir, "dir1", "file1.txt"), "hello")
+	info, err := os.Stat(filepath.Join(syncDir, "dir1", "file1.txt"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(time.UnixMilli(1000)))
+}
+
+func TestFullSyncRemovesUnknownLocalFiles(t *testing.T) {
+	store := remote.NewMemoryStore()
+	syncDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(syncDir, "stale.txt"), []byte("x"), 0o644))
+
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{SyncDir: syncDir})
+	m.Start()
+	t.Cleanup(func() { _ = store.Close() })
+
+	assertNotExists(t, filepath.Join(syncDir, "stale.txt"))
+}
+
+func TestEventFileNew(t *testing.T) {
+	store := remote.NewMemoryStore()
+	syncDir := startMirror(t, store)
+
+	dir := store.CreateDir(store.BaseFolderUUID(), "dir1")
+	store.CreateFile(dir, "file1.txt", []byte("hello"), time.UnixMilli(2000))
+
+	assertFileContent(t, filepath.Join(syncDir, "dir1", "file1.txt"), "hello")
+}
+
+func TestEventFileMoveAndTrash(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetFile("file1", store.BaseFolderUUID(), "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	syncDir := startMirror(t, store)
+	assertFileContent(t, filepath.Join(syncDir, "file1.txt"), "hello")
+
+	assert.NoError(t, store.MoveFile("file1", "dir1"))
+	assertFileContent(t, filepath.Join(syncDir, "dir1", "file1.txt"), "hello")
+	assertNotExists(t, filepath.Join(syncDir, "file1.txt"))
+
+	assert.NoError(t, store.TrashFile("file1"))
+	assertNotExists(t, filepath.Join(syncDir, "dir1", "file1.txt"))
+}
+
+func TestEventFolderTrash(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetFile("file1", "dir1", "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	syncDir := startMirror(t, store)
+	assertFileContent(t, filepath.Join(syncDir, "dir1", "file1.txt"), "hello")
+
+	assert.NoError(t, store.TrashDir("dir1"))
+	assertNotExists(t, filepath.Join(syncDir, "dir1"))
+}
+
+func TestFullSyncMemoryExecuter(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetFile("file1", "dir1", "file1.txt", []byte("hello"), time.UnixMilli(1000))
+
+	exec := executer.NewMemoryExecuter()
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{
+		SyncDir:  "/data",
+		Executer: exec,
+	})
+	m.Start()
+	t.Cleanup(func() { _ = store.Close() })
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		content, err := exec.ReadFile("/data/dir1/file1.txt")
+		assert.NoError(c, err)
+		assert.Equal(c, "hello", string(content))
+	}, waitFor, tick)
+}
+
+func TestChaosFullSyncRecovers(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetFile("file1", store.BaseFolderUUID(), "file1.txt", []byte("hello"), time.UnixMilli(1000))
+
+	inner := executer.NewMemoryExecuter()
+	exec := executer.NewChaosExecuter(inner, executer.ChaosConfig{NoSpaceRate: 1})
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{
+		SyncDir:  "/data",
+		Executer: exec,
+	})
+	m.Start()
+	t.Cleanup(func() { _ = store.Close() })
+
+	// the initial full sync is retried while the disk is full
+	time.Sleep(100 * time.Millisecond)
+	_, err := inner.ReadFile("/data/file1.txt")
+	assert.True(t, os.IsNotExist(err))
+
+	exec.SetConfig(executer.ChaosConfig{})
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		content, err := inner.ReadFile("/data/file1.txt")
+		assert.NoError(c, err)
+		assert.Equal(c, "hello", string(content))
+	}, 2*waitFor, tick)
+}
+
+func TestEventFailedMoveKeepsOldPath(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetFile("file1", store.BaseFolderUUID(), "a.txt", []byte("hello"), time.UnixMilli(1000))
+
+	inner := executer.NewMemoryExecuter()
+	exec := executer.NewChaosExecuter(inner, executer.ChaosConfig{RenameFailRate: 1})
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{
+		SyncDir:  "/data",
+		Executer: exec,
+	})
+	m.Start()
+	t.Cleanup(func() { _ = store.Close() })
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		_, err := inner.ReadFile("/data/a.txt")
+		assert.NoError(c, err)
+	}, waitFor, tick)
+
+	assert.NoError(t, store.RenameFile("file1", "b.txt"))
+	assert.Never(t, func() bool {
+		_, err := inner.ReadFile("/data/b.txt")
+		return err == nil
+	}, 100*time.Millisecond, tick)
+
+	// the file is still tracked at the path it was not moved away from
+	exec.SetConfig(executer.ChaosConfig{})
+	assert.NoError(t, store.TrashFile("file1"))
+	assert.Eventually(t, func() bool {
+		_, err := inner.ReadFile("/data/a.txt")
+		return os.IsNotExist(err)
+	}, waitFor, tick)
+}
+
+// gatedStore takes a listing and then waits until the gate is opened, so
+// that changes can be made while a sync is running.
+type gatedStore struct {
+	*remote.MemoryStore
+	listings atomic.Int32
+
+	mu   sync.Mutex
+	gate chan struct{}
+}
+
+func (s *gatedStore) ListRecursive(ctx context.Context) ([]remote.File, []remote.Directory, error) {
+	files, dirs, err := s.MemoryStore.ListRecursive(ctx)
+	s.listings.Add(1)
+
+	s.mu.Lock()
+	gate := s.gate
+	s.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	return files, dirs, err
+}
+
+func (s *gatedStore) closeGate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate = make(chan struct{})
+}
+
+func (s *gatedStore) openGate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.gate)
+	s.gate = nil
+}
+
+// countingStore counts the downloads.
+type countingStore struct {
+	*remote.MemoryStore
+	downloads atomic.Int32
+}
+
+func (s *countingStore) Download(ctx context.Context, uuid string) (io.ReadCloser, error) {
+	s.downloads.Add(1)
+	return s.MemoryStore.Download(ctx, uuid)
+}
+
+func emitConnectionState(store *remote.MemoryStore, state filenextra.ConnectionState) {
+	store.Emit(filenextra.TypedEvent{
+		Name: "connection-state",
+		Data: &filenextra.EventConnectionState{State: state},
+	})
+}
+
+func TestCatchUpAfterConnectionLoss(t *testing.T) {
+	mem := remote.NewMemoryStore()
+	mem.SetFile("file1", mem.BaseFolderUUID(), "a.txt", []byte("hello"), time.UnixMilli(1000))
+	store := &gatedStore{MemoryStore: mem}
+
+	syncDir := t.TempDir()
+	m := mirror.NewFilenMirror(store, mem, mirror.FilenMirrorConfig{SyncDir: syncDir})
+	m.Start()
+	t.Cleanup(func() { _ = mem.Close() })
+	assertFileContent(t, filepath.Join(syncDir, "a.txt"), "hello")
+
+	// a ready connection without a gap does not sync
+	emitConnectionState(mem, filenextra.ConnectionReady)
+	assert.Never(t, func() bool { return store.listings.Load() > 1 }, 100*time.Millisecond, tick)
+
+	store.closeGate()
+	emitConnectionState(mem, filenextra.ConnectionLost)
+	mem.SetFile("file2", mem.BaseFolderUUID(), "b.txt", []byte("missed"), time.UnixMilli(1000))
+	emitConnectionState(mem, filenextra.ConnectionReady)
+	assert.Eventually(t, func() bool { return store.listings.Load() == 2 }, waitFor, tick)
+
+	// the rename happens after the listing and is applied on top of it
+	assert.NoError(t, mem.RenameFile("file1", "renamed.txt"))
+	assert.Never(t, func() bool {
+		_, err := os.Stat(filepath.Join(syncDir, "renamed.txt"))
+		return err == nil
+	}, 100*time.Millisecond, tick)
+
+	store.openGate()
+	assertFileContent(t, filepath.Join(syncDir, "b.txt"), "missed")
+	assertFileContent(t, filepath.Join(syncDir, "renamed.txt"), "hello")
+	assertNotExists(t, filepath.Join(syncDir, "a.txt"))
+}
+
+func TestEventsDuringInitialSync(t *testing.T) {
+	mem := remote.NewMemoryStore()
+	mem.SetFile("file1", mem.BaseFolderUUID(), "a.txt", []byte("hello"), time.UnixMilli(1000))
+	store := &gatedStore{MemoryStore: mem}
+	store.closeGate()
+
+	syncDir := t.TempDir()
+	m := mirror.NewFilenMirror(store, mem, mirror.FilenMirrorConfig{SyncDir: syncDir})
+	m.Start()
+	t.Cleanup(func() { _ = mem.Close() })
+	assert.Eventually(t, func() bool { return store.listings.Load() == 1 }, waitFor, tick)
+
+	// the rename is queued until the initial sync is done
+	assert.NoError(t, mem.RenameFile("file1", "renamed.txt"))
+	store.openGate()
+	assertFileContent(t, filepath.Join(syncDir, "renamed.txt"), "hello")
+	assertNotExists(t, filepath.Join(syncDir, "a.txt"))
+}
+
+func TestReplayRecordedEvents(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetFile("file1", store.BaseFolderUUID(), "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	store.SetFile("file2", store.BaseFolderUUID(), "file2.txt", []byte("bye"), time.UnixMilli(1000))
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recorder, err := filenextra.NewRecorder(filenextra.RecorderConfig{Path: path})
+	assert.NoError(t, err)
+	for _, rec := range []filenextra.Record{
+		{Event: "connection-state", State: "ready"},
+		{Event: "file-rename", Decoded: map[string]any{
+			"uuid":     "file1",
+			"metadata": map[string]any{"name": "renamed.txt", "size": 5, "lastModified": 1000},
+		}},
+		{Event: "file-trash", Decoded: map[string]any{"uuid": "file2"}},
+	} {
+		assert.NoError(t, recorder.Record(rec))
+	}
+	assert.NoError(t, recorder.Close())
+
+	replay, err := filenextra.NewReplaySource(path)
+	assert.NoError(t, err)
+	syncDir := t.TempDir()
+	m := mirror.NewFilenMirror(store, replay, mirror.FilenMirrorConfig{SyncDir: syncDir})
+	m.Run()
+
+	b, err := os.ReadFile(filepath.Join(syncDir, "renamed.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	_, err = os.Stat(filepath.Join(syncDir, "file1.txt"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(syncDir, "file2.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSyncOnce(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetFile("file1", "dir1", "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	syncDir := t.TempDir()
+
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{SyncDir: syncDir})
+	assert.NoError(t, m.SyncOnce(context.Background()))
+
+	b, err := os.ReadFile(filepath.Join(syncDir, "dir1", "file1.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	status := m.Status()
+	assert.False(t, status.Syncing)
+	assert.NotNil(t, status.LastSync)
+	assert.True(t, status.Healthy())
+}
+
+// stallingStore hands out downloads that only end when their context is done.
+type stallingStore struct {
+	*remote.MemoryStore
+}
+
+func (s stallingStore) Download(ctx context.Context, uuid string) (io.ReadCloser, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSyncOnceCanceled(t *testing.T) {
+	mem := remote.NewMemoryStore()
+	mem.SetFile("file1", mem.BaseFolderUUID(), "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	m := mirror.NewFilenMirror(stallingStore{mem}, mem, mirror.FilenMirrorConfig{
+		SyncDir:  "/data",
+		Executer: executer.NewMemoryExecuter(),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.SyncOnce(ctx), context.DeadlineExceeded)
+	assert.False(t, m.Status().Healthy())
+}
+
+// Files that are already there with the remote content are not downloaded
+// again, only their modification time is updated.
+func TestSyncOnceKeepsSameContent(t *testing.T) {
+	mem := remote.NewMemoryStore()
+	mem.SetFile("file1", mem.BaseFolderUUID(), "file1.txt", []byte("hello"), time.UnixMilli(2000))
+	store := &countingStore{MemoryStore: mem}
+	exec := executer.NewMemoryExecuter()
+	assert.NoError(t, exec.WriteFile(context.Background(), "/data/file1.txt", time.UnixMilli(1000), strings.NewReader("hello")))
+
+	m := mirror.NewFilenMirror(store, mem, mirror.FilenMirrorConfig{SyncDir: "/data", Executer: exec})
+	assert.NoError(t, m.SyncOnce(context.Background()))
+
+	assert.Equal(t, int32(0), store.downloads.Load())
+	info, err := exec.Stat("/data/file1.txt")
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(time.UnixMilli(2000)))
+}
+
+func TestPlan(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetFile("file1", "dir1", "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	store.SetFile("file2", store.BaseFolderUUID(), "file2.txt", []byte("world"), time.UnixMilli(1000))
+	store.SetFile("file3", store.BaseFolderUUID(), "file3.txt", []byte("!"), time.UnixMilli(1000))
+	syncDir := t.TempDir()
+
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{SyncDir: syncDir})
+	assert.NoError(t, m.SyncOnce(context.Background()))
+
+	store.SetFile("file2", store.BaseFolderUUID(), "file2.txt", []byte("world"), time.UnixMilli(2000))
+	store.SetFile("file4", "dir1", "file4.txt", []byte("new"), time.UnixMilli(1000))
+	assert.NoError(t, os.Remove(filepath.Join(syncDir, "file3.txt")))
+	assert.NoError(t, os.MkdirAll(filepath.Join(syncDir, "stale", "sub"), 0o755))
+
+	changes, err := m.Plan(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []mirror.Change{
+		{Kind: mirror.ChangeAdd, Path: "dir1/file4.txt"},
+		{Kind: mirror.ChangeUpdate, Path: "file2.txt"},
+		{Kind: mirror.ChangeAdd, Path: "file3.txt"},
+		{Kind: mirror.ChangeRemove, Path: "stale", IsDir: true},
+	}, changes)
+
+	// nothing was changed
+	_, err = os.Stat(filepath.Join(syncDir, "stale"))
+	assert.NoError(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetFile("file1", "dir1", "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	store.SetFile("file2", store.BaseFolderUUID(), "file2.txt", []byte("world"), time.UnixMilli(1000))
+	store.SetFile("file3", store.BaseFolderUUID(), "file3.txt", []byte("!"), time.UnixMilli(1000))
+
+	exec := executer.NewMemoryExecuter()
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{SyncDir: "/data", Executer: exec})
+	assert.NoError(t, m.SyncOnce(context.Background()))
+
+	mismatches, err := m.Verify(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	assert.NoError(t, exec.WriteFile(context.Background(), "/data/file2.txt", time.UnixMilli(1000), strings.NewReader("corrupt")))
+	assert.NoError(t, exec.RemovePath("/data/file3.txt"))
+
+	mismatches, err = m.Verify(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []mirror.Mismatch{
+		{Kind: mirror.MismatchHash, Path: "file2.txt"},
+		{Kind: mirror.MismatchMissing, Path: "file3.txt"},
+	}, mismatches)
+}
+
+func TestSyncOnceExclude(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("dir1", store.BaseFolderUUID(), "dir1")
+	store.SetDir("raw", "dir1", "raw")
+	store.SetFile("file1", "dir1", "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	store.SetFile("file2", "raw", "file2.txt", []byte("raw"), time.UnixMilli(1000))
+	store.SetFile("file3", store.BaseFolderUUID(), "file3.tmp", []byte("tmp"), time.UnixMilli(1000))
+	syncDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(syncDir, "local.tmp"), []byte("x"), 0o644))
+
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{
+		SyncDir: syncDir,
+		Exclude: []string{"*.tmp", "dir1/raw"},
+	})
+	assert.NoError(t, m.SyncOnce(context.Background()))
+
+	_, err := os.Stat(filepath.Join(syncDir, "dir1", "file1.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(syncDir, "dir1", "raw"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(syncDir, "file3.tmp"))
+	assert.True(t, os.IsNotExist(err))
+	// excluded local files are kept
+	_, err = os.Stat(filepath.Join(syncDir, "local.tmp"))
+	assert.NoError(t, err)
+
+	changes, err := m.Plan(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestValidateExclude(t *testing.T) {
+	assert.NoError(t, mirror.ValidateExclude([]string{"*.tmp", "/photos/raw", ".cache"}))
+	assert.Error(t, mirror.ValidateExclude([]string{"[a-"}))
+	assert.Error(t, mirror.ValidateExclude([]string{"/"}))
+}
+
+func TestReconfigureExclude(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("raw", store.BaseFolderUUID(), "raw")
+	store.SetFile("file1", store.BaseFolderUUID(), "file1.tmp", []byte("tmp"), time.UnixMilli(1000))
+	store.SetFile("file2", "raw", "file2.txt", []byte("raw"), time.UnixMilli(1000))
+	syncDir := t.TempDir()
+
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{
+		SyncDir: syncDir,
+		Exclude: []string{"raw"},
+	})
+	m.Start()
+	t.Cleanup(func() { _ = store.Close() })
+	assertFileContent(t, filepath.Join(syncDir, "file1.tmp"), "tmp")
+	_, err := os.Stat(filepath.Join(syncDir, "raw"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, m.Reconfigure(mirror.FilenMirrorConfig{
+		Exclude:          []string{"*.tmp"},
+		Workers:          2,
+		FullSyncInterval: time.Minute,
+	}))
+	assertFileContent(t, filepath.Join(syncDir, "raw", "file2.txt"), "raw")
+
+	// newly excluded files are left alone, also by later syncs and events
+	store.SetFile("file1", store.BaseFolderUUID(), "file1.tmp", []byte("changed"), time.UnixMilli(2000))
+	store.CreateFile("raw", "file3.txt", []byte("new"), time.UnixMilli(1000))
+	assertFileContent(t, filepath.Join(syncDir, "raw", "file3.txt"), "new")
+	b, err := os.ReadFile(filepath.Join(syncDir, "file1.tmp"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tmp", string(b))
+}
+
+func TestDownloadLimit(t *testing.T) {
+	content := strings.Repeat("x", 128*1024)
+	limit := int64(64 * 1024)
+	for _, tc := range []struct {
+		name        string
+		cfg         mirror.FilenMirrorConfig
+		reconfigure int64
+	}{
+		{"configured", mirror.FilenMirrorConfig{DownloadLimit: limit}, limit},
+		{"reconfigured", mirror.FilenMirrorConfig{}, limit},
+	} {
+		store := remote.NewMemoryStore()
+		store.SetFile("file", store.BaseFolderUUID(), "file.bin", []byte(content), time.UnixMilli(1000))
+		assert.NoError(t, store.Close())
+		tc.cfg.SyncDir = "/data"
+		tc.cfg.Executer = executer.NewMemoryExecuter()
+		m := mirror.NewFilenMirror(store, store, tc.cfg)
+		assert.NoError(t, m.Reconfigure(mirror.FilenMirrorConfig{DownloadLimit: tc.reconfigure}))
+
+		start := time.Now()
+		m.Run()
+		// the first second is covered by the burst
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond, tc.name)
+		b, err := tc.cfg.Executer.(*executer.MemoryExecuter).ReadFile("/data/file.bin")
+		assert.NoError(t, err)
+		assert.Equal(t, len(content), len(b), tc.name)
+	}
+}
+
+func TestReconfigureRejectsSyncDir(t *testing.T) {
+	store := remote.NewMemoryStore()
+	m := mirror.NewFilenMirror(store, store, mirror.FilenMirrorConfig{SyncDir: t.TempDir()})
+	err := m.Reconfigure(mirror.FilenMirrorConfig{SyncDir: t.TempDir()})
+	assert.ErrorIs(t, err, mirror.ErrNotReloadable)
+}
@@ -0,0 +1,94 @@
+package mirror
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ChangeKind string
+
+const (
+	// ChangeAdd downloads a file or creates a directory missing locally.
+	ChangeAdd ChangeKind = "add"
+	// ChangeUpdate downloads a file again whose modification time differs.
+	ChangeUpdate ChangeKind = "update"
+	// ChangeRemove removes a local file or directory missing remotely.
+	ChangeRemove ChangeKind = "remove"
+	// ChangeReplace replaces a file by a directory or the other way around.
+	ChangeReplace ChangeKind = "replace"
+)
+
+// Change is a difference between the sync directory and the remote tree.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Path is relative to the sync directory.
+	Path  string `json:"path"`
+	IsDir bool   `json:"isDir"`
+}
+
+type localEntry struct {
+	isDir   bool
+	modTime time.Time
+}
+
+// Plan returns the changes a full sync would make, sorted by path. Nothing
+// is changed. Of a directory to be removed only the directory itself is
+// listed. Excluded paths are left out.
+func (m *FilenMirror) Plan(ctx context.Context) ([]Change, error) {
+	remoteDb, err := m.fetchRemoteDb(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remotePaths := remoteDb.GetPathToUuidMap()
+
+	var changes []Change
+	local := make(map[string]localEntry)
+	err = m.executer.WalkDir(m.syncDir, func(p string, isDir bool, continueDescending *bool) {
+		*continueDescending = true
+		relPath := strings.TrimPrefix(p, m.syncDir+"/")
+		if m.exclude.excluded(relPath) {
+			*continueDescending = false
+			return
+		}
+		if _, ok := remotePaths[relPath]; !ok {
+			*continueDescending = false
+			changes = append(changes, Change{Kind: ChangeRemove, Path: relPath, IsDir: isDir})
+			return
+		}
+		entry := localEntry{isDir: isDir}
+		if !isDir {
+			info, err := m.executer.Stat(p)
+			if err == nil {
+				entry.modTime = info.ModTime()
+			}
+		}
+		local[relPath] = entry
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for relPath, uuid := range remotePaths {
+		node, ok := remoteDb.GetNode(uuid)
+		if !ok {
+			continue
+		}
+		entry, ok := local[relPath]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeAdd, Path: relPath, IsDir: node.IsDir})
+		case entry.isDir != node.IsDir:
+			changes = append(changes, Change{Kind: ChangeReplace, Path: relPath, IsDir: node.IsDir})
+		case !node.IsDir && !entry.modTime.Equal(node.Modtime):
+			changes = append(changes, Change{Kind: ChangeUpdate, Path: relPath})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes, nil
+}
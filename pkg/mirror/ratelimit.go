@@ -0,0 +1,66 @@
+package mirror
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// minBurst keeps the reads of a limited download from getting tiny under
+// low limits.
+const minBurst = 64 * 1024
+
+// setDownloadLimit limits l to bytesPerSecond. 0 removes the limit.
+func setDownloadLimit(l *rate.Limiter, bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		l.SetLimit(rate.Inf)
+		return
+	}
+	l.SetBurst(int(max(bytesPerSecond, minBurst)))
+	l.SetLimit(rate.Limit(bytesPerSecond))
+}
+
+// limitedReader takes the bytes it reads from a limiter shared by all
+// downloads, so that a changed limit also applies to running downloads. It
+// stops reading once ctx is done.
+type limitedReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+func (lr limitedReader) Read(p []byte) (int, error) {
+	if err := lr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := lr.ReadCloser.Read(p)
+	for remaining := n; remaining > 0; {
+		if lr.limiter.Limit() == rate.Inf {
+			break
+		}
+		chunk := min(remaining, lr.limiter.Burst())
+		if chunk <= 0 {
+			break
+		}
+		waitErr := lr.limiter.WaitN(lr.ctx, chunk)
+		if waitErr != nil && lr.ctx.Err() == nil && chunk > lr.limiter.Burst() {
+			// the burst was lowered after the chunk was sized
+			continue
+		}
+		if waitErr != nil {
+			return n, waitErr
+		}
+		remaining -= chunk
+	}
+	return n, err
+}
+
+// download opens the remote file uuid, limited to the download limit.
+func (m *FilenMirror) download(ctx context.Context, uuid string) (io.ReadCloser, error) {
+	r, err := m.store.Download(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return limitedReader{ReadCloser: r, ctx: ctx, limiter: m.downloadLimiter}, nil
+}
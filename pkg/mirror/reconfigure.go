@@ -0,0 +1,134 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filedb"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNotReloadable is returned by Reconfigure for settings that cannot
+// change while the mirror runs.
+var ErrNotReloadable = errors.New("setting cannot change while running")
+
+// Reconfigure applies the settings of cfg that can change while the mirror
+// runs: Workers, FullSyncInterval, DownloadLimit and Exclude. SyncDir and
+// Executer must stay the same; zero values keep the current setting for
+// Workers and FullSyncInterval. DownloadLimit also applies to running
+// downloads.
+//
+// A changed Exclude is applied in between events, followed by a resync of
+// the paths it newly includes. Paths it newly excludes are left alone
+// locally.
+func (m *FilenMirror) Reconfigure(cfg FilenMirrorConfig) error {
+	if cfg.SyncDir != "" && cfg.SyncDir != m.syncDir {
+		return fmt.Errorf("sync dir: %w", ErrNotReloadable)
+	}
+	if cfg.Executer != nil && cfg.Executer != m.executer {
+		return fmt.Errorf("executer: %w", ErrNotReloadable)
+	}
+	err := ValidateExclude(cfg.Exclude)
+	if err != nil {
+		return err
+	}
+
+	m.reconfigMu.Lock()
+	defer m.reconfigMu.Unlock()
+
+	if cfg.Workers > 0 && cfg.Workers != m.workers {
+		log.Info().Msgf("Changing workers from %d to %d", m.workers, cfg.Workers)
+		m.workers = cfg.Workers
+		m.taskRunner.SetWorkers(cfg.Workers)
+	}
+
+	if cfg.FullSyncInterval > 0 && cfg.FullSyncInterval != m.fullSyncInterval {
+		log.Info().Msgf("Changing full sync interval from %s to %s", m.fullSyncInterval, cfg.FullSyncInterval)
+		m.fullSyncInterval = cfg.FullSyncInterval
+		// replace a request the ticker did not pick up yet
+		select {
+		case <-m.intervalRequests:
+		default:
+		}
+		m.intervalRequests <- cfg.FullSyncInterval
+	}
+
+	if cfg.DownloadLimit != m.downloadLimit {
+		log.Info().Msgf("Changing download limit from %d to %d bytes per second", m.downloadLimit, cfg.DownloadLimit)
+		m.downloadLimit = cfg.DownloadLimit
+		setDownloadLimit(m.downloadLimiter, cfg.DownloadLimit)
+	}
+
+	if !slices.Equal(cfg.Exclude, m.requestedExclude) {
+		log.Info().Msgf("Changing exclude patterns from %q to %q", m.requestedExclude, cfg.Exclude)
+		m.requestedExclude = slices.Clone(cfg.Exclude)
+		next := newFilter(cfg.Exclude)
+		m.nextExclude = &next
+		select {
+		case m.excludeRequests <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// resyncExcludeChange brings the local tree in line with the new exclude
+// patterns, which were active as previous before. Only items that were
+// excluded before are downloaded; the rest of the tree is not compared.
+func (m *FilenMirror) resyncExcludeChange(previous filter) {
+	m.setSyncing()
+
+	// newly excluded items are forgotten, so that syncs leave them alone
+	for p, uuid := range m.osDb.GetPathToUuidMap() {
+		if m.exclude.excluded(p) {
+			m.osDb.Remove(uuid)
+		}
+	}
+
+	ctx := context.Background()
+	remoteDb, err := m.fetchRemoteDb(ctx)
+	if err != nil {
+		m.setSynced(err)
+		log.Error().Err(err).Msg("Failed to list the remote tree after the exclude patterns changed")
+		m.requestFullSync()
+		return
+	}
+
+	var included []filedb.DiffItem
+	var nodes []filedb.FileTreeNode
+	for item := range filedb.Diff(m.osDb, remoteDb) {
+		added, ok := item.(filedb.DiffAdded)
+		if !ok || !previous.excluded(added.Path) {
+			continue
+		}
+		node, ok := remoteDb.GetNode(added.Uuid)
+		if !ok {
+			continue
+		}
+		included = append(included, added)
+		nodes = append(nodes, node)
+	}
+
+	log.Info().Msgf("Syncing %d newly included items", len(included))
+	err = m.applyDiffItems(ctx, slices.Values(included), remoteDb)
+	m.osDb.EnsureItems(nodes)
+	m.setSynced(err)
+	if err != nil {
+		log.Warn().Err(err).Msg("Resync after exclude change incomplete")
+	}
+}
+
+func (m *FilenMirror) currentWorkers() int {
+	m.reconfigMu.Lock()
+	defer m.reconfigMu.Unlock()
+	return m.workers
+}
+
+func (m *FilenMirror) currentFullSyncInterval() time.Duration {
+	m.reconfigMu.Lock()
+	defer m.reconfigMu.Unlock()
+	return m.fullSyncInterval
+}
@@ -0,0 +1,194 @@
+package mirror
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/executer"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the event handler scenarios")
+
+const scenarioSyncDir = "/data"
+
+// scenario describes a run of the event handler. The remote tree is synced
+// first, then the events are handled in order. Uuids may be any string;
+// "base" stands for the base folder.
+type scenario struct {
+	Remote []scenarioItem `yaml:"remote"`
+	Steps  []scenarioStep `yaml:"steps"`
+}
+
+type scenarioItem struct {
+	UUID    string `yaml:"uuid"`
+	Parent  string `yaml:"parent"`
+	Name    string `yaml:"name"`
+	Dir     bool   `yaml:"dir"`
+	Content string `yaml:"content"`
+	ModTime int64  `yaml:"modTime"`
+}
+
+// scenarioStep stores the items in Set remotely without an event, so that
+// they can be downloaded, and then emits Event with the decrypted Data.
+type scenarioStep struct {
+	Set   []scenarioItem `yaml:"set"`
+	Event string         `yaml:"event"`
+	Data  map[string]any `yaml:"data"`
+}
+
+// scenarioResult is the state after a scenario. Paths are relative to the
+// sync directory, directories end with a slash.
+type scenarioResult struct {
+	// Local maps each local path to the file content.
+	Local map[string]string `yaml:"local"`
+	// Db maps each uuid in osDb to its path.
+	Db map[string]string `yaml:"db"`
+}
+
+func TestScenarios(t *testing.T) {
+	files, err := filepath.Glob("testdata/scenarios/*.yaml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		if strings.HasSuffix(file, ".golden.yaml") {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			runScenarioFile(t, file)
+		})
+	}
+}
+
+func runScenarioFile(t *testing.T, file string) {
+	raw, err := os.ReadFile(file)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var sc scenario
+	if !assert.NoError(t, yaml.Unmarshal(raw, &sc)) {
+		return
+	}
+
+	actual := runScenario(t, sc)
+
+	goldenFile := strings.TrimSuffix(file, ".yaml") + ".golden.yaml"
+	if *update {
+		out, err := yaml.Marshal(actual)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(goldenFile, out, 0o644))
+		return
+	}
+
+	raw, err = os.ReadFile(goldenFile)
+	if !assert.NoError(t, err, "run with -update to create the golden file") {
+		return
+	}
+	var expected scenarioResult
+	if !assert.NoError(t, yaml.Unmarshal(raw, &expected)) {
+		return
+	}
+	assert.Equal(t, normalizeResult(expected), normalizeResult(actual))
+}
+
+func runScenario(t *testing.T, sc scenario) scenarioResult {
+	store := remote.NewMemoryStore()
+	resolve := func(uuid string) string {
+		if uuid == "base" {
+			return store.BaseFolderUUID()
+		}
+		return uuid
+	}
+	set := func(items []scenarioItem) {
+		for _, item := range items {
+			if item.Dir {
+				store.SetDir(item.UUID, resolve(item.Parent), item.Name)
+			} else {
+				store.SetFile(item.UUID, resolve(item.Parent), item.Name, []byte(item.Content), time.UnixMilli(item.ModTime))
+			}
+		}
+	}
+
+	set(sc.Remote)
+
+	exec := executer.NewMemoryExecuter()
+	m := NewFilenMirror(store, store, FilenMirrorConfig{
+		SyncDir:  scenarioSyncDir,
+		Executer: exec,
+	})
+	m.taskRunner.Start(1)
+	m.fullSync()
+
+	for _, step := range sc.Steps {
+		set(step.Set)
+		if parent, ok := step.Data["parent"].(string); ok {
+			step.Data["parent"] = resolve(parent)
+		}
+		// invalid events are passed on just like the listener does
+		evt, err := filenextra.InterpretEvent(step.Event, step.Data)
+		if !errors.Is(err, filenextra.ErrInvalidEvent) && !assert.NoError(t, err) {
+			continue
+		}
+		store.Emit(evt)
+	}
+
+	// handle all events, then wait for the scheduled downloads
+	assert.NoError(t, store.Close())
+	m.runFilenEventHandler(false)
+	m.taskRunner.Stop()
+
+	return scenarioResult{
+		Local: localState(t, exec),
+		Db:    dbState(m),
+	}
+}
+
+func localState(t *testing.T, exec *executer.MemoryExecuter) map[string]string {
+	local := make(map[string]string)
+	err := exec.WalkDir(scenarioSyncDir, func(p string, isDir bool, continueDescending *bool) {
+		*continueDescending = true
+		rel := strings.TrimPrefix(p, scenarioSyncDir+"/")
+		if isDir {
+			local[rel+"/"] = ""
+			return
+		}
+		content, err := exec.ReadFile(p)
+		assert.NoError(t, err)
+		local[rel] = string(content)
+	})
+	assert.NoError(t, err)
+	return local
+}
+
+func dbState(m *FilenMirror) map[string]string {
+	db := make(map[string]string)
+	for p, uuid := range m.osDb.GetPathToUuidMap() {
+		node, _ := m.osDb.GetNode(uuid)
+		if node.IsDir {
+			p += "/"
+		}
+		db[uuid.String()] = p
+	}
+	return db
+}
+
+// normalizeResult treats missing and empty sections alike.
+func normalizeResult(r scenarioResult) scenarioResult {
+	if r.Local == nil {
+		r.Local = map[string]string{}
+	}
+	if r.Db == nil {
+		r.Db = map[string]string{}
+	}
+	return r
+}
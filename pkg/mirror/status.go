@@ -0,0 +1,55 @@
+package mirror
+
+import (
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+)
+
+// Status is a snapshot of what a running mirror is doing.
+type Status struct {
+	SyncDir string `json:"syncDir"`
+	// Connection is the state of the event socket, empty before the first
+	// connection attempt.
+	Connection string `json:"connection,omitempty"`
+	Syncing    bool   `json:"syncing"`
+	// LastSync is when the last full sync ended, nil before the first one.
+	LastSync      *time.Time `json:"lastSync,omitempty"`
+	LastSyncError string     `json:"lastSyncError,omitempty"`
+}
+
+// Healthy reports whether the last full sync succeeded and the event socket
+// is not known to be down.
+func (s Status) Healthy() bool {
+	return s.LastSync != nil && s.LastSyncError == "" && s.Connection != filenextra.ConnectionLost.String()
+}
+
+func (m *FilenMirror) Status() Status {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	return m.status
+}
+
+func (m *FilenMirror) setSyncing() {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	m.status.Syncing = true
+}
+
+func (m *FilenMirror) setSynced(err error) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	now := time.Now()
+	m.status.Syncing = false
+	m.status.LastSync = &now
+	m.status.LastSyncError = ""
+	if err != nil {
+		m.status.LastSyncError = err.Error()
+	}
+}
+
+func (m *FilenMirror) setConnection(state filenextra.ConnectionState) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	m.status.Connection = state.String()
+}
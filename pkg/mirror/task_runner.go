@@ -20,6 +20,11 @@ type TaskRunner struct {
 	tasks  chan Task
 	wg     sync.WaitGroup
 	closed chan struct{}
+	// retire stops one idle worker per value.
+	retire chan struct{}
+
+	mu      sync.Mutex
+	workers int
 }
 
 func NewTaskRunner() *TaskRunner {
@@ -27,23 +32,56 @@ func NewTaskRunner() *TaskRunner {
 	return &TaskRunner{
 		tasks:  make(chan Task, bufferSize),
 		closed: make(chan struct{}),
+		retire: make(chan struct{}),
 	}
 }
 
 func (s *TaskRunner) Start(workerCount int) {
-	s.wg.Add(workerCount)
+	s.SetWorkers(workerCount)
+}
 
-	for range workerCount {
+// SetWorkers changes the number of workers. Surplus workers stop once they
+// finished their current task.
+func (s *TaskRunner) SetWorkers(workerCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if workerCount > s.workers {
+		s.wg.Add(workerCount - s.workers)
+		for range workerCount - s.workers {
+			go s.work()
+		}
+	} else if workerCount < s.workers {
+		surplus := s.workers - workerCount
 		go func() {
-			for task := range s.tasks {
-				err := task.Execute()
-				if err != nil {
-					log.Error().Err(err).Msg("Task execution failed")
+			for range surplus {
+				select {
+				case s.retire <- struct{}{}:
+				case <-s.closed:
+					return
 				}
 			}
-			s.wg.Done()
 		}()
 	}
+	s.workers = workerCount
+}
+
+func (s *TaskRunner) work() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.retire:
+			return
+		case task, ok := <-s.tasks:
+			if !ok {
+				return
+			}
+			err := task.Execute()
+			if err != nil {
+				log.Error().Err(err).Msg("Task execution failed")
+			}
+		}
+	}
 }
 
 func (s *TaskRunner) Schedule(task Task) error {
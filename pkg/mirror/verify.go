@@ -0,0 +1,91 @@
+package mirror
+
+import (
+	"context"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filedb"
+)
+
+type MismatchKind string
+
+const (
+	// MismatchMissing is a remote file that does not exist locally.
+	MismatchMissing MismatchKind = "missing"
+	// MismatchNotAFile is a remote file that is something else locally.
+	MismatchNotAFile MismatchKind = "not-a-file"
+	// MismatchHash is a local file whose content differs from the remote
+	// file.
+	MismatchHash MismatchKind = "hash"
+	// MismatchUnreadable is a local file that could not be read.
+	MismatchUnreadable MismatchKind = "unreadable"
+)
+
+// Mismatch is a remote file whose local copy is not intact.
+type Mismatch struct {
+	Kind MismatchKind `json:"kind"`
+	// Path is relative to the sync directory.
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"`
+}
+
+// Verify hashes every local copy of a remote file and compares it with the
+// SHA-512 hash Filen keeps for the file, sorted by path. Remote files
+// without a hash are skipped. Local files missing remotely are not reported,
+// see Plan for those.
+func (m *FilenMirror) Verify(ctx context.Context) ([]Mismatch, error) {
+	files, dirs, err := m.store.ListRecursive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remoteDb := m.buildRemoteDb(files, dirs)
+
+	var mismatches []Mismatch
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if file.Hash == "" {
+			continue
+		}
+		relPath, ok := remoteDb.GetPath(filedb.UuidFromString(file.UUID))
+		if !ok {
+			continue
+		}
+		mismatch, ok := m.verifyFile(relPath, file.Hash)
+		if !ok {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	slices.SortFunc(mismatches, func(a, b Mismatch) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return mismatches, nil
+}
+
+func (m *FilenMirror) verifyFile(relPath, want string) (Mismatch, bool) {
+	localPath := path.Join(m.syncDir, relPath)
+	info, err := m.executer.Stat(localPath)
+	if os.IsNotExist(err) {
+		return Mismatch{Kind: MismatchMissing, Path: relPath}, false
+	}
+	if err != nil {
+		return Mismatch{Kind: MismatchUnreadable, Path: relPath, Error: err.Error()}, false
+	}
+	if info.IsDir() {
+		return Mismatch{Kind: MismatchNotAFile, Path: relPath}, false
+	}
+
+	got, err := m.executer.CalculateHash(localPath)
+	if err != nil {
+		return Mismatch{Kind: MismatchUnreadable, Path: relPath, Error: err.Error()}, false
+	}
+	if !strings.EqualFold(got, want) {
+		return Mismatch{Kind: MismatchHash, Path: relPath}, false
+	}
+	return Mismatch{}, true
+}
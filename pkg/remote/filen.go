@@ -0,0 +1,92 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/types"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+)
+
+type FilenStore struct {
+	mu     sync.Mutex
+	client *filen.Filen
+}
+
+func NewFilenStore(client *filen.Filen) *FilenStore {
+	return &FilenStore{
+		client: client,
+	}
+}
+
+// SetClient replaces the client, e.g. after logging in again.
+func (s *FilenStore) SetClient(client *filen.Filen) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.client = client
+}
+
+func (s *FilenStore) currentClient() *filen.Filen {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+func (s *FilenStore) BaseFolderUUID() string {
+	return s.currentClient().BaseFolder.UUID
+}
+
+func (s *FilenStore) ListRecursive(ctx context.Context) ([]File, []Directory, error) {
+	client := s.currentClient()
+	allFiles, allDirs, err := client.ListRecursive(ctx, types.DirectoryInterface(client.BaseFolder))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]File, 0, len(allFiles))
+	for _, file := range allFiles {
+		files = append(files, fileFromFilen(file))
+	}
+
+	dirs := make([]Directory, 0, len(allDirs))
+	for _, dir := range allDirs {
+		dirs = append(dirs, Directory{
+			UUID:       dir.UUID,
+			ParentUUID: dir.ParentUUID,
+			Name:       dir.Name,
+		})
+	}
+
+	return files, dirs, nil
+}
+
+func (s *FilenStore) FileInfo(ctx context.Context, uuid string) (File, error) {
+	file, err := filenextra.GetFile(ctx, s.currentClient(), uuid)
+	if errors.Is(err, filenextra.ErrFileNotFound) {
+		return File{}, fmt.Errorf("file %s: %w", uuid, ErrNotFound)
+	}
+	if err != nil {
+		return File{}, err
+	}
+	return fileFromFilen(file), nil
+}
+
+func (s *FilenStore) Download(ctx context.Context, uuid string) (io.ReadCloser, error) {
+	return filenextra.CreateDownloadReader(ctx, s.currentClient(), uuid)
+}
+
+func fileFromFilen(file *types.File) File {
+	return File{
+		UUID:         file.UUID,
+		ParentUUID:   file.ParentUUID,
+		Name:         file.Name,
+		Size:         int64(file.Size),
+		MimeType:     file.MimeType,
+		Hash:         file.Hash,
+		LastModified: file.LastModified,
+	}
+}
@@ -0,0 +1,38 @@
+package remote_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filentest"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+var account = filentest.Account{
+	Email:    "user@example.com",
+	Password: "password",
+}
+
+func TestFilenStoreFileInfo(t *testing.T) {
+	srv := filentest.Start(t, account)
+	client, err := filen.New(srv.Context(context.Background()), account.Email, account.Password, "XXXXXX")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	store := remote.NewFilenStore(client)
+
+	uuid, err := srv.AddFile(srv.BaseFolderUUID(), "file1.txt", []byte("hello"), time.UnixMilli(1000))
+	assert.NoError(t, err)
+	file, err := store.FileInfo(context.Background(), uuid)
+	assert.NoError(t, err)
+	assert.Equal(t, "file1.txt", file.Name)
+	assert.Equal(t, srv.BaseFolderUUID(), file.ParentUUID)
+
+	assert.NoError(t, srv.TrashFile(uuid))
+	_, err = store.FileInfo(context.Background(), uuid)
+	assert.True(t, errors.Is(err, remote.ErrNotFound), err)
+}
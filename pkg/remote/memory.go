@@ -0,0 +1,341 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"sync"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+)
+
+// MemoryStore is an in-memory RemoteStore for tests. Changes are scripted
+// through its methods, each of which queues the socket event Filen would
+// send for it. MemoryStore is also the EventSource for these events.
+type MemoryStore struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	baseUUID string
+	files    map[string]*memoryFile
+	dirs     map[string]Directory
+	events   []filenextra.TypedEvent
+	closed   bool
+}
+
+type memoryFile struct {
+	File
+	content []byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		baseUUID: newUuid(),
+		files:    make(map[string]*memoryFile),
+		dirs:     make(map[string]Directory),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *MemoryStore) BaseFolderUUID() string {
+	return s.baseUUID
+}
+
+func (s *MemoryStore) ListRecursive(ctx context.Context) ([]File, []Directory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files := make([]File, 0, len(s.files))
+	for _, f := range s.files {
+		files = append(files, f.File)
+	}
+	dirs := make([]Directory, 0, len(s.dirs))
+	for _, d := range s.dirs {
+		dirs = append(dirs, d)
+	}
+	return files, dirs, nil
+}
+
+func (s *MemoryStore) FileInfo(ctx context.Context, uuid string) (File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[uuid]
+	if !ok {
+		return File{}, fmt.Errorf("file %s: %w", uuid, ErrNotFound)
+	}
+	return f.File, nil
+}
+
+func (s *MemoryStore) Download(ctx context.Context, uuid string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[uuid]
+	if !ok {
+		return nil, fmt.Errorf("file %s: %w", uuid, ErrNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(f.content)), nil
+}
+
+// SetFile adds or replaces a file without emitting an event, for setting up
+// the initial tree.
+func (s *MemoryStore) SetFile(uuid, parentUuid, name string, content []byte, modTime time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setFile(uuid, parentUuid, name, content, modTime)
+}
+
+// SetDir adds or replaces a directory without emitting an event.
+func (s *MemoryStore) SetDir(uuid, parentUuid, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirs[uuid] = Directory{UUID: uuid, ParentUUID: parentUuid, Name: name}
+}
+
+func (s *MemoryStore) CreateFile(parentUuid, name string, content []byte, modTime time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uuid := newUuid()
+	f := s.setFile(uuid, parentUuid, name, content, modTime)
+	s.push("file-new", &filenextra.EventSocketFileNew{
+		Parent: parentUuid,
+		UUID:   uuid,
+		Meta:   f.metadata(),
+		Time:   time.Now().UnixMilli(),
+	})
+	return uuid
+}
+
+func (s *MemoryStore) CreateDir(parentUuid, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uuid := newUuid()
+	s.dirs[uuid] = Directory{UUID: uuid, ParentUUID: parentUuid, Name: name}
+	s.push("folder-sub-created", &filenextra.EventSocketFolderSubCreated{
+		Name:      filenextra.NameStruct{Name: name},
+		UUID:      uuid,
+		Parent:    parentUuid,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return uuid
+}
+
+func (s *MemoryStore) RenameFile(uuid, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[uuid]
+	if !ok {
+		return fmt.Errorf("file %s: %w", uuid, ErrNotFound)
+	}
+	f.Name = name
+	s.push("file-rename", &filenextra.EventSocketFileRename{
+		UUID: uuid,
+		Meta: f.metadata(),
+	})
+	return nil
+}
+
+func (s *MemoryStore) MoveFile(uuid, parentUuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[uuid]
+	if !ok {
+		return fmt.Errorf("file %s: %w", uuid, ErrNotFound)
+	}
+	f.ParentUUID = parentUuid
+	s.push("file-move", &filenextra.EventSocketFileMove{
+		Parent: parentUuid,
+		UUID:   uuid,
+		Meta:   f.metadata(),
+		Time:   time.Now().UnixMilli(),
+	})
+	return nil
+}
+
+func (s *MemoryStore) TrashFile(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.files[uuid]; !ok {
+		return fmt.Errorf("file %s: %w", uuid, ErrNotFound)
+	}
+	delete(s.files, uuid)
+	s.push("file-trash", &filenextra.EventSocketFileTrash{UUID: uuid})
+	return nil
+}
+
+func (s *MemoryStore) DeleteFilePermanent(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.files[uuid]; !ok {
+		return fmt.Errorf("file %s: %w", uuid, ErrNotFound)
+	}
+	delete(s.files, uuid)
+	s.push("file-deleted-permanent", &filenextra.EventSocketFileDeletedPermanent{UUID: uuid})
+	return nil
+}
+
+func (s *MemoryStore) RenameDir(uuid, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.dirs[uuid]
+	if !ok {
+		return fmt.Errorf("directory %s: %w", uuid, ErrNotFound)
+	}
+	d.Name = name
+	s.dirs[uuid] = d
+	s.push("folder-rename", &filenextra.EventSocketFolderRename{
+		Name: filenextra.NameStruct{Name: name},
+		UUID: uuid,
+	})
+	return nil
+}
+
+func (s *MemoryStore) MoveDir(uuid, parentUuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.dirs[uuid]
+	if !ok {
+		return fmt.Errorf("directory %s: %w", uuid, ErrNotFound)
+	}
+	d.ParentUUID = parentUuid
+	s.dirs[uuid] = d
+	s.push("folder-move", &filenextra.EventSocketFolderMove{
+		Name:      filenextra.NameStruct{Name: d.Name},
+		UUID:      uuid,
+		Parent:    parentUuid,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return nil
+}
+
+// TrashDir removes a directory together with everything below it.
+func (s *MemoryStore) TrashDir(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.dirs[uuid]
+	if !ok {
+		return fmt.Errorf("directory %s: %w", uuid, ErrNotFound)
+	}
+	s.removeDir(uuid)
+	s.push("folder-trash", &filenextra.EventSocketFolderTrash{
+		Parent: d.ParentUUID,
+		UUID:   uuid,
+	})
+	return nil
+}
+
+// Emit queues evt without changing the store, e.g. to script events that
+// disagree with the listing.
+func (s *MemoryStore) Emit(evt filenextra.TypedEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, evt)
+	s.cond.Broadcast()
+}
+
+// Start is a no-op; events are queued as soon as changes are scripted.
+func (s *MemoryStore) Start() {
+}
+
+func (s *MemoryStore) NextEvent() (filenextra.TypedEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.events) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if len(s.events) == 0 {
+		return filenextra.TypedEvent{}, false
+	}
+
+	evt := s.events[0]
+	s.events = s.events[1:]
+	return evt, true
+}
+
+// Close ends the event stream once all queued events were consumed.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.cond.Broadcast()
+	return nil
+}
+
+func (s *MemoryStore) setFile(uuid, parentUuid, name string, content []byte, modTime time.Time) *memoryFile {
+	hash := sha512.Sum512(content)
+	f := &memoryFile{
+		File: File{
+			UUID:         uuid,
+			ParentUUID:   parentUuid,
+			Name:         name,
+			Size:         int64(len(content)),
+			MimeType:     mime.TypeByExtension(path.Ext(name)),
+			Hash:         hex.EncodeToString(hash[:]),
+			LastModified: modTime,
+		},
+		content: bytes.Clone(content),
+	}
+	s.files[uuid] = f
+	return f
+}
+
+func (s *MemoryStore) removeDir(uuid string) {
+	delete(s.dirs, uuid)
+	for fileUuid, f := range s.files {
+		if f.ParentUUID == uuid {
+			delete(s.files, fileUuid)
+		}
+	}
+	for dirUuid, d := range s.dirs {
+		if d.ParentUUID == uuid {
+			s.removeDir(dirUuid)
+		}
+	}
+}
+
+func (s *MemoryStore) push(name string, data any) {
+	s.events = append(s.events, filenextra.TypedEvent{
+		Name: name,
+		Data: data,
+	})
+	s.cond.Broadcast()
+}
+
+// metadata returns the file metadata as it arrives in socket events.
+func (f *memoryFile) metadata() filenextra.FileMetadata {
+	return filenextra.FileMetadata{
+		Name:         f.Name,
+		Size:         f.Size,
+		Mime:         f.MimeType,
+		LastModified: f.LastModified.UnixMilli(),
+		Hash:         f.Hash,
+	}
+}
+
+func newUuid() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
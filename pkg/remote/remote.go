@@ -0,0 +1,44 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+)
+
+// ErrNotFound is returned by RemoteStore.FileInfo for files that do not
+// exist.
+var ErrNotFound = errors.New("item not found")
+
+type File struct {
+	UUID         string
+	ParentUUID   string
+	Name         string
+	Size         int64
+	MimeType     string
+	Hash         string
+	LastModified time.Time
+}
+
+type Directory struct {
+	UUID       string
+	ParentUUID string
+	Name       string
+}
+
+// RemoteStore is the cloud side of the mirror.
+type RemoteStore interface {
+	BaseFolderUUID() string
+	ListRecursive(ctx context.Context) ([]File, []Directory, error)
+	FileInfo(ctx context.Context, uuid string) (File, error)
+	Download(ctx context.Context, uuid string) (io.ReadCloser, error)
+}
+
+// EventSource delivers change events of a RemoteStore.
+type EventSource interface {
+	Start()
+	NextEvent() (filenextra.TypedEvent, bool)
+}
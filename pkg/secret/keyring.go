@@ -0,0 +1,147 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrWrongPassphrase is returned if a keyring cannot be decrypted.
+var ErrWrongPassphrase = errors.New("wrong keyring passphrase or corrupted keyring")
+
+const keyringVersion = 1
+
+// keyringFile is the JSON stored on disk. The entries are encrypted with
+// AES-256-GCM under a key derived from the passphrase with Argon2id.
+type keyringFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// LoadKeyring decrypts the keyring at path. The values must be cleared with
+// WipeAll.
+func LoadKeyring(path string, passphrase []byte) (map[string][]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file keyringFile
+	err = json.Unmarshal(b, &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Version != keyringVersion || file.KDF != "argon2id" {
+		return nil, fmt.Errorf("%s: unsupported keyring version %d with kdf %q", path, file.Version, file.KDF)
+	}
+
+	aead, err := keyringCipher(passphrase, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%s: %w", path, ErrWrongPassphrase)
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrWrongPassphrase)
+	}
+	defer Wipe(plaintext)
+
+	var entries map[string][]byte
+	err = json.Unmarshal(plaintext, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if entries == nil {
+		entries = make(map[string][]byte)
+	}
+	return entries, nil
+}
+
+// SaveKeyring encrypts entries with a new salt and replaces the keyring at
+// path, which is only readable by the owner.
+func SaveKeyring(path string, passphrase []byte, entries map[string][]byte) error {
+	if len(passphrase) == 0 {
+		return fmt.Errorf("empty keyring passphrase")
+	}
+	file := keyringFile{
+		Version: keyringVersion,
+		KDF:     "argon2id",
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+		Salt:    make([]byte, 16),
+	}
+	_, err := rand.Read(file.Salt)
+	if err != nil {
+		return err
+	}
+	aead, err := keyringCipher(passphrase, file)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	_, err = rand.Read(file.Nonce)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	file.Data = aead.Seal(nil, file.Nonce, plaintext, nil)
+	Wipe(plaintext)
+
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+func keyringCipher(passphrase []byte, file keyringFile) (cipher.AEAD, error) {
+	if file.Time == 0 || file.Memory == 0 || file.Threads == 0 || len(file.Salt) < 16 {
+		return nil, fmt.Errorf("invalid keyring key derivation parameters")
+	}
+	key := argon2.IDKey(passphrase, file.Salt, file.Time, file.Memory, file.Threads, 32)
+	defer Wipe(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,191 @@
+// Package secret reads credentials from where they are kept: the config
+// itself, environment variables, files such as Docker or Kubernetes secret
+// mounts, or an encrypted keyring file.
+//
+// Secrets are read when they are needed, not when the config is loaded, and
+// handed out as byte slices that the caller clears with Wipe after use.
+// Values passed on to APIs taking strings cannot be cleared.
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNotConfigured is returned for a secret without a source.
+	ErrNotConfigured = errors.New("secret not configured")
+	// ErrNotInKeyring is returned for a name the keyring does not hold.
+	ErrNotInKeyring = errors.New("secret not in keyring")
+)
+
+// DefaultDir is where Docker mounts secrets.
+const DefaultDir = "/run/secrets"
+
+// Ref tells where a secret is read from. At most one field is set. In YAML
+// a plain string is taken as Value, e.g. `password: hunter2` or
+// `password: {file: /run/secrets/filen_password}`.
+type Ref struct {
+	Value   string `yaml:"value"`
+	File    string `yaml:"file"`
+	Env     string `yaml:"env"`
+	Keyring string `yaml:"keyring"`
+}
+
+func (r *Ref) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = Ref{Value: node.Value}
+		return nil
+	}
+	type plain Ref
+	return node.Decode((*plain)(r))
+}
+
+func (r Ref) IsZero() bool {
+	return r == Ref{}
+}
+
+// Validate returns an error if more than one source is set.
+func (r Ref) Validate() error {
+	var sources []string
+	for name, value := range map[string]string{"value": r.Value, "file": r.File, "env": r.Env, "keyring": r.Keyring} {
+		if value != "" {
+			sources = append(sources, name)
+		}
+	}
+	if len(sources) > 1 {
+		return fmt.Errorf("only one of value, file, env and keyring may be set")
+	}
+	return nil
+}
+
+// String describes the source without revealing the secret.
+func (r Ref) String() string {
+	switch {
+	case r.Value != "":
+		return "inline value"
+	case r.File != "":
+		return "file " + r.File
+	case r.Env != "":
+		return "environment variable " + r.Env
+	case r.Keyring != "":
+		return "keyring entry " + r.Keyring
+	default:
+		return "no source"
+	}
+}
+
+// KeyringConfig locates an encrypted keyring file.
+type KeyringConfig struct {
+	Path string `yaml:"path"`
+	// Passphrase unlocks the keyring. A key file is a passphrase read from
+	// a file, e.g. `passphrase: {file: /etc/filen-mirror/keyring.key}`. It
+	// must not refer to the keyring itself.
+	Passphrase Ref `yaml:"passphrase"`
+}
+
+// Resolver reads secrets.
+type Resolver struct {
+	// Dir holds secret mounts, one file per secret named like the secret.
+	// It is consulted for secrets without a configured source. Empty
+	// disables it.
+	Dir     string
+	Keyring KeyringConfig
+	// LookupEnv defaults to os.LookupEnv.
+	LookupEnv func(string) (string, bool)
+}
+
+// Get reads the secret ref points to. Without a source it reads the file
+// called name in Dir, e.g. /run/secrets/filen_password. The result must be
+// cleared with Wipe.
+func (r *Resolver) Get(ref Ref, name string) ([]byte, error) {
+	err := ref.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch {
+	case ref.Value != "":
+		return []byte(ref.Value), nil
+	case ref.File != "":
+		return readFile(ref.File)
+	case ref.Env != "":
+		lookupEnv := r.LookupEnv
+		if lookupEnv == nil {
+			lookupEnv = os.LookupEnv
+		}
+		value, ok := lookupEnv(ref.Env)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%s: environment variable %s is not set: %w", name, ref.Env, ErrNotConfigured)
+		}
+		return []byte(value), nil
+	case ref.Keyring != "":
+		return r.fromKeyring(ref.Keyring)
+	}
+
+	if r.Dir != "" {
+		value, err := readFile(filepath.Join(r.Dir, name))
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			return value, err
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrNotConfigured)
+}
+
+func (r *Resolver) fromKeyring(name string) ([]byte, error) {
+	if r.Keyring.Path == "" {
+		return nil, fmt.Errorf("%s: keyring path not configured: %w", name, ErrNotConfigured)
+	}
+	if r.Keyring.Passphrase.Keyring != "" {
+		return nil, fmt.Errorf("keyring passphrase must not be stored in the keyring")
+	}
+	passphrase, err := r.Get(r.Keyring.Passphrase, "filen_keyring_passphrase")
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(passphrase)
+
+	entries, err := LoadKeyring(r.Keyring.Path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeAll(entries)
+
+	value, ok := entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotInKeyring)
+	}
+	return bytes.Clone(value), nil
+}
+
+// readFile reads a secret file, without the trailing newline editors and
+// `echo` leave.
+func readFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimRight(b, "\r\n")
+	Wipe(b[len(trimmed):])
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	return trimmed, nil
+}
+
+// Wipe overwrites b with zeros.
+func Wipe(b []byte) {
+	clear(b)
+}
+
+// WipeAll wipes all values of entries.
+func WipeAll(entries map[string][]byte) {
+	for _, value := range entries {
+		Wipe(value)
+	}
+}
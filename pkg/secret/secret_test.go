@@ -0,0 +1,95 @@
+package secret_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/secret"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRefYAML(t *testing.T) {
+	var cfg struct {
+		Plain  secret.Ref `yaml:"plain"`
+		File   secret.Ref `yaml:"file"`
+		Unset  secret.Ref `yaml:"unset"`
+		Broken secret.Ref `yaml:"broken"`
+	}
+	err := yaml.Unmarshal([]byte(`
+plain: hunter2
+file: {file: /run/secrets/filen_password}
+broken: {file: /a, env: B}
+`), &cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, secret.Ref{Value: "hunter2"}, cfg.Plain)
+	assert.Equal(t, secret.Ref{File: "/run/secrets/filen_password"}, cfg.File)
+	assert.True(t, cfg.Unset.IsZero())
+	assert.Error(t, cfg.Broken.Validate())
+	assert.Equal(t, "file /run/secrets/filen_password", cfg.File.String())
+}
+
+func TestResolverGet(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "filen_password"), []byte("from-dir\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other"), []byte("from-file\r\n"), 0o600))
+	r := &secret.Resolver{
+		Dir: dir,
+		LookupEnv: func(name string) (string, bool) {
+			return map[string]string{"PASSWORD": "from-env"}[name], name == "PASSWORD"
+		},
+	}
+
+	for ref, want := range map[secret.Ref]string{
+		{Value: "inline"}:                   "inline",
+		{File: filepath.Join(dir, "other")}: "from-file",
+		{Env: "PASSWORD"}:                   "from-env",
+		{}:                                  "from-dir",
+	} {
+		value, err := r.Get(ref, "filen_password")
+		assert.NoError(t, err, ref.String())
+		assert.Equal(t, want, string(value), ref.String())
+		secret.Wipe(value)
+		assert.Equal(t, make([]byte, len(want)), value)
+	}
+
+	_, err := r.Get(secret.Ref{}, "filen_totp_secret")
+	assert.ErrorIs(t, err, secret.ErrNotConfigured)
+	_, err = r.Get(secret.Ref{Env: "UNSET"}, "filen_password")
+	assert.ErrorIs(t, err, secret.ErrNotConfigured)
+	_, err = r.Get(secret.Ref{Keyring: "filen_password"}, "filen_password")
+	assert.ErrorIs(t, err, secret.ErrNotConfigured)
+}
+
+func TestKeyring(t *testing.T) {
+	dir := t.TempDir()
+	keyringPath := filepath.Join(dir, "keyring.json")
+	keyFile := filepath.Join(dir, "keyring.key")
+	assert.NoError(t, os.WriteFile(keyFile, []byte("correct horse battery staple\n"), 0o600))
+
+	err := secret.SaveKeyring(keyringPath, []byte("correct horse battery staple"), map[string][]byte{
+		"filen_password": []byte("hunter2"),
+	})
+	assert.NoError(t, err)
+	info, err := os.Stat(keyringPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	content, err := os.ReadFile(keyringPath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "hunter2")
+
+	r := &secret.Resolver{Keyring: secret.KeyringConfig{
+		Path:       keyringPath,
+		Passphrase: secret.Ref{File: keyFile},
+	}}
+	value, err := r.Get(secret.Ref{Keyring: "filen_password"}, "filen_password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", string(value))
+
+	_, err = r.Get(secret.Ref{Keyring: "missing"}, "missing")
+	assert.ErrorIs(t, err, secret.ErrNotInKeyring)
+
+	_, err = secret.LoadKeyring(keyringPath, []byte("wrong"))
+	assert.ErrorIs(t, err, secret.ErrWrongPassphrase)
+}
@@ -0,0 +1,617 @@
+// Package socketio is a Socket.IO client over the websocket transport of
+// Engine.IO protocol 3 (Socket.IO 2) and 4 (Socket.IO 3 and later).
+//
+// A Client connects to a single namespace and reconnects whenever the
+// connection is lost. Handlers run on the goroutine reading the connection,
+// one at a time and in the order the packets arrived. Frames are written by
+// a single writer goroutine per connection, so Emit may be called
+// concurrently.
+package socketio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrNotConnected     = errors.New("socket.io client is not connected")
+	ErrClosed           = errors.New("socket.io client is closed")
+	ErrHeartbeatTimeout = errors.New("socket.io heartbeat timed out")
+	ErrServerClosed     = errors.New("socket.io server closed the connection")
+	ErrConnectRefused   = errors.New("socket.io server refused the connection")
+)
+
+// Protocol is the Engine.IO protocol version.
+type Protocol int
+
+const (
+	EIO3 Protocol = 3
+	EIO4 Protocol = 4
+)
+
+type Config struct {
+	// URL is the base URL of the server, e.g. wss://example.com.
+	URL string
+	// Path is appended to URL. Defaults to /socket.io/.
+	Path string
+	// Protocol defaults to EIO4.
+	Protocol Protocol
+	// Namespace defaults to "/".
+	Namespace string
+	// Auth is sent with the connect packet. It is only supported by EIO4.
+	Auth any
+	// Header is sent with the websocket handshake.
+	Header http.Header
+	// Backoff is the delay between reconnection attempts. Defaults to
+	// DefaultBackoff.
+	Backoff Backoff
+	// WriteTimeout bounds every frame written. A write that does not finish
+	// in time drops the connection. Defaults to 10s.
+	WriteTimeout time.Duration
+	// SendQueueSize is the number of writes that may wait for the writer.
+	// Defaults to 64.
+	SendQueueSize int
+
+	// OnConnect is called once the namespace is connected, after every
+	// reconnect.
+	OnConnect func()
+	// OnEvent is called for every event sent to the namespace.
+	OnEvent func(Event)
+	// OnStateChange is called whenever the state changes. err tells why a
+	// connection attempt failed or the connection was lost.
+	OnStateChange func(state State, err error)
+}
+
+// Event is an event received from the server.
+type Event struct {
+	Name string
+	Args []json.RawMessage
+	// Attachments holds the binary data referenced by placeholders in Args.
+	Attachments [][]byte
+
+	client   *Client
+	id       int64
+	wantsAck bool
+}
+
+// Binary returns the attachment that argument i stands for.
+func (e Event) Binary(i int) ([]byte, bool) {
+	if i >= len(e.Args) {
+		return nil, false
+	}
+	var p placeholder
+	if json.Unmarshal(e.Args[i], &p) != nil || !p.Placeholder || p.Num < 0 || p.Num >= len(e.Attachments) {
+		return nil, false
+	}
+	return e.Attachments[p.Num], true
+}
+
+// WantsAck reports whether the server asked for an acknowledgement.
+func (e Event) WantsAck() bool {
+	return e.wantsAck
+}
+
+// Ack acknowledges the event with args. It does nothing if the server did
+// not ask for an acknowledgement.
+func (e Event) Ack(args ...any) error {
+	if !e.wantsAck {
+		return nil
+	}
+	return e.client.send(packetAck, e.id, true, args)
+}
+
+type ackResult struct {
+	args []json.RawMessage
+	err  error
+}
+
+type Client struct {
+	cfg Config
+	url string
+
+	mu      sync.Mutex
+	current *connection
+	state   State
+	nextID  int64
+	acks    map[int64]chan ackResult
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	startOnce sync.Once
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Path == "" {
+		cfg.Path = "/socket.io/"
+	}
+	if cfg.Protocol == 0 {
+		cfg.Protocol = EIO4
+	}
+	if cfg.Protocol != EIO3 && cfg.Protocol != EIO4 {
+		return nil, fmt.Errorf("unsupported Engine.IO protocol %d", cfg.Protocol)
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = "/"
+	}
+	if !strings.HasPrefix(cfg.Namespace, "/") {
+		return nil, fmt.Errorf("namespace %q must start with /", cfg.Namespace)
+	}
+	if cfg.Backoff == (Backoff{}) {
+		cfg.Backoff = DefaultBackoff
+	}
+	cfg.Backoff.Max = max(cfg.Backoff.Max, cfg.Backoff.Initial)
+	cfg.Backoff.Multiplier = max(cfg.Backoff.Multiplier, 1)
+	cfg.Backoff.Jitter = min(max(cfg.Backoff.Jitter, 0), 1)
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.SendQueueSize <= 0 {
+		cfg.SendQueueSize = 64
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(cfg.URL, "/") + cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("socket.io URL %q must use ws or wss", cfg.URL)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		cfg:    cfg,
+		url:    u.String(),
+		acks:   make(map[int64]chan ackResult),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Start connects in the background. Connection failures are retried until
+// Close is called.
+func (c *Client) Start() {
+	c.startOnce.Do(func() {
+		go c.run()
+	})
+}
+
+// Close disconnects and waits for the running handlers to return.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+
+		c.mu.Lock()
+		cn := c.current
+		c.mu.Unlock()
+		if cn != nil {
+			_ = c.send(packetDisconnect, 0, false, nil)
+			_ = cn.conn.Close()
+		}
+	})
+	// a client that was never started has nothing to wait for
+	c.startOnce.Do(func() {
+		close(c.done)
+	})
+	<-c.done
+	c.setState(StateClosed, nil)
+	return nil
+}
+
+func (c *Client) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Connected reports whether the namespace is currently connected.
+func (c *Client) Connected() bool {
+	return c.State() == StateConnected
+}
+
+// setState must only be called from the goroutine running the connection,
+// or after it ended.
+func (c *Client) setState(state State, err error) {
+	c.mu.Lock()
+	changed := c.state != state
+	c.state = state
+	c.mu.Unlock()
+
+	if changed && c.cfg.OnStateChange != nil {
+		c.cfg.OnStateChange(state, err)
+	}
+}
+
+// Emit sends an event. Top level []byte arguments are sent as binary
+// attachments.
+func (c *Client) Emit(event string, args ...any) error {
+	return c.send(packetEvent, 0, false, append([]any{event}, args...))
+}
+
+// EmitWithAck sends an event and waits for the server to acknowledge it. It
+// must not be called from a handler, as those block the reading of the
+// acknowledgement.
+func (c *Client) EmitWithAck(ctx context.Context, event string, args ...any) ([]json.RawMessage, error) {
+	result := make(chan ackResult, 1)
+	c.mu.Lock()
+	id := c.nextID
+	c.nextID++
+	c.acks[id] = result
+	c.mu.Unlock()
+
+	err := c.send(packetEvent, id, true, append([]any{event}, args...))
+	if err != nil {
+		c.removeAck(id)
+		return nil, err
+	}
+
+	select {
+	case r := <-result:
+		return r.args, r.err
+	case <-ctx.Done():
+		c.removeAck(id)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) removeAck(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.acks, id)
+}
+
+func (c *Client) send(typ packetType, id int64, hasID bool, args []any) error {
+	p := packet{
+		typ:       typ,
+		namespace: c.cfg.Namespace,
+		id:        id,
+		hasID:     hasID,
+	}
+
+	var attachments [][]byte
+	if args != nil {
+		var err error
+		p.data, attachments, err = encodeArgs(args)
+		if err != nil {
+			return err
+		}
+	}
+	if len(attachments) > 0 {
+		p.attachments = len(attachments)
+		switch typ {
+		case packetEvent:
+			p.typ = packetBinaryEvent
+		case packetAck:
+			p.typ = packetBinaryAck
+		}
+	}
+
+	c.mu.Lock()
+	cn, state := c.current, c.state
+	c.mu.Unlock()
+	if cn == nil || state != StateConnected {
+		return ErrNotConnected
+	}
+
+	frames := []frame{{websocket.TextMessage, []byte(string(engineMessage) + p.encode())}}
+	for _, attachment := range attachments {
+		if c.cfg.Protocol == EIO3 {
+			attachment = append([]byte{engineMessage - '0'}, attachment...)
+		}
+		frames = append(frames, frame{websocket.BinaryMessage, attachment})
+	}
+	return cn.enqueue(frames...)
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+
+	attempt := 0
+	for {
+		c.setState(StateConnecting, nil)
+		err := c.session()
+		if c.disconnected() {
+			attempt = 0
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.setState(StateDisconnected, err)
+
+		delay := c.cfg.Backoff.Delay(attempt)
+		attempt++
+		log.Warn().Err(err).Msgf("Socket.IO connection lost, reconnecting in %s", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+type openPayload struct {
+	SID          string `json:"sid"`
+	PingInterval int64  `json:"pingInterval"`
+	PingTimeout  int64  `json:"pingTimeout"`
+}
+
+// session connects and reads until the connection fails. All frames of the
+// session are written by the writer of its connection.
+func (c *Client) session() (err error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	query.Set("EIO", strconv.Itoa(int(c.cfg.Protocol)))
+	query.Set("transport", "websocket")
+	query.Set("t", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	u.RawQuery = query.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(c.ctx, u.String(), c.cfg.Header)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	open, err := readOpen(conn)
+	if err != nil {
+		return err
+	}
+	heartbeat := time.Duration(open.PingInterval+open.PingTimeout) * time.Millisecond
+
+	cn := newConnection(conn, c.cfg.WriteTimeout, c.cfg.SendQueueSize)
+	defer func() {
+		_ = conn.Close()
+		cn.close()
+		// a failed write closes the connection, which is the real cause of
+		// the failed read
+		select {
+		case writeErr := <-cn.writeErr:
+			err = writeErr
+		default:
+		}
+	}()
+
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.current = cn
+	c.mu.Unlock()
+
+	// the default namespace of Socket.IO 2 is joined without asking
+	if c.cfg.Protocol == EIO4 || c.cfg.Namespace != "/" {
+		var args []any
+		if c.cfg.Protocol == EIO4 && c.cfg.Auth != nil {
+			args = []any{c.cfg.Auth}
+		}
+		err = c.sendConnect(cn, args)
+		if err != nil {
+			return err
+		}
+	}
+
+	stopPing := make(chan struct{})
+	defer close(stopPing)
+	if c.cfg.Protocol == EIO3 {
+		go pingLoop(cn, time.Duration(open.PingInterval)*time.Millisecond, stopPing)
+	}
+
+	r := &reader{client: c, conn: cn}
+	for {
+		if heartbeat > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(heartbeat))
+		}
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return ErrHeartbeatTimeout
+			}
+			return err
+		}
+
+		if messageType == websocket.BinaryMessage {
+			err = r.handleBinary(message)
+		} else {
+			err = r.handleText(string(message))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sendConnect sends the connect packet, which may carry the auth payload.
+func (c *Client) sendConnect(cn *connection, args []any) error {
+	p := packet{typ: packetConnect, namespace: c.cfg.Namespace}
+	if len(args) > 0 {
+		data, err := json.Marshal(args[0])
+		if err != nil {
+			return err
+		}
+		p.data = data
+	}
+	return cn.writeText(string(engineMessage) + p.encode())
+}
+
+func readOpen(conn *websocket.Conn) (openPayload, error) {
+	_ = conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return openPayload{}, err
+	}
+	if len(message) == 0 || message[0] != engineOpen {
+		return openPayload{}, fmt.Errorf("%w: expected open packet, got %q", ErrInvalidPacket, message)
+	}
+
+	var open openPayload
+	err = json.Unmarshal(message[1:], &open)
+	if err != nil {
+		return openPayload{}, fmt.Errorf("%w: open packet: %w", ErrInvalidPacket, err)
+	}
+	return open, nil
+}
+
+// pingLoop sends the pings of EIO3, where the client starts the heartbeat.
+func pingLoop(cn *connection, interval time.Duration, stop chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := cn.writeText(string(enginePing))
+			if err != nil {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// disconnected drops the connection and fails pending acks. It reports
+// whether the namespace had been connected.
+func (c *Client) disconnected() bool {
+	c.mu.Lock()
+	wasConnected := c.state == StateConnected
+	c.current = nil
+	acks := c.acks
+	c.acks = make(map[int64]chan ackResult)
+	c.mu.Unlock()
+
+	for _, result := range acks {
+		result <- ackResult{err: ErrNotConnected}
+	}
+	return wasConnected
+}
+
+// reader assembles packets from the frames of one connection.
+type reader struct {
+	client  *Client
+	conn    *connection
+	pending *packet
+	buffers [][]byte
+}
+
+func (r *reader) handleText(message string) error {
+	if message == "" {
+		return nil
+	}
+
+	switch message[0] {
+	case enginePing:
+		return r.conn.writeText(string(enginePong) + message[1:])
+	case enginePong, engineNoop:
+		return nil
+	case engineClose:
+		return ErrServerClosed
+	case engineMessage:
+		p, err := decodePacket(message[1:])
+		if err != nil {
+			log.Warn().Err(err).Msg("Dropping Socket.IO packet")
+			return nil
+		}
+		if p.attachments > 0 {
+			r.pending = &p
+			r.buffers = nil
+			return nil
+		}
+		return r.client.handlePacket(p, nil)
+	default:
+		return nil
+	}
+}
+
+func (r *reader) handleBinary(message []byte) error {
+	if r.client.cfg.Protocol == EIO3 {
+		if len(message) == 0 || message[0] != engineMessage-'0' {
+			return nil
+		}
+		message = message[1:]
+	}
+	if r.pending == nil {
+		log.Warn().Msg("Dropping unexpected Socket.IO attachment")
+		return nil
+	}
+
+	r.buffers = append(r.buffers, message)
+	if len(r.buffers) < r.pending.attachments {
+		return nil
+	}
+	p, buffers := *r.pending, r.buffers
+	r.pending, r.buffers = nil, nil
+	return r.client.handlePacket(p, buffers)
+}
+
+func (c *Client) handlePacket(p packet, attachments [][]byte) error {
+	if p.namespace != c.cfg.Namespace {
+		return nil
+	}
+
+	switch p.typ {
+	case packetConnect:
+		c.setState(StateConnected, nil)
+		if c.cfg.OnConnect != nil {
+			c.cfg.OnConnect()
+		}
+	case packetDisconnect:
+		return ErrServerClosed
+	case packetConnectError:
+		return fmt.Errorf("%w: %s", ErrConnectRefused, p.data)
+	case packetEvent, packetBinaryEvent:
+		var args []json.RawMessage
+		if json.Unmarshal(p.data, &args) != nil || len(args) == 0 {
+			log.Warn().Str("payload", string(p.data)).Msg("Dropping malformed Socket.IO event")
+			return nil
+		}
+		var name string
+		if json.Unmarshal(args[0], &name) != nil {
+			log.Warn().Str("payload", string(p.data)).Msg("Dropping Socket.IO event without name")
+			return nil
+		}
+		if c.cfg.OnEvent != nil {
+			c.cfg.OnEvent(Event{
+				Name:        name,
+				Args:        args[1:],
+				Attachments: attachments,
+				client:      c,
+				id:          p.id,
+				wantsAck:    p.hasID,
+			})
+		}
+	case packetAck, packetBinaryAck:
+		var args []json.RawMessage
+		_ = json.Unmarshal(p.data, &args)
+		c.mu.Lock()
+		result, ok := c.acks[p.id]
+		delete(c.acks, p.id)
+		c.mu.Unlock()
+		if ok {
+			result <- ackResult{args: args}
+		}
+	}
+	return nil
+}
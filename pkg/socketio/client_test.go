@@ -0,0 +1,385 @@
+package socketio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const waitFor = 5 * time.Second
+const tick = 5 * time.Millisecond
+
+type recorder struct {
+	events      chan Event
+	connects    atomic.Int32
+	disconnects chan error
+
+	mu     sync.Mutex
+	states []State
+}
+
+func (r *recorder) stateHistory() []State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.states)
+}
+
+func newTestClient(t *testing.T, srv *testServer, cfg Config) (*Client, *recorder) {
+	rec := &recorder{
+		events:      make(chan Event, 16),
+		disconnects: make(chan error, 16),
+	}
+	cfg.URL = srv.URL()
+	cfg.Backoff = Backoff{Initial: 10 * time.Millisecond, Max: 10 * time.Millisecond}
+	cfg.OnConnect = func() { rec.connects.Add(1) }
+	cfg.OnEvent = func(e Event) { rec.events <- e }
+	cfg.OnStateChange = func(state State, err error) {
+		rec.mu.Lock()
+		rec.states = append(rec.states, state)
+		rec.mu.Unlock()
+		if state == StateDisconnected {
+			rec.disconnects <- err
+		}
+	}
+
+	c, err := NewClient(cfg)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	c.Start()
+	t.Cleanup(func() { _ = c.Close() })
+	return c, rec
+}
+
+func nextEvent(t *testing.T, rec *recorder) Event {
+	select {
+	case e := <-rec.events:
+		return e
+	case <-time.After(waitFor):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func nextServerEvent(t *testing.T, srv *testServer) serverEvent {
+	select {
+	case e := <-srv.received:
+		return e
+	case <-time.After(waitFor):
+		t.Fatal("no event received by the server")
+		return serverEvent{}
+	}
+}
+
+func forEachProtocol(t *testing.T, test func(t *testing.T, protocol Protocol)) {
+	for _, protocol := range []Protocol{EIO3, EIO4} {
+		t.Run(fmt.Sprintf("EIO%d", protocol), func(t *testing.T) {
+			test(t, protocol)
+		})
+	}
+}
+
+func TestEventsAndAcks(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, protocol Protocol) {
+		srv := startTestServer(t)
+		c, rec := newTestClient(t, srv, Config{Protocol: protocol})
+		assert.Eventually(t, c.Connected, waitFor, tick)
+
+		srv.emit("/", "hello", map[string]int{"a": 1})
+		e := nextEvent(t, rec)
+		assert.Equal(t, "hello", e.Name)
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`{"a":1}`)}, e.Args)
+		assert.False(t, e.WantsAck())
+
+		args, err := c.EmitWithAck(context.Background(), "echo", 1, "x")
+		assert.NoError(t, err)
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`"x"`)}, args)
+		received := nextServerEvent(t, srv)
+		assert.Equal(t, "echo", received.name)
+
+		ack := srv.emitWithAck("/", "question")
+		e = nextEvent(t, rec)
+		assert.True(t, e.WantsAck())
+		assert.NoError(t, e.Ack("answer"))
+		select {
+		case args := <-ack:
+			assert.Equal(t, []json.RawMessage{json.RawMessage(`"answer"`)}, args)
+		case <-time.After(waitFor):
+			t.Fatal("no ack received by the server")
+		}
+	})
+}
+
+func TestNamespace(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, protocol Protocol) {
+		srv := startTestServer(t)
+		c, rec := newTestClient(t, srv, Config{
+			Protocol:  protocol,
+			Namespace: "/chat",
+			Auth:      map[string]string{"token": "secret"},
+		})
+		assert.Eventually(t, c.Connected, waitFor, tick)
+		assert.Equal(t, 1, srv.connectCount("/chat"))
+
+		srv.emit("/", "ignored")
+		srv.emit("/chat", "message")
+		assert.Equal(t, "message", nextEvent(t, rec).Name)
+
+		assert.NoError(t, c.Emit("reply"))
+		received := nextServerEvent(t, srv)
+		assert.Equal(t, "/chat", received.namespace)
+		assert.Equal(t, "reply", received.name)
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		if protocol == EIO4 {
+			assert.Contains(t, srv.auth, json.RawMessage(`{"token":"secret"}`))
+		}
+	})
+}
+
+func TestBinary(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, protocol Protocol) {
+		srv := startTestServer(t)
+		c, rec := newTestClient(t, srv, Config{Protocol: protocol})
+		assert.Eventually(t, c.Connected, waitFor, tick)
+
+		srv.emit("/", "file", "name.bin", []byte{1, 2, 3})
+		e := nextEvent(t, rec)
+		data, ok := e.Binary(1)
+		assert.True(t, ok)
+		assert.Equal(t, []byte{1, 2, 3}, data)
+		_, ok = e.Binary(0)
+		assert.False(t, ok)
+
+		args, err := c.EmitWithAck(context.Background(), "upload", []byte{4, 5})
+		assert.NoError(t, err)
+		assert.Len(t, args, 1)
+		received := nextServerEvent(t, srv)
+		assert.Equal(t, [][]byte{{4, 5}}, received.attachments)
+	})
+}
+
+func TestConnectError(t *testing.T) {
+	srv := startTestServer(t)
+	c, rec := newTestClient(t, srv, Config{Namespace: "/forbidden"})
+
+	assert.Never(t, c.Connected, 100*time.Millisecond, tick)
+	assert.Zero(t, rec.connects.Load())
+	assert.ErrorIs(t, c.Emit("hello"), ErrNotConnected)
+}
+
+func TestReconnect(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, protocol Protocol) {
+		srv := startTestServer(t)
+		c, rec := newTestClient(t, srv, Config{Protocol: protocol})
+		assert.Eventually(t, c.Connected, waitFor, tick)
+
+		srv.dropConnections()
+		select {
+		case err := <-rec.disconnects:
+			assert.Error(t, err)
+		case <-time.After(waitFor):
+			t.Fatal("disconnect not reported")
+		}
+		assert.Eventually(t, func() bool { return rec.connects.Load() == 2 }, waitFor, tick)
+		assert.Eventually(t, c.Connected, waitFor, tick)
+
+		srv.emit("/", "after")
+		assert.Equal(t, "after", nextEvent(t, rec).Name)
+	})
+}
+
+func TestHeartbeatTimeout(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, protocol Protocol) {
+		srv := startTestServer(t)
+		srv.pingInterval = 50 * time.Millisecond
+		srv.pingTimeout = 50 * time.Millisecond
+		c, rec := newTestClient(t, srv, Config{Protocol: protocol})
+		assert.Eventually(t, c.Connected, waitFor, tick)
+
+		// a working heartbeat keeps the connection open
+		assert.Never(t, func() bool { return len(rec.disconnects) > 0 }, 300*time.Millisecond, tick)
+
+		srv.silent.Store(true)
+		select {
+		case err := <-rec.disconnects:
+			assert.ErrorIs(t, err, ErrHeartbeatTimeout)
+		case <-time.After(waitFor):
+			t.Fatal("heartbeat timeout not detected")
+		}
+		srv.silent.Store(false)
+		assert.Eventually(t, func() bool { return rec.connects.Load() == 2 }, waitFor, tick)
+	})
+}
+
+func TestPendingAckFailsOnDisconnect(t *testing.T) {
+	srv := startTestServer(t)
+	c, _ := newTestClient(t, srv, Config{})
+	assert.Eventually(t, c.Connected, waitFor, tick)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.EmitWithAck(context.Background(), "noack")
+		result <- err
+	}()
+	nextServerEvent(t, srv)
+	srv.dropConnections()
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, ErrNotConnected)
+	case <-time.After(waitFor):
+		t.Fatal("pending ack not failed")
+	}
+
+	assert.Eventually(t, c.Connected, waitFor, tick)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.EmitWithAck(ctx, "noack")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCloseWithoutStart(t *testing.T) {
+	c, err := NewClient(Config{URL: "ws://localhost"})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Close())
+	assert.ErrorIs(t, c.Emit("hello"), ErrNotConnected)
+
+	_, err = NewClient(Config{URL: "http://localhost"})
+	assert.Error(t, err)
+	_, err = NewClient(Config{URL: "ws://localhost", Protocol: 2})
+	assert.Error(t, err)
+}
+
+func TestStateChanges(t *testing.T) {
+	srv := startTestServer(t)
+	c, rec := newTestClient(t, srv, Config{})
+	assert.Eventually(t, c.Connected, waitFor, tick)
+
+	srv.dropConnections()
+	<-rec.disconnects
+	assert.Eventually(t, c.Connected, waitFor, tick)
+	assert.NoError(t, c.Close())
+
+	assert.Equal(t, StateClosed, c.State())
+	assert.Equal(t, []State{
+		StateConnecting, StateConnected,
+		StateDisconnected,
+		StateConnecting, StateConnected,
+		StateClosed,
+	}, rec.stateHistory())
+}
+
+func TestReconnectAfterFailedAttempts(t *testing.T) {
+	srv := startTestServer(t)
+	url := srv.URL()
+	srv.Close()
+
+	rec := &recorder{events: make(chan Event, 16), disconnects: make(chan error, 64)}
+	c, err := NewClient(Config{
+		URL:     url,
+		Backoff: Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2},
+		OnStateChange: func(state State, err error) {
+			if state == StateDisconnected {
+				select {
+				case rec.disconnects <- err:
+				default:
+				}
+			}
+		},
+	})
+	assert.NoError(t, err)
+	c.Start()
+	defer c.Close()
+
+	for range 3 {
+		select {
+		case err := <-rec.disconnects:
+			assert.Error(t, err)
+		case <-time.After(waitFor):
+			t.Fatal("connection attempt not retried")
+		}
+	}
+	assert.False(t, c.Connected())
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	assert.Equal(t, 100*time.Millisecond, b.Delay(0))
+	assert.Equal(t, 200*time.Millisecond, b.Delay(1))
+	assert.Equal(t, 800*time.Millisecond, b.Delay(3))
+	assert.Equal(t, time.Second, b.Delay(4))
+	assert.Equal(t, time.Second, b.Delay(1000))
+
+	b.Jitter = 0.5
+	for attempt := range 10 {
+		d := b.Delay(attempt)
+		assert.LessOrEqual(t, d, min(100*time.Millisecond<<attempt, time.Second))
+		assert.GreaterOrEqual(t, d, min(100*time.Millisecond<<attempt, time.Second)/2)
+	}
+}
+
+func TestConcurrentEmit(t *testing.T) {
+	forEachProtocol(t, func(t *testing.T, protocol Protocol) {
+		srv := startTestServer(t)
+		srv.received = make(chan serverEvent, 256)
+		c, _ := newTestClient(t, srv, Config{Protocol: protocol, SendQueueSize: 4})
+		assert.Eventually(t, c.Connected, waitFor, tick)
+
+		var wg sync.WaitGroup
+		for i := range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, c.Emit("upload", i, []byte{byte(i)}))
+			}()
+		}
+		wg.Wait()
+
+		seen := make(map[byte]bool)
+		for range 100 {
+			e := nextServerEvent(t, srv)
+			var i int
+			assert.NoError(t, json.Unmarshal(e.args[0], &i))
+			assert.Equal(t, [][]byte{{byte(i)}}, e.attachments)
+			seen[byte(i)] = true
+		}
+		assert.Len(t, seen, 100)
+	})
+}
+
+func TestWriteTimeout(t *testing.T) {
+	srv := startTestServer(t)
+	c, rec := newTestClient(t, srv, Config{WriteTimeout: 50 * time.Millisecond})
+	assert.Eventually(t, c.Connected, waitFor, tick)
+
+	srv.stalled.Store(true)
+	defer srv.stalled.Store(false)
+	payload := make([]byte, 1<<20)
+	var err error
+	for range 256 {
+		err = c.Emit("upload", payload)
+		if err != nil {
+			break
+		}
+	}
+	assert.Error(t, err)
+
+	select {
+	case err := <-rec.disconnects:
+		var netErr net.Error
+		assert.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+	case <-time.After(waitFor):
+		t.Fatal("stalled write not detected")
+	}
+}
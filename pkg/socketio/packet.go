@@ -0,0 +1,148 @@
+package socketio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPacket = errors.New("invalid packet")
+
+// Engine.IO packet types.
+const (
+	engineOpen    = '0'
+	engineClose   = '1'
+	enginePing    = '2'
+	enginePong    = '3'
+	engineMessage = '4'
+	engineUpgrade = '5'
+	engineNoop    = '6'
+)
+
+// packetType is a Socket.IO packet type.
+type packetType byte
+
+const (
+	packetConnect packetType = iota
+	packetDisconnect
+	packetEvent
+	packetAck
+	// packetConnectError is called ERROR in protocol version 4 (EIO3).
+	packetConnectError
+	packetBinaryEvent
+	packetBinaryAck
+)
+
+// packet is a Socket.IO packet. Binary packets are followed by attachments
+// binary frames, referenced from data by placeholders.
+type packet struct {
+	typ         packetType
+	namespace   string
+	id          int64
+	hasID       bool
+	data        json.RawMessage
+	attachments int
+}
+
+// encode returns the packet in the text encoding of Socket.IO, without the
+// Engine.IO message prefix.
+func (p packet) encode() string {
+	var b strings.Builder
+	b.WriteByte('0' + byte(p.typ))
+	if p.typ == packetBinaryEvent || p.typ == packetBinaryAck {
+		b.WriteString(strconv.Itoa(p.attachments))
+		b.WriteByte('-')
+	}
+	if p.namespace != "" && p.namespace != "/" {
+		b.WriteString(p.namespace)
+		b.WriteByte(',')
+	}
+	if p.hasID {
+		b.WriteString(strconv.FormatInt(p.id, 10))
+	}
+	b.Write(p.data)
+	return b.String()
+}
+
+func decodePacket(s string) (packet, error) {
+	if s == "" {
+		return packet{}, fmt.Errorf("%w: empty", ErrInvalidPacket)
+	}
+	p := packet{
+		typ:       packetType(s[0] - '0'),
+		namespace: "/",
+	}
+	if p.typ > packetBinaryAck {
+		return packet{}, fmt.Errorf("%w: unknown type %q", ErrInvalidPacket, s[0])
+	}
+	s = s[1:]
+
+	if p.typ == packetBinaryEvent || p.typ == packetBinaryAck {
+		count, rest, ok := strings.Cut(s, "-")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n < 0 {
+			return packet{}, fmt.Errorf("%w: attachment count", ErrInvalidPacket)
+		}
+		p.attachments = n
+		s = rest
+	}
+
+	if strings.HasPrefix(s, "/") {
+		end := strings.IndexAny(s, ",")
+		if end < 0 {
+			p.namespace, s = s, ""
+		} else {
+			p.namespace, s = s[:end], s[end+1:]
+		}
+	}
+
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 {
+		id, err := strconv.ParseInt(s[:digits], 10, 64)
+		if err != nil {
+			return packet{}, fmt.Errorf("%w: id: %w", ErrInvalidPacket, err)
+		}
+		p.id, p.hasID = id, true
+		s = s[digits:]
+	}
+
+	if s != "" {
+		if !json.Valid([]byte(s)) {
+			return packet{}, fmt.Errorf("%w: payload is not JSON", ErrInvalidPacket)
+		}
+		p.data = json.RawMessage(s)
+	}
+	return p, nil
+}
+
+// placeholder stands in for a binary attachment in the payload of a packet.
+type placeholder struct {
+	Placeholder bool `json:"_placeholder"`
+	Num         int  `json:"num"`
+}
+
+// encodeArgs marshals args as a JSON array. Top level []byte arguments are
+// replaced by placeholders and returned as attachments.
+func encodeArgs(args []any) (json.RawMessage, [][]byte, error) {
+	var attachments [][]byte
+	values := make([]any, len(args))
+	for i, arg := range args {
+		if data, ok := arg.([]byte); ok {
+			values[i] = placeholder{Placeholder: true, Num: len(attachments)}
+			attachments = append(attachments, data)
+			continue
+		}
+		values[i] = arg
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, attachments, nil
+}
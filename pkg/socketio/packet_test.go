@@ -0,0 +1,43 @@
+package socketio
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	for encoded, expected := range map[string]packet{
+		`0`:                    {typ: packetConnect, namespace: "/"},
+		`0/chat,{"token":"x"}`: {typ: packetConnect, namespace: "/chat", data: json.RawMessage(`{"token":"x"}`)},
+		`1/chat,`:              {typ: packetDisconnect, namespace: "/chat"},
+		`2["hello",1]`:         {typ: packetEvent, namespace: "/", data: json.RawMessage(`["hello",1]`)},
+		`2/chat,12["hello"]`:   {typ: packetEvent, namespace: "/chat", id: 12, hasID: true, data: json.RawMessage(`["hello"]`)},
+		`30[]`:                 {typ: packetAck, namespace: "/", hasID: true, data: json.RawMessage(`[]`)},
+		`4{"message":"nope"}`:  {typ: packetConnectError, namespace: "/", data: json.RawMessage(`{"message":"nope"}`)},
+		`52-/up,7["f",{"_placeholder":true,"num":0},{"_placeholder":true,"num":1}]`: {
+			typ: packetBinaryEvent, namespace: "/up", id: 7, hasID: true, attachments: 2,
+			data: json.RawMessage(`["f",{"_placeholder":true,"num":0},{"_placeholder":true,"num":1}]`),
+		},
+	} {
+		p, err := decodePacket(encoded)
+		assert.NoError(t, err, encoded)
+		assert.Equal(t, expected, p, encoded)
+		assert.Equal(t, encoded, p.encode())
+	}
+}
+
+func TestDecodeInvalidPacket(t *testing.T) {
+	for _, encoded := range []string{"", "9", `2["hello"`, `5["x"]`, `5x-["x"]`} {
+		_, err := decodePacket(encoded)
+		assert.ErrorIs(t, err, ErrInvalidPacket, encoded)
+	}
+}
+
+func TestEncodeArgs(t *testing.T) {
+	data, attachments, err := encodeArgs([]any{"upload", []byte{1, 2}, map[string]int{"a": 1}, []byte{3}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `["upload",{"_placeholder":true,"num":0},{"a":1},{"_placeholder":true,"num":1}]`, string(data))
+	assert.Equal(t, [][]byte{{1, 2}, {3}}, attachments)
+}
@@ -0,0 +1,308 @@
+package socketio
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testServer is a minimal Socket.IO server for the websocket transport of
+// both Engine.IO protocols. Events named "noack" are never acknowledged,
+// all other events that ask for it are acknowledged with their arguments.
+type testServer struct {
+	*httptest.Server
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+	// silent stops the server's side of the heartbeat.
+	silent atomic.Bool
+	// stalled stops the server from reading, so that client writes block.
+	stalled atomic.Bool
+
+	mu       sync.Mutex
+	conns    map[*serverConn]bool
+	connects map[string]int
+	auth     []json.RawMessage
+	nextID   int64
+	acks     map[int64]chan []json.RawMessage
+	received chan serverEvent
+}
+
+type serverConn struct {
+	conn     *websocket.Conn
+	protocol Protocol
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+	joined   map[string]bool
+}
+
+type serverEvent struct {
+	namespace   string
+	name        string
+	args        []json.RawMessage
+	attachments [][]byte
+}
+
+var testUpgrader = websocket.Upgrader{}
+
+func startTestServer(t *testing.T) *testServer {
+	s := &testServer{
+		pingInterval: 25 * time.Second,
+		pingTimeout:  20 * time.Second,
+		conns:        make(map[*serverConn]bool),
+		connects:     make(map[string]int),
+		acks:         make(map[int64]chan []json.RawMessage),
+		received:     make(chan serverEvent, 16),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/socket.io/", s.handle)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		s.dropConnections()
+		s.Close()
+	})
+	return s
+}
+
+func (s *testServer) URL() string {
+	return "ws" + strings.TrimPrefix(s.Server.URL, "http")
+}
+
+func (s *testServer) handle(w http.ResponseWriter, r *http.Request) {
+	protocol, _ := strconv.Atoi(r.URL.Query().Get("EIO"))
+	if (protocol != 3 && protocol != 4) || r.URL.Query().Get("transport") != "websocket" {
+		http.Error(w, "unsupported transport", http.StatusBadRequest)
+		return
+	}
+	conn, err := testUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	sc := &serverConn{conn: conn, protocol: Protocol(protocol), joined: make(map[string]bool)}
+
+	s.mu.Lock()
+	s.conns[sc] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, sc)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	open, _ := json.Marshal(map[string]any{
+		"sid":          "sid",
+		"upgrades":     []string{},
+		"pingInterval": s.pingInterval.Milliseconds(),
+		"pingTimeout":  s.pingTimeout.Milliseconds(),
+	})
+	if sc.write(websocket.TextMessage, "0"+string(open)) != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if sc.protocol == EIO3 {
+		// Socket.IO 2 joins the default namespace right away
+		s.joined(sc, "/", nil)
+	} else {
+		go s.pingLoop(sc, stop)
+	}
+
+	var pending *packet
+	var attachments [][]byte
+	for {
+		for s.stalled.Load() {
+			time.Sleep(time.Millisecond)
+		}
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if messageType == websocket.BinaryMessage {
+			if sc.protocol == EIO3 {
+				message = message[1:]
+			}
+			if pending == nil {
+				continue
+			}
+			attachments = append(attachments, message)
+			if len(attachments) == pending.attachments {
+				s.handlePacket(sc, *pending, attachments)
+				pending, attachments = nil, nil
+			}
+			continue
+		}
+
+		text := string(message)
+		switch {
+		case text == "2":
+			if !s.silent.Load() {
+				_ = sc.write(websocket.TextMessage, "3")
+			}
+		case strings.HasPrefix(text, "4"):
+			p, err := decodePacket(text[1:])
+			if err != nil {
+				continue
+			}
+			if p.attachments > 0 {
+				pending, attachments = &p, nil
+				continue
+			}
+			s.handlePacket(sc, p, nil)
+		}
+	}
+}
+
+func (s *testServer) pingLoop(sc *serverConn, stop chan struct{}) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !s.silent.Load() {
+				_ = sc.write(websocket.TextMessage, "2")
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *testServer) handlePacket(sc *serverConn, p packet, attachments [][]byte) {
+	switch p.typ {
+	case packetConnect:
+		if p.namespace == "/forbidden" {
+			_ = sc.writePacket(packet{typ: packetConnectError, namespace: p.namespace, data: json.RawMessage(`{"message":"forbidden"}`)}, nil)
+			return
+		}
+		s.joined(sc, p.namespace, p.data)
+	case packetEvent, packetBinaryEvent:
+		var args []json.RawMessage
+		_ = json.Unmarshal(p.data, &args)
+		var name string
+		_ = json.Unmarshal(args[0], &name)
+		s.received <- serverEvent{namespace: p.namespace, name: name, args: args[1:], attachments: attachments}
+
+		if p.hasID && name != "noack" {
+			ack := packet{typ: packetAck, namespace: p.namespace, id: p.id, hasID: true}
+			ack.data, _ = json.Marshal(args[1:])
+			if len(attachments) > 0 {
+				ack.typ = packetBinaryAck
+				ack.attachments = len(attachments)
+			}
+			_ = sc.writePacket(ack, attachments)
+		}
+	case packetAck:
+		var args []json.RawMessage
+		_ = json.Unmarshal(p.data, &args)
+		s.mu.Lock()
+		result, ok := s.acks[p.id]
+		s.mu.Unlock()
+		if ok {
+			result <- args
+		}
+	}
+}
+
+func (s *testServer) joined(sc *serverConn, namespace string, auth json.RawMessage) {
+	sc.mu.Lock()
+	sc.joined[namespace] = true
+	sc.mu.Unlock()
+
+	s.mu.Lock()
+	s.connects[namespace]++
+	s.auth = append(s.auth, auth)
+	s.mu.Unlock()
+
+	p := packet{typ: packetConnect, namespace: namespace}
+	if sc.protocol == EIO4 {
+		p.data = json.RawMessage(`{"sid":"sid"}`)
+	}
+	_ = sc.writePacket(p, nil)
+}
+
+func (s *testServer) connectCount(namespace string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connects[namespace]
+}
+
+// emit sends an event to all connections that joined namespace. Top level
+// []byte arguments are sent as attachments.
+func (s *testServer) emit(namespace, name string, args ...any) {
+	s.emitPacket(packet{typ: packetEvent, namespace: namespace}, name, args)
+}
+
+// emitWithAck is like emit, but asks for an acknowledgement, which is
+// delivered on the returned channel.
+func (s *testServer) emitWithAck(namespace, name string, args ...any) chan []json.RawMessage {
+	result := make(chan []json.RawMessage, 1)
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.acks[id] = result
+	s.mu.Unlock()
+
+	s.emitPacket(packet{typ: packetEvent, namespace: namespace, id: id, hasID: true}, name, args)
+	return result
+}
+
+func (s *testServer) emitPacket(p packet, name string, args []any) {
+	var attachments [][]byte
+	p.data, attachments, _ = encodeArgs(append([]any{name}, args...))
+	if len(attachments) > 0 {
+		p.typ = packetBinaryEvent
+		p.attachments = len(attachments)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sc := range s.conns {
+		sc.mu.Lock()
+		joined := sc.joined[p.namespace]
+		sc.mu.Unlock()
+		if joined {
+			_ = sc.writePacket(p, attachments)
+		}
+	}
+}
+
+func (s *testServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sc := range s.conns {
+		_ = sc.conn.Close()
+	}
+}
+
+func (sc *serverConn) writePacket(p packet, attachments [][]byte) error {
+	err := sc.write(websocket.TextMessage, "4"+p.encode())
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if sc.protocol == EIO3 {
+			attachment = append([]byte{4}, attachment...)
+		}
+		err = sc.write(websocket.BinaryMessage, string(attachment))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *serverConn) write(messageType int, message string) error {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	return sc.conn.WriteMessage(messageType, []byte(message))
+}
@@ -0,0 +1,74 @@
+package socketio
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// State is the connection state of a Client.
+type State int
+
+const (
+	// StateIdle is the state before Start.
+	StateIdle State = iota
+	// StateConnecting is entered before every connection attempt.
+	StateConnecting
+	// StateConnected means the namespace is joined.
+	StateConnected
+	// StateDisconnected means an attempt failed or the connection was lost.
+	// A reconnect is scheduled.
+	StateDisconnected
+	// StateClosed is final and entered after Close.
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Backoff configures the delay between reconnection attempts. The delay
+// starts at Initial and grows by Multiplier with every failed attempt, up to
+// Max. Each delay is shortened by a random fraction of up to Jitter, so that
+// clients disconnected together do not reconnect together.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// DefaultBackoff is used for the zero Backoff.
+var DefaultBackoff = Backoff{
+	Initial:    time.Second,
+	Max:        time.Minute,
+	Multiplier: 2,
+	Jitter:     0.5,
+}
+
+// Delay returns the delay before the given attempt, counted from zero.
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for range attempt {
+		d *= b.Multiplier
+		if d >= float64(b.Max) {
+			d = float64(b.Max)
+			break
+		}
+	}
+	d = min(d, float64(b.Max))
+	d -= d * b.Jitter * rand.Float64()
+	return time.Duration(d)
+}
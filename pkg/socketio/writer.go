@@ -0,0 +1,112 @@
+package socketio
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type frame struct {
+	messageType int
+	data        []byte
+}
+
+// outgoing is written as a whole, so that the attachments of a packet are
+// never interleaved with other frames.
+type outgoing struct {
+	frames []frame
+	result chan error
+}
+
+// connection is a websocket connection whose frames are all written by a
+// single goroutine, as websocket.Conn supports only one concurrent writer.
+type connection struct {
+	conn    *websocket.Conn
+	timeout time.Duration
+	out     chan outgoing
+	// stop ends the writer once the session is over.
+	stop chan struct{}
+	// done is closed when the writer returned.
+	done chan struct{}
+	// writeErr receives the error that ended the writer.
+	writeErr chan error
+}
+
+func newConnection(conn *websocket.Conn, timeout time.Duration, queueSize int) *connection {
+	cn := &connection{
+		conn:     conn,
+		timeout:  timeout,
+		out:      make(chan outgoing, queueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		writeErr: make(chan error, 1),
+	}
+	go cn.writeLoop()
+	return cn
+}
+
+// writeLoop writes the queued frames until the connection is stopped or a
+// write fails. A failed write closes the connection, which ends the reader.
+func (cn *connection) writeLoop() {
+	defer close(cn.done)
+	for {
+		select {
+		case item := <-cn.out:
+			err := cn.write(item.frames)
+			item.result <- err
+			if err != nil {
+				cn.writeErr <- err
+				_ = cn.conn.Close()
+				return
+			}
+		case <-cn.stop:
+			return
+		}
+	}
+}
+
+func (cn *connection) write(frames []frame) error {
+	for _, f := range frames {
+		if cn.timeout > 0 {
+			_ = cn.conn.SetWriteDeadline(time.Now().Add(cn.timeout))
+		}
+		err := cn.conn.WriteMessage(f.messageType, f.data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue hands frames to the writer and waits until they are written.
+func (cn *connection) enqueue(frames ...frame) error {
+	item := outgoing{frames: frames, result: make(chan error, 1)}
+	select {
+	case cn.out <- item:
+	case <-cn.done:
+		return ErrNotConnected
+	}
+
+	select {
+	case err := <-item.result:
+		return err
+	case <-cn.done:
+		// the writer may have finished the item before it returned
+		select {
+		case err := <-item.result:
+			return err
+		default:
+			return ErrNotConnected
+		}
+	}
+}
+
+func (cn *connection) writeText(message string) error {
+	return cn.enqueue(frame{websocket.TextMessage, []byte(message)})
+}
+
+// close stops the writer and waits for it.
+func (cn *connection) close() {
+	close(cn.stop)
+	<-cn.done
+}
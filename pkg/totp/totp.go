@@ -1,42 +1,134 @@
+// Package totp generates time-based one-time passwords as specified in
+// RFC 6238.
 package totp
 
 import (
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base32"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var ErrInvalidURI = errors.New("invalid otpauth URI")
+
+// Algorithm is the HMAC hash function of a generator.
+type Algorithm string
+
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+// ParseAlgorithm accepts the algorithm names of otpauth URIs, ignoring
+// case. An empty name is SHA1.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch Algorithm(strings.ToUpper(name)) {
+	case "", SHA1:
+		return SHA1, nil
+	case SHA256:
+		return SHA256, nil
+	case SHA512:
+		return SHA512, nil
+	}
+	return "", fmt.Errorf("unknown algorithm %q, expected SHA1, SHA256 or SHA512", name)
+}
+
+func (a Algorithm) hash() func() hash.Hash {
+	switch a {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	}
+	return sha1.New
+}
+
+// TOTPGenerator generates the codes of a base32 secret. Zero values use the
+// defaults of most authenticators: SHA1, 6 digits and a 30 second period.
 type TOTPGenerator struct {
-	Secret string
-	Digits int
-	Period int64
+	Secret    string
+	Digits    int
+	Period    int64
+	Algorithm Algorithm
 }
 
 func (t TOTPGenerator) String() string {
 	return "TOTP"
 }
 
+// ParseURI reads a generator from an otpauth://totp/ key URI as shown by
+// QR codes. Parameters missing from the URI take their defaults.
+func ParseURI(uri string) (*TOTPGenerator, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		// the error would contain the secret
+		return nil, ErrInvalidURI
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("%w: scheme must be otpauth", ErrInvalidURI)
+	}
+	if u.Host != "totp" {
+		return nil, fmt.Errorf("%w: type %q is not supported, expected totp", ErrInvalidURI, u.Host)
+	}
+
+	query := u.Query()
+	t := &TOTPGenerator{Secret: query.Get("secret"), Digits: 6, Period: 30}
+	if t.Secret == "" {
+		return nil, fmt.Errorf("%w: secret missing", ErrInvalidURI)
+	}
+	_, err = t.key()
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret is not base32", ErrInvalidURI)
+	}
+	t.Algorithm, err = ParseAlgorithm(query.Get("algorithm"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURI, err)
+	}
+	if digits := query.Get("digits"); digits != "" {
+		t.Digits, err = strconv.Atoi(digits)
+		if err != nil || t.Digits < 6 || t.Digits > 10 {
+			return nil, fmt.Errorf("%w: digits must be between 6 and 10, got %q", ErrInvalidURI, digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		t.Period, err = strconv.ParseInt(period, 10, 64)
+		if err != nil || t.Period <= 0 {
+			return nil, fmt.Errorf("%w: period must be a positive number of seconds, got %q", ErrInvalidURI, period)
+		}
+	}
+	return t, nil
+}
+
+// Generate returns the code of the current time window.
 func (t *TOTPGenerator) Generate() (string, error) {
-	// Normalize and decode Base32 secret
-	secretUpper := strings.ToUpper(strings.ReplaceAll(t.Secret, " ", ""))
-	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secretUpper)
+	return t.GenerateAt(time.Now())
+}
+
+// GenerateAt returns the code of the time window containing at.
+func (t *TOTPGenerator) GenerateAt(at time.Time) (string, error) {
+	key, err := t.key()
 	if err != nil {
 		return "", err
 	}
 
 	// Time counter = Unix time / period
-	counter := time.Now().Unix() / t.Period
+	counter := at.Unix() / t.period()
 
 	// Convert counter to 8-byte big-endian
 	var buf [8]byte
 	binary.BigEndian.PutUint64(buf[:], uint64(counter))
 
-	// HMAC-SHA1
-	h := hmac.New(sha1.New, key)
+	h := hmac.New(t.Algorithm.hash(), key)
 	h.Write(buf[:])
 	hash := h.Sum(nil)
 
@@ -48,13 +140,37 @@ func (t *TOTPGenerator) Generate() (string, error) {
 		(int(hash[offset+3]) & 0xff)
 
 	// Mod 10^digits
+	digits := t.Digits
+	if digits == 0 {
+		digits = 6
+	}
 	modulo := 1
-	for range t.Digits {
+	for range digits {
 		modulo *= 10
 	}
 	otp := code % modulo
 
-	return zeropad(strconv.FormatInt(int64(otp), 10), t.Digits), nil
+	return zeropad(strconv.FormatInt(int64(otp), 10), digits), nil
+}
+
+// PeriodDuration returns the length of a time window.
+func (t *TOTPGenerator) PeriodDuration() time.Duration {
+	return time.Duration(t.period()) * time.Second
+}
+
+func (t *TOTPGenerator) period() int64 {
+	if t.Period <= 0 {
+		return 30
+	}
+	return t.Period
+}
+
+// key decodes the secret, which may contain spaces, lower case letters and
+// padding.
+func (t *TOTPGenerator) key() ([]byte, error) {
+	secret := strings.ToUpper(strings.ReplaceAll(t.Secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
 }
 
 func zeropad(input string, length int) string {
@@ -0,0 +1,87 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// The test vectors of RFC 6238, Appendix B.
+func TestGenerateRFC6238(t *testing.T) {
+	seeds := map[totp.Algorithm]string{
+		totp.SHA1:   "12345678901234567890",
+		totp.SHA256: "12345678901234567890123456789012",
+		totp.SHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	vectors := []struct {
+		unix      int64
+		algorithm totp.Algorithm
+		code      string
+	}{
+		{59, totp.SHA1, "94287082"},
+		{59, totp.SHA256, "46119246"},
+		{59, totp.SHA512, "90693936"},
+		{1111111109, totp.SHA1, "07081804"},
+		{1111111109, totp.SHA256, "68084774"},
+		{1111111109, totp.SHA512, "25091201"},
+		{1111111111, totp.SHA1, "14050471"},
+		{1111111111, totp.SHA256, "67062674"},
+		{1111111111, totp.SHA512, "99943326"},
+		{1234567890, totp.SHA1, "89005924"},
+		{1234567890, totp.SHA256, "91819424"},
+		{1234567890, totp.SHA512, "93441116"},
+		{2000000000, totp.SHA1, "69279037"},
+		{2000000000, totp.SHA256, "90698825"},
+		{2000000000, totp.SHA512, "38618901"},
+		{20000000000, totp.SHA1, "65353130"},
+		{20000000000, totp.SHA256, "77737706"},
+		{20000000000, totp.SHA512, "47863826"},
+	}
+
+	for _, v := range vectors {
+		generator := totp.TOTPGenerator{
+			Secret:    base32.StdEncoding.EncodeToString([]byte(seeds[v.algorithm])),
+			Digits:    8,
+			Period:    30,
+			Algorithm: v.algorithm,
+		}
+		code, err := generator.GenerateAt(time.Unix(v.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, v.code, code, "%s at %d", v.algorithm, v.unix)
+	}
+}
+
+func TestGenerateDefaults(t *testing.T) {
+	generator := totp.TOTPGenerator{Secret: "gezd gnbv gy3t qojq gezd gnbv gy3t qojq"}
+	code, err := generator.GenerateAt(time.Unix(59, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+	assert.Equal(t, 30*time.Second, generator.PeriodDuration())
+}
+
+func TestParseURI(t *testing.T) {
+	generator, err := totp.ParseURI("otpauth://totp/Filen:user@example.com?secret=GEZDGNBVGY3TQOJQ&issuer=Filen&algorithm=sha256&digits=8&period=60")
+	assert.NoError(t, err)
+	assert.Equal(t, &totp.TOTPGenerator{Secret: "GEZDGNBVGY3TQOJQ", Digits: 8, Period: 60, Algorithm: totp.SHA256}, generator)
+
+	generator, err = totp.ParseURI("otpauth://totp/user?secret=GEZDGNBVGY3TQOJQ")
+	assert.NoError(t, err)
+	assert.Equal(t, &totp.TOTPGenerator{Secret: "GEZDGNBVGY3TQOJQ", Digits: 6, Period: 30, Algorithm: totp.SHA1}, generator)
+
+	for uri, msg := range map[string]string{
+		"https://totp/user?secret=GEZDGNBVGY3TQOJQ":                 "scheme must be otpauth",
+		"otpauth://hotp/user?secret=GEZDGNBVGY3TQOJQ&counter=1":     `type "hotp" is not supported`,
+		"otpauth://totp/user":                                       "secret missing",
+		"otpauth://totp/user?secret=not-base32!":                    "secret is not base32",
+		"otpauth://totp/user?secret=GEZDGNBVGY3TQOJQ&algorithm=MD5": `unknown algorithm "MD5"`,
+		"otpauth://totp/user?secret=GEZDGNBVGY3TQOJQ&digits=4":      "digits must be between 6 and 10",
+		"otpauth://totp/user?secret=GEZDGNBVGY3TQOJQ&period=0":      "period must be a positive",
+	} {
+		_, err := totp.ParseURI(uri)
+		assert.ErrorIs(t, err, totp.ErrInvalidURI, uri)
+		assert.ErrorContains(t, err, msg, uri)
+	}
+}
@@ -0,0 +1,313 @@
+// Package watch prints the events of a Filen account as they arrive, with
+// the remote paths of the items they are about.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filedb"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/rs/zerolog/log"
+)
+
+type Format int
+
+const (
+	// FormatJSON prints one JSON object per line.
+	FormatJSON Format = iota
+	// FormatPretty prints one human readable line per event.
+	FormatPretty
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "json":
+		return FormatJSON, nil
+	case "pretty":
+		return FormatPretty, nil
+	default:
+		return 0, fmt.Errorf("unknown format %q, expected json or pretty", s)
+	}
+}
+
+type Config struct {
+	Format Format
+	// Events limits the output to these event names. Empty means all.
+	Events []string
+	// PathPrefix limits the output to events about items below this remote
+	// path, e.g. /photos. Connection state changes are left out when set.
+	PathPrefix string
+	Output     io.Writer
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Line is what is printed for an event.
+type Line struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	UUID  string    `json:"uuid,omitempty"`
+	// Path is the remote path of the item after the event.
+	Path string `json:"path,omitempty"`
+	// OldPath is the path before a move or rename.
+	OldPath string `json:"oldPath,omitempty"`
+	State   string `json:"state,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Watcher prints events. The remote tree used to resolve paths is listed
+// when the first event needs it, kept up to date from the events and listed
+// again after the event stream had a gap. Events are printed without paths
+// while listing fails.
+type Watcher struct {
+	cfg     Config
+	store   remote.RemoteStore
+	baseDir filedb.Uuid
+	tree    *filedb.FileTree
+}
+
+func New(store remote.RemoteStore, cfg Config) *Watcher {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	cfg.PathPrefix = strings.TrimSuffix(cfg.PathPrefix, "/")
+	if cfg.PathPrefix != "" && !strings.HasPrefix(cfg.PathPrefix, "/") {
+		cfg.PathPrefix = "/" + cfg.PathPrefix
+	}
+	return &Watcher{
+		cfg:     cfg,
+		store:   store,
+		baseDir: filedb.UuidFromString(store.BaseFolderUUID()),
+	}
+}
+
+// Run prints the events of source until it is closed or ctx is done.
+func (w *Watcher) Run(ctx context.Context, source remote.EventSource) error {
+	source.Start()
+	for {
+		evt, ok := source.NextEvent()
+		if !ok {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := w.Handle(ctx, evt)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Handle prints evt if it passes the filters.
+func (w *Watcher) Handle(ctx context.Context, evt filenextra.TypedEvent) error {
+	line := w.line(ctx, evt)
+	if !w.wants(line) {
+		return nil
+	}
+	return w.print(line)
+}
+
+func (w *Watcher) wants(line Line) bool {
+	if len(w.cfg.Events) > 0 && !slices.Contains(w.cfg.Events, line.Event) {
+		return false
+	}
+	if w.cfg.PathPrefix == "" {
+		return true
+	}
+	return hasPathPrefix(line.Path, w.cfg.PathPrefix) || hasPathPrefix(line.OldPath, w.cfg.PathPrefix)
+}
+
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+func (w *Watcher) print(line Line) error {
+	var err error
+	switch w.cfg.Format {
+	case FormatPretty:
+		_, err = fmt.Fprintln(w.cfg.Output, pretty(line))
+	default:
+		var b []byte
+		b, err = json.Marshal(line)
+		if err == nil {
+			_, err = w.cfg.Output.Write(append(b, '\n'))
+		}
+	}
+	return err
+}
+
+func pretty(line Line) string {
+	var b strings.Builder
+	b.WriteString(line.Time.Format(time.RFC3339))
+	b.WriteByte(' ')
+	b.WriteString(line.Event)
+	switch {
+	case line.State != "":
+		b.WriteString(" " + line.State)
+	case line.OldPath != "" && line.OldPath != line.Path:
+		b.WriteString(" " + line.OldPath + " -> " + line.Path)
+	case line.Path != "":
+		b.WriteString(" " + line.Path)
+	case line.UUID != "":
+		b.WriteString(" " + line.UUID)
+	}
+	if line.Error != "" {
+		b.WriteString(" (" + line.Error + ")")
+	}
+	return b.String()
+}
+
+// line resolves the paths of evt and applies it to the tree.
+func (w *Watcher) line(ctx context.Context, evt filenextra.TypedEvent) Line {
+	line := Line{Time: w.cfg.Now(), Event: evt.Name}
+
+	if e, ok := evt.Data.(*filenextra.EventConnectionState); ok {
+		line.State = e.State.String()
+		if e.Err != nil {
+			line.Error = e.Err.Error()
+		}
+		if e.State == filenextra.ConnectionLost {
+			// changes during the gap are unknown, list again when needed
+			w.tree = nil
+		}
+		return line
+	}
+
+	err := w.ensureTree(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve paths, listing again on the next event")
+		w.tree = filedb.NewFileTree()
+		defer func() { w.tree = nil }()
+	}
+
+	switch e := evt.Data.(type) {
+	case *filenextra.EventSocketFileNew:
+		line.UUID = e.UUID
+		w.tree.CreateFile(w.uuid(e.UUID), w.parent(e.Parent), e.Meta.Name, e.Meta.ModTime(), e.Meta.Hash)
+	case *filenextra.EventSocketFileRestore:
+		line.UUID = e.UUID
+		w.tree.CreateFile(w.uuid(e.UUID), w.parent(e.Parent), e.Meta.Name, e.Meta.ModTime(), e.Meta.Hash)
+	case *filenextra.EventSocketFileArchiveRestored:
+		line.UUID = e.UUID
+		w.tree.CreateFile(w.uuid(e.UUID), w.parent(e.Parent), e.Meta.Name, e.Meta.ModTime(), e.Meta.Hash)
+	case *filenextra.EventSocketFolderSubCreated:
+		line.UUID = e.UUID
+		w.tree.CreateDir(w.uuid(e.UUID), w.parent(e.Parent), e.Name.Name)
+	case *filenextra.EventSocketFolderRestore:
+		line.UUID = e.UUID
+		w.tree.CreateDir(w.uuid(e.UUID), w.parent(e.Parent), e.Name.Name)
+	case *filenextra.EventSocketFileMove:
+		line.UUID = e.UUID
+		line.OldPath = w.path(e.UUID)
+		w.tree.Move(w.uuid(e.UUID), w.parent(e.Parent), filedb.FileNameFromString(e.Meta.Name))
+	case *filenextra.EventSocketFolderMove:
+		line.UUID = e.UUID
+		line.OldPath = w.path(e.UUID)
+		w.tree.Move(w.uuid(e.UUID), w.parent(e.Parent), filedb.FileNameFromString(e.Name.Name))
+	case *filenextra.EventSocketFileRename:
+		line.UUID = e.UUID
+		line.OldPath = w.path(e.UUID)
+		w.rename(e.UUID, e.Meta.Name)
+	case *filenextra.EventSocketFolderRename:
+		line.UUID = e.UUID
+		line.OldPath = w.path(e.UUID)
+		w.rename(e.UUID, e.Name.Name)
+	case *filenextra.EventSocketFileTrash:
+		return w.removed(line, e.UUID)
+	case *filenextra.EventSocketFolderTrash:
+		return w.removed(line, e.UUID)
+	case *filenextra.EventSocketFileDeletedPermanent:
+		return w.removed(line, e.UUID)
+	case *filenextra.EventSocketFileArchived:
+		return w.removed(line, e.UUID)
+	case *filenextra.EventSocketFolderColorChanged:
+		line.UUID = e.UUID
+	case *filenextra.EventInvalid:
+		line.UUID = e.UUID
+		line.Error = e.Err.Error()
+	default:
+		log.Debug().Msgf("No path known for %s event", evt.Name)
+	}
+	line.Path = w.path(line.UUID)
+	return line
+}
+
+// removed reports the last known path of an item that is gone.
+func (w *Watcher) removed(line Line, uuid string) Line {
+	line.UUID = uuid
+	line.Path = w.path(uuid)
+	w.tree.Remove(w.uuid(uuid))
+	return line
+}
+
+func (w *Watcher) rename(uuid, name string) {
+	node, ok := w.tree.GetNode(w.uuid(uuid))
+	if !ok {
+		return
+	}
+	w.tree.Move(node.Uuid, node.Parent, filedb.FileNameFromString(name))
+}
+
+func (w *Watcher) path(uuid string) string {
+	if uuid == "" {
+		return ""
+	}
+	p, ok := w.tree.GetPath(w.uuid(uuid))
+	if !ok {
+		return ""
+	}
+	return "/" + p
+}
+
+func (w *Watcher) uuid(s string) filedb.Uuid {
+	return filedb.UuidFromString(s)
+}
+
+// parent maps the base folder to the root of the tree.
+func (w *Watcher) parent(s string) filedb.Uuid {
+	uuid := filedb.UuidFromString(s)
+	if uuid == w.baseDir {
+		return filedb.NilUuid
+	}
+	return uuid
+}
+
+func (w *Watcher) ensureTree(ctx context.Context) error {
+	if w.tree != nil {
+		return nil
+	}
+
+	files, dirs, err := w.store.ListRecursive(ctx)
+	if err != nil {
+		return fmt.Errorf("listing remote tree: %w", err)
+	}
+	items := make([]filedb.FileTreeNode, 0, len(files)+len(dirs))
+	for _, dir := range dirs {
+		items = append(items, filedb.FileTreeNode{
+			Uuid:   w.uuid(dir.UUID),
+			IsDir:  true,
+			Parent: w.parent(dir.ParentUUID),
+			Name:   filedb.FileNameFromString(dir.Name),
+		})
+	}
+	for _, file := range files {
+		items = append(items, filedb.FileTreeNode{
+			Uuid:    w.uuid(file.UUID),
+			Parent:  w.parent(file.ParentUUID),
+			Modtime: file.LastModified,
+			Hash:    filedb.HashFromString(file.Hash),
+			Name:    filedb.FileNameFromString(file.Name),
+		})
+	}
+	w.tree = filedb.NewFileTree()
+	w.tree.EnsureItems(items)
+	return nil
+}
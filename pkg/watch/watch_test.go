@@ -0,0 +1,125 @@
+package watch_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/watch"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func run(t *testing.T, store *remote.MemoryStore, cfg watch.Config) string {
+	var out bytes.Buffer
+	cfg.Output = &out
+	cfg.Now = func() time.Time { return now }
+	assert.NoError(t, store.Close())
+	assert.NoError(t, watch.New(store, cfg).Run(context.Background(), store))
+	return out.String()
+}
+
+func script(store *remote.MemoryStore) {
+	store.SetDir("photos", store.BaseFolderUUID(), "photos")
+
+	docs := store.CreateDir(store.BaseFolderUUID(), "docs")
+	file := store.CreateFile(docs, "a.txt", []byte("a"), now)
+	_ = store.RenameFile(file, "b.txt")
+	_ = store.MoveFile(file, "photos")
+	old := store.CreateFile("photos", "old.jpg", []byte("x"), now)
+	_ = store.TrashFile(old)
+	store.Emit(filenextra.TypedEvent{
+		Name: "connection-state",
+		Data: &filenextra.EventConnectionState{State: filenextra.ConnectionReady},
+	})
+}
+
+func TestPretty(t *testing.T) {
+	store := remote.NewMemoryStore()
+	script(store)
+
+	out := run(t, store, watch.Config{Format: watch.FormatPretty})
+	assert.Equal(t, strings.Join([]string{
+		"2026-01-02T03:04:05Z folder-sub-created /docs",
+		"2026-01-02T03:04:05Z file-new /docs/a.txt",
+		"2026-01-02T03:04:05Z file-rename /docs/a.txt -> /docs/b.txt",
+		"2026-01-02T03:04:05Z file-move /docs/b.txt -> /photos/b.txt",
+		"2026-01-02T03:04:05Z file-new /photos/old.jpg",
+		"2026-01-02T03:04:05Z file-trash /photos/old.jpg",
+		"2026-01-02T03:04:05Z connection-state ready",
+		"",
+	}, "\n"), out)
+}
+
+func TestJSONWithFilters(t *testing.T) {
+	store := remote.NewMemoryStore()
+	script(store)
+
+	out := run(t, store, watch.Config{
+		Events:     []string{"file-move", "file-trash", "file-new"},
+		PathPrefix: "photos/",
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.JSONEq(t, `{"time":"2026-01-02T03:04:05Z","event":"file-move","uuid":"`+uuidOf(t, lines[0])+`","path":"/photos/b.txt","oldPath":"/docs/b.txt"}`, lines[0])
+		assert.JSONEq(t, `{"time":"2026-01-02T03:04:05Z","event":"file-new","uuid":"`+uuidOf(t, lines[1])+`","path":"/photos/old.jpg"}`, lines[1])
+		assert.JSONEq(t, `{"time":"2026-01-02T03:04:05Z","event":"file-trash","uuid":"`+uuidOf(t, lines[1])+`","path":"/photos/old.jpg"}`, lines[2])
+	}
+}
+
+// flakyStore fails the first listings.
+type flakyStore struct {
+	*remote.MemoryStore
+	failures int
+}
+
+func (s *flakyStore) ListRecursive(ctx context.Context) ([]remote.File, []remote.Directory, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, nil, errors.New("unavailable")
+	}
+	return s.MemoryStore.ListRecursive(ctx)
+}
+
+func TestListingFailure(t *testing.T) {
+	store := remote.NewMemoryStore()
+	store.SetDir("docs", store.BaseFolderUUID(), "docs")
+	store.SetFile("a", "docs", "a.txt", []byte("a"), now)
+	_ = store.RenameFile("a", "b.txt")
+	store.CreateFile("docs", "c.txt", []byte("c"), now)
+	assert.NoError(t, store.Close())
+
+	var out bytes.Buffer
+	w := watch.New(&flakyStore{MemoryStore: store, failures: 1}, watch.Config{
+		Format: watch.FormatPretty,
+		Output: &out,
+		Now:    func() time.Time { return now },
+	})
+	assert.NoError(t, w.Run(context.Background(), store))
+	assert.Equal(t, strings.Join([]string{
+		"2026-01-02T03:04:05Z file-rename a",
+		"2026-01-02T03:04:05Z file-new /docs/c.txt",
+		"",
+	}, "\n"), out.String())
+}
+
+func uuidOf(t *testing.T, line string) string {
+	_, rest, ok := strings.Cut(line, `"uuid":"`)
+	assert.True(t, ok)
+	uuid, _, _ := strings.Cut(rest, `"`)
+	return uuid
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := watch.ParseFormat("pretty")
+	assert.NoError(t, err)
+	assert.Equal(t, watch.FormatPretty, format)
+	_, err = watch.ParseFormat("xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/secret"
+)
+
+// reloader re-reads the config of a running daemon. Settings that can change
+// live are applied, others must keep their running value.
+type reloader struct {
+	mu       sync.Mutex
+	m        *mirror.FilenMirror
+	override func(c *config.Config)
+}
+
+// reload reads, validates and applies the config. The running config is kept
+// if any step fails.
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := readConfig(r.override)
+	if err != nil {
+		return err
+	}
+	err = config.CheckReload(currentConfig.Load(), next)
+	if err != nil {
+		return err
+	}
+	err = r.m.Reconfigure(mirrorConfig(next))
+	if err != nil {
+		return err
+	}
+	setupLogging(next.Log)
+	currentConfig.Store(next)
+	return nil
+}
+
+// reloadLogged reloads and logs the outcome.
+func (r *reloader) reloadLogged(trigger string) error {
+	log.Info().Msgf("Reloading config (%s)", trigger)
+	err := r.reload()
+	if err != nil {
+		log.Error().Err(err).Msg("Config not reloaded, keeping the running config")
+		return err
+	}
+	log.Info().Msg("Config reloaded")
+	return nil
+}
+
+// reloadTokenName is the secret file the reload token is read from if
+// status.reloadToken has no source.
+const reloadTokenName = "filen_status_reload_token"
+
+var (
+	ErrReloadDisabled     = errors.New("reloading through the status address is disabled, set status.reloadToken or send SIGHUP")
+	ErrInvalidReloadToken = errors.New("invalid reload token")
+)
+
+func (r *reloader) handler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	status, err := authorizeReload(req)
+	if err != nil {
+		w.WriteHeader(status)
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	err = r.reloadLogged("control API")
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintln(w, "config reloaded")
+}
+
+// authorizeReload checks that req carries the configured reload token. The
+// token is read for every request, so that a changed token applies at once.
+func authorizeReload(req *http.Request) (int, error) {
+	cfg := currentConfig.Load()
+	token, err := cfg.Resolver().Get(cfg.Status.ReloadToken, reloadTokenName)
+	if errors.Is(err, secret.ErrNotConfigured) {
+		return http.StatusForbidden, ErrReloadDisabled
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read the reload token")
+		return http.StatusInternalServerError, errors.New("failed to read the reload token")
+	}
+	defer secret.Wipe(token)
+	if len(token) == 0 {
+		return http.StatusForbidden, ErrReloadDisabled
+	}
+
+	given, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), token) != 1 {
+		return http.StatusUnauthorized, ErrInvalidReloadToken
+	}
+	return http.StatusOK, nil
+}
+
+// runReload asks a running daemon to reload its config.
+func runReload(args []string) int {
+	flags := newFlagSet("reload", "Make a running daemon reload its config")
+	addr := flags.String("addr", "", "status address of the daemon (overrides status.addr)")
+	timeout := flags.Duration("timeout", 30*time.Second, "give up after this long")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	// only the address is needed, so the config is not validated
+	c, err := config.Load(configPath, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if isFlagSet(flags, "addr") {
+		c.Status.Addr = *addr
+	}
+	if c.Status.Addr == "" {
+		fmt.Fprintln(os.Stderr, "no status address configured")
+		return exitError
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+c.Status.Addr+"/reload", nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	token, err := c.Resolver().Get(c.Status.ReloadToken, reloadTokenName)
+	if err != nil && !errors.Is(err, secret.ErrNotConfigured) {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+string(token))
+		secret.Wipe(token)
+	}
+
+	httpClient := &http.Client{Timeout: *timeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reach the daemon")
+		return exitError
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	message := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintln(os.Stderr, message)
+		return exitFailed
+	}
+	fmt.Println(message)
+	return exitOK
+}
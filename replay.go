@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/executer"
+	filenextra "github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/filen_extra"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/mirror"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+)
+
+// runReplay runs a mirror against recorded events, so that an incident can
+// be reproduced. Files are downloaded from the account. Without -sync-dir
+// the mirror works in memory and the resulting tree is printed.
+func runReplay(args []string) int {
+	flags := newFlagSet("replay", "Run a mirror against recorded events and exit once they are applied")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: filen-mirror replay [flags] recording...\n\n")
+		fmt.Fprintf(flags.Output(), "Run a mirror against recorded events and exit once they are applied.\n")
+		fmt.Fprintf(flags.Output(), "Rotated recordings have to be passed oldest first.\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	syncDir := flags.String("sync-dir", "", "directory to mirror to, e.g. a copy of the real one (default in memory)")
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitError
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintf(flags.Output(), "no recordings given\n")
+		flags.Usage()
+		return exitError
+	}
+
+	source, err := filenextra.NewReplaySource(flags.Args()...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read recordings")
+		return exitError
+	}
+	override := func(c *config.Config) {
+		overrideSyncDir(c, *syncDir)
+		if c.Sync.Dir == "" {
+			// only used as the root of the in-memory tree
+			c.Sync.Dir = "/replay"
+		}
+	}
+	if !loadConfig(override) {
+		return exitError
+	}
+	client, _, err := login()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to log in")
+		return exitError
+	}
+
+	cfg := mirrorConfig(currentConfig.Load())
+	var memory *executer.MemoryExecuter
+	if *syncDir == "" {
+		memory = executer.NewMemoryExecuter()
+		cfg.Executer = memory
+	}
+	m := mirror.NewFilenMirror(remote.NewFilenStore(client), source, cfg)
+	m.Run()
+	log.Info().Msg("Replay complete")
+
+	if memory != nil {
+		err = memory.WalkDir(cfg.SyncDir, func(p string, isDir bool, continueDescending *bool) {
+			*continueDescending = true
+			p = strings.TrimPrefix(p, cfg.SyncDir+"/")
+			if isDir {
+				p += "/"
+			}
+			fmt.Println(p)
+		})
+		if err != nil && !os.IsNotExist(err) {
+			log.Error().Err(err).Msg("Failed to list the replayed tree")
+			return exitError
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/config"
+)
+
+// runStatus queries the status server of a running daemon. It exits with
+// exitFailed if the daemon is unhealthy and with exitError if it cannot be
+// reached.
+func runStatus(args []string) int {
+	flags := newFlagSet("status", "Query a running daemon")
+	addr := flags.String("addr", "", "status address of the daemon (overrides status.addr)")
+	asJSON := flags.Bool("json", false, "print the status as JSON")
+	timeout := flags.Duration("timeout", 5*time.Second, "give up after this long")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	// only the address is needed, so the config is not validated
+	c, err := config.Load(configPath, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if isFlagSet(flags, "addr") {
+		c.Status.Addr = *addr
+	}
+	if c.Status.Addr == "" {
+		fmt.Fprintln(os.Stderr, "no status address configured")
+		return exitError
+	}
+
+	httpClient := &http.Client{Timeout: *timeout}
+	resp, err := httpClient.Get("http://" + c.Status.Addr + "/status")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reach the daemon")
+		return exitError
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var status statusResponse
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	if err != nil {
+		log.Error().Err(err).Msgf("Invalid status response (%s)", resp.Status)
+		return exitError
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(status)
+	} else {
+		err = printStatus(status)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print status")
+		return exitError
+	}
+
+	if !status.Healthy {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func printStatus(status statusResponse) error {
+	health := "healthy"
+	if !status.Healthy {
+		health = "unhealthy"
+	}
+	lastSync := "never"
+	if status.LastSync != nil {
+		lastSync = status.LastSync.Format(time.RFC3339)
+	}
+	connection := status.Connection
+	if connection == "" {
+		connection = "not connected"
+	}
+
+	_, err := fmt.Printf("%s\nsync dir:    %s\nconnection:  %s\nsyncing:     %t\nlast sync:   %s\n",
+		health, status.SyncDir, connection, status.Syncing, lastSync)
+	if err == nil && status.LastSyncError != "" {
+		_, err = fmt.Printf("last error:  %s\n", status.LastSyncError)
+	}
+	return err
+}
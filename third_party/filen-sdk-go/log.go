@@ -0,0 +1 @@
+package filen_sdk_go
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/remote"
+	"github.com/Schidstorm/edge_config/apps/filen-mirror/pkg/watch"
+)
+
+// runWatch prints the events of the account until interrupted.
+func runWatch(args []string) int {
+	flags := newFlagSet("watch", "Print the events of the account until interrupted")
+	format := flags.String("format", "json", "output format, json or pretty")
+	eventNames := flags.String("events", "", "comma separated event names to print, e.g. file-new,file-trash")
+	pathPrefix := flags.String("path", "", "only print events about items below this remote path")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	outputFormat, err := watch.ParseFormat(*format)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid -format")
+		return exitError
+	}
+	if !loadConfig(nil) {
+		return exitError
+	}
+	var events []string
+	if *eventNames != "" {
+		events = strings.Split(*eventNames, ",")
+	}
+
+	client, session, err := login()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to log in")
+		return exitError
+	}
+	listener, err := setupEvents(client, session)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create Filen events")
+		return exitError
+	}
+	store := remote.NewFilenStore(client)
+	session.OnClientChange(store.SetClient)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	watcher := watch.New(store, watch.Config{
+		Format:     outputFormat,
+		Events:     events,
+		PathPrefix: *pathPrefix,
+		Output:     os.Stdout,
+	})
+	err = watcher.Run(ctx, listener)
+	if err != nil {
+		log.Error().Err(err).Msg("Watching events failed")
+		return exitError
+	}
+	return exitOK
+}